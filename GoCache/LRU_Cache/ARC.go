@@ -0,0 +1,222 @@
+package LRU_Cache
+
+import "container/list"
+
+//ARC 实现 Adaptive Replacement Cache 淘汰策略，并发访问不安全。
+//缓存的记录分为两段：T1 保存只访问过一次的记录，T2 保存至少访问过两次的记录，分别兼顾最近访问和访问频率；
+//两段被淘汰的记录只保留 key 和大小，分别进入幽灵列表 B1 和 B2。
+//新记录命中 B1 说明 T1 太小，增大 T1 的目标大小 p；命中 B2 说明 T2 太小，减小 p。淘汰时按 T1 是否超过 p 选择从哪一段淘汰。
+//与原论文按记录数计算不同，这里的容量、p 和幽灵列表的大小都按字节计算，B1、B2 中的记录不计入 Bytes
+type ARC struct {
+	maxBytes int64 //允许使用的最大内存
+	p        int64 //T1 的目标大小
+	overhead int64 //创建时的 EntryOverhead
+	sizes    [4]int64
+	lists    [4]*list.List //T1、T2、B1、B2，front 为最近访问的记录
+	cache    map[string]*list.Element
+	//当条目被清除时执行。
+	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
+}
+
+const (
+	arcT1 = iota
+	arcT2
+	arcB1
+	arcB2
+)
+
+type arcEntry struct {
+	key   string
+	value Value //在 B1、B2 中时为 nil
+	size  int64
+	seg   int
+}
+
+//NewARC 创建 ARC 实例
+func NewARC(maxBytes int64, onEvicted func(string, Value)) *ARC {
+	c := &ARC{
+		maxBytes:  maxBytes,
+		overhead:  EntryOverhead,
+		cache:     make(map[string]*list.Element),
+		OnEvicted: onEvicted,
+	}
+	for i := range c.lists {
+		c.lists[i] = list.New()
+	}
+	return c
+}
+
+//查找功能，命中的记录移到 T2 的队首
+func (c *ARC) Get(key string) (value Value, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*arcEntry)
+	if e.seg == arcB1 || e.seg == arcB2 {
+		return nil, false
+	}
+	c.move(ele, arcT2)
+	return e.value, true
+}
+
+//Peek 与 Get 相同，但不影响淘汰顺序
+func (c *ARC) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		if e := ele.Value.(*arcEntry); e.seg == arcT1 || e.seg == arcT2 {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+//新增 or 修改
+func (c *ARC) Add(key string, value Value) {
+	size := int64(len(key)) + int64(value.Len()) + c.overhead
+	ele, ok := c.cache[key]
+	if !ok {
+		e := &arcEntry{key: key, value: value, size: size, seg: arcT1}
+		c.cache[key] = c.lists[arcT1].PushFront(e)
+		c.sizes[arcT1] += size
+		c.evict(false)
+		return
+	}
+	e := ele.Value.(*arcEntry)
+	hitB2 := e.seg == arcB2
+	switch e.seg {
+	case arcB1:
+		//最近被 T1 淘汰的记录再次写入，T1 应该更大
+		c.p += size * max64(c.sizes[arcB2]/max64(c.sizes[arcB1], 1), 1)
+		if c.maxBytes != 0 && c.p > c.maxBytes {
+			c.p = c.maxBytes
+		}
+	case arcB2:
+		c.p -= size * max64(c.sizes[arcB1]/max64(c.sizes[arcB2], 1), 1)
+		if c.p < 0 {
+			c.p = 0
+		}
+	}
+	c.sizes[e.seg] += size - e.size
+	e.value, e.size = value, size
+	c.move(ele, arcT2)
+	c.evict(hitB2)
+}
+
+//move 把记录移到 seg 的队首
+func (c *ARC) move(ele *list.Element, seg int) {
+	e := ele.Value.(*arcEntry)
+	c.lists[e.seg].Remove(ele)
+	c.sizes[e.seg] -= e.size
+	e.seg = seg
+	c.cache[e.key] = c.lists[seg].PushFront(e)
+	c.sizes[seg] += e.size
+}
+
+//evict 淘汰记录直到不超过 maxBytes，再裁剪幽灵列表
+func (c *ARC) evict(hitB2 bool) {
+	for c.maxBytes != 0 && c.Bytes() > c.maxBytes {
+		c.replace(hitB2)
+	}
+	c.trimGhosts()
+}
+
+//replace 淘汰一条记录：T1 超过目标大小 p 时淘汰 T1 最久未访问的记录，否则淘汰 T2 的
+func (c *ARC) replace(hitB2 bool) {
+	t1, t2 := c.sizes[arcT1], c.sizes[arcT2]
+	if t1 > 0 && (t1 > c.p || (hitB2 && t1 == c.p) || t2 == 0) {
+		c.demote(c.lists[arcT1].Back(), arcB1)
+	} else if t2 > 0 {
+		c.demote(c.lists[arcT2].Back(), arcB2)
+	}
+}
+
+//demote 把记录移入幽灵列表 ghost 并触发回调
+func (c *ARC) demote(ele *list.Element, ghost int) {
+	e := ele.Value.(*arcEntry)
+	value := e.value
+	e.value = nil
+	c.move(ele, ghost)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, value)
+	}
+}
+
+//trimGhosts 保证 T1+B1 不超过 maxBytes，所有列表加起来不超过 2*maxBytes
+func (c *ARC) trimGhosts() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.sizes[arcB1] > 0 && c.sizes[arcT1]+c.sizes[arcB1] > c.maxBytes {
+		c.forget(c.lists[arcB1].Back())
+	}
+	for c.sizes[arcB2] > 0 && c.Bytes()+c.sizes[arcB1]+c.sizes[arcB2] > 2*c.maxBytes {
+		c.forget(c.lists[arcB2].Back())
+	}
+}
+
+//forget 从幽灵列表中删除记录，不触发回调
+func (c *ARC) forget(ele *list.Element) {
+	e := ele.Value.(*arcEntry)
+	c.lists[e.seg].Remove(ele)
+	c.sizes[e.seg] -= e.size
+	delete(c.cache, e.key)
+}
+
+//RemoveOldest 按 ARC 的规则淘汰一条记录
+func (c *ARC) RemoveOldest() {
+	c.replace(false)
+	c.trimGhosts()
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做，幽灵列表中的 key 也会被删除
+func (c *ARC) Remove(key string) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	e := ele.Value.(*arcEntry)
+	c.forget(ele)
+	if e.seg == arcT1 || e.seg == arcT2 {
+		if c.OnEvicted != nil {
+			c.OnEvicted(e.key, e.value)
+		}
+	}
+}
+
+//Resize 修改允许使用的最大内存，0 表示不限制，缩小时立即淘汰超出的记录
+func (c *ARC) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	if maxBytes != 0 && c.p > maxBytes {
+		c.p = maxBytes
+	}
+	c.evict(false)
+}
+
+func (c *ARC) Len() int {
+	return c.lists[arcT1].Len() + c.lists[arcT2].Len()
+}
+
+//Bytes 返回当前已使用的内存，只包括 T1 和 T2
+func (c *ARC) Bytes() int64 {
+	return c.sizes[arcT1] + c.sizes[arcT2]
+}
+
+//Range 先遍历 T2 再遍历 T1，每一段从最近访问到最久未访问，fn 返回 false 时停止遍历
+//遍历过程中不能修改 ARC
+func (c *ARC) Range(fn func(key string, value Value) bool) {
+	for _, seg := range []int{arcT2, arcT1} {
+		for ele := c.lists[seg].Front(); ele != nil; ele = ele.Next() {
+			e := ele.Value.(*arcEntry)
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
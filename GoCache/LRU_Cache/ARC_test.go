@@ -0,0 +1,124 @@
+package LRU_Cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+//访问过两次的记录进入 T2，一次性扫描只会挤占 T1
+func TestARCScanResistance(t *testing.T) {
+	size := EntryBytes("hot00", String("v"))
+	arc := NewARC(100*size, nil)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 50; j++ {
+			key := fmt.Sprintf("hot%02d", j)
+			if _, ok := arc.Get(key); !ok {
+				arc.Add(key, String("v"))
+			}
+		}
+	}
+	for j := 0; j < 1000; j++ {
+		key := fmt.Sprintf("s%04d", j)
+		if _, ok := arc.Get(key); !ok {
+			arc.Add(key, String("v"))
+		}
+	}
+	for j := 0; j < 50; j++ {
+		if _, ok := arc.Peek(fmt.Sprintf("hot%02d", j)); !ok {
+			t.Fatalf("hot%02d was evicted by the scan", j)
+		}
+	}
+	if arc.Bytes() > arc.maxBytes || arc.Len() != 100 {
+		t.Fatalf("Bytes() = %d, Len() = %d exceeds maxBytes %d", arc.Bytes(), arc.Len(), arc.maxBytes)
+	}
+	if ghosts := arc.sizes[arcT1] + arc.sizes[arcB1]; ghosts > arc.maxBytes {
+		t.Fatalf("T1+B1 = %d exceeds maxBytes %d", ghosts, arc.maxBytes)
+	}
+}
+
+//T1 中刚被淘汰的记录再次写入时说明 T1 太小，p 随之增大，记录直接进入 T2
+func TestARCAdapt(t *testing.T) {
+	size := EntryBytes("k1", String("v"))
+	var evicted []string
+	arc := NewARC(2*size, func(key string, value Value) { evicted = append(evicted, key) })
+	arc.Add("k1", String("v"))
+	arc.Get("k1")
+	arc.Add("k2", String("v"))
+	arc.Add("k3", String("v"))
+	if fmt.Sprint(evicted) != "[k2]" || arc.p != 0 {
+		t.Fatalf("evicted %v, p = %d", evicted, arc.p)
+	}
+	if _, ok := arc.Get("k2"); ok {
+		t.Fatal("ghost entries should not be returned")
+	}
+	arc.Add("k2", String("v"))
+	if arc.p != size || arc.cache["k2"].Value.(*arcEntry).seg != arcT2 {
+		t.Fatalf("expect a B1 hit to grow p and promote k2, p = %d", arc.p)
+	}
+	//T1 没有超过 p，从 T2 淘汰
+	if fmt.Sprint(evicted) != "[k2 k1]" || arc.Len() != 2 || arc.Bytes() != 2*size {
+		t.Fatalf("evicted %v, Len() = %d, Bytes() = %d", evicted, arc.Len(), arc.Bytes())
+	}
+}
+
+func TestARCAddRemove(t *testing.T) {
+	var evicted int
+	arc := NewARC(4*EntryBytes("k1", String("1234")), func(string, Value) { evicted++ })
+	arc.Add("k1", String("v1"))
+	arc.Add("k1", String("1234"))
+	if v, ok := arc.Get("k1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit k1=1234 failed")
+	}
+	arc.Add("k2", String("v2"))
+	var keys []string
+	arc.Range(func(key string, value Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if fmt.Sprint(keys) != "[k1 k2]" {
+		t.Fatalf("Range = %v, want T2 before T1", keys)
+	}
+	arc.Remove("k1")
+	arc.Remove("missing")
+	arc.Resize(1)
+	if arc.Len() != 0 || arc.Bytes() != 0 || evicted != 2 {
+		t.Fatalf("Len() = %d, Bytes() = %d, evicted = %d", arc.Len(), arc.Bytes(), evicted)
+	}
+}
+
+//BenchmarkMixedHitRatio 在一半访问落在少量热点 key、一半按顺序循环扫描大量 key 的访问序列上比较 LRU、ARC 与 2Q 的命中率
+//go test -bench MixedHitRatio ./LRU_Cache
+func BenchmarkMixedHitRatio(b *testing.B) {
+	const hot, scan, capacity = 500, 5000, 1000
+	size := capacity * EntryBytes("scan0000", String("v"))
+	for _, bc := range []struct {
+		name string
+		new  func() cacher
+	}{
+		{"LRU", func() cacher { return New(size, nil) }},
+		{"ARC", func() cacher { return NewARC(size, nil) }},
+		{"2Q", func() cacher { return NewTwoQueue(size, 0, 0, nil) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			c := bc.new()
+			r := rand.New(rand.NewSource(1))
+			zipf := rand.NewZipf(r, 1.01, 1, hot-1)
+			var hits int
+			for i := 0; i < b.N; i++ {
+				var key string
+				if r.Intn(2) == 0 {
+					key = fmt.Sprintf("hot%05d", zipf.Uint64())
+				} else {
+					key = fmt.Sprintf("scan%04d", (i/2)%scan)
+				}
+				if _, ok := c.Get(key); ok {
+					hits++
+				} else {
+					c.Add(key, String("v"))
+				}
+			}
+			b.ReportMetric(float64(hits)/float64(b.N)*100, "hit%")
+		})
+	}
+}
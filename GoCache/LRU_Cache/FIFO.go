@@ -0,0 +1,106 @@
+package LRU_Cache
+
+import "container/list"
+
+//FIFO 实现先进先出淘汰策略，按写入顺序淘汰，Get 不改变淘汰顺序，没有 LRU 移动节点的开销，适合写多读少、类似队列的缓存。
+//覆盖已有的记录不改变它的写入顺序。与 Cache 一样，并发访问不安全
+type FIFO struct {
+	maxBytes int64                    //允许使用的最大内存
+	nbytes   int64                    //当前已使用的内存
+	ll       *list.List               //front 为最新写入的记录
+	overhead int64                    //创建时的 EntryOverhead
+	cache    map[string]*list.Element //键是字符串，值是双向链表中对应节点的指针
+	//当条目被清除时执行。
+	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
+}
+
+//NewFIFO 创建 FIFO 实例
+func NewFIFO(maxBytes int64, onEvicted func(string, Value)) *FIFO {
+	return &FIFO{
+		maxBytes:  maxBytes,
+		ll:        list.New(),
+		overhead:  EntryOverhead,
+		cache:     make(map[string]*list.Element),
+		OnEvicted: onEvicted,
+	}
+}
+
+//查找功能，不影响淘汰顺序
+func (c *FIFO) Get(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		return ele.Value.(*entry).value, true
+	}
+	return
+}
+
+//Peek 与 Get 相同
+func (c *FIFO) Peek(key string) (value Value, ok bool) {
+	return c.Get(key)
+}
+
+//RemoveOldest 移除最早写入的记录
+func (c *FIFO) RemoveOldest() {
+	if ele := c.ll.Back(); ele != nil {
+		c.removeElement(ele)
+	}
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做
+func (c *FIFO) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+	}
+}
+
+func (c *FIFO) removeElement(ele *list.Element) {
+	c.ll.Remove(ele)
+	kv := ele.Value.(*entry)
+	delete(c.cache, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) + c.overhead
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
+
+//新增 or 修改
+func (c *FIFO) Add(key string, value Value) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		kv.value = value
+	} else {
+		c.cache[key] = c.ll.PushFront(&entry{key, value})
+		c.nbytes += int64(len(key)) + int64(value.Len()) + c.overhead
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+//Resize 修改允许使用的最大内存，0 表示不限制，缩小时按写入顺序移除记录直到不超过新的上限
+func (c *FIFO) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+func (c *FIFO) Len() int {
+	return c.ll.Len()
+}
+
+//Bytes 返回当前已使用的内存
+func (c *FIFO) Bytes() int64 {
+	return c.nbytes
+}
+
+//Range 从最新写入到最早写入依次遍历所有记录，fn 返回 false 时停止遍历
+//遍历过程中不能修改 FIFO
+func (c *FIFO) Range(fn func(key string, value Value) bool) {
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		kv := ele.Value.(*entry)
+		if !fn(kv.key, kv.value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package LRU_Cache
+
+import "container/list"
+
+//LFU 实现最不经常使用(Least Frequently Used)淘汰策略，访问次数相同时淘汰其中最久未访问的记录。
+//与 Cache 一样，并发访问不安全。
+//所有记录按访问次数分桶，桶本身按访问次数从小到大串成链表，因此 Get、Add、RemoveOldest 都是 O(1) 的。
+type LFU struct {
+	maxBytes int64                //允许使用的最大内存
+	nbytes   int64                //当前已使用的内存
+	freqs    *list.List           //按访问次数从小到大排列的桶，值为 *freqNode
+	cache    map[string]*lfuEntry //键是字符串，值是记录
+	overhead int64                //创建时的 EntryOverhead
+	//当条目被清除时执行。
+	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
+}
+
+//freqNode 是访问次数为 freq 的桶，items 中 front 为最近访问的记录
+type freqNode struct {
+	freq  int
+	items *list.List
+}
+
+type lfuEntry struct {
+	key     string
+	value   Value
+	freqEle *list.Element //所在的桶
+	ele     *list.Element //在桶中的位置
+}
+
+//NewLFU 创建 LFU 实例
+func NewLFU(maxBytes int64, onEvicted func(string, Value)) *LFU {
+	return &LFU{
+		maxBytes:  maxBytes,
+		freqs:     list.New(),
+		cache:     make(map[string]*lfuEntry),
+		overhead:  EntryOverhead,
+		OnEvicted: onEvicted,
+	}
+}
+
+//查找功能，命中时访问次数加一
+func (c *LFU) Get(key string) (value Value, ok bool) {
+	if e, ok := c.cache[key]; ok {
+		c.increment(e)
+		return e.value, true
+	}
+	return
+}
+
+//Peek 与 Get 相同，但不增加访问次数
+func (c *LFU) Peek(key string) (value Value, ok bool) {
+	if e, ok := c.cache[key]; ok {
+		return e.value, true
+	}
+	return
+}
+
+//increment 把记录移动到访问次数加一的桶中，桶不存在时创建，旧桶为空时删除
+func (c *LFU) increment(e *lfuEntry) {
+	cur := e.freqEle
+	node := cur.Value.(*freqNode)
+	next := cur.Next()
+	if next == nil || next.Value.(*freqNode).freq != node.freq+1 {
+		next = c.freqs.InsertAfter(&freqNode{freq: node.freq + 1, items: list.New()}, cur)
+	}
+	node.items.Remove(e.ele)
+	e.ele = next.Value.(*freqNode).items.PushFront(e)
+	e.freqEle = next
+	if node.items.Len() == 0 {
+		c.freqs.Remove(cur)
+	}
+}
+
+//新增 or 修改
+//新增时先淘汰访问次数最少的记录腾出空间，再写入，避免刚写入的记录因为访问次数最少而被立刻淘汰
+func (c *LFU) Add(key string, value Value) {
+	if e, ok := c.cache[key]; ok {
+		c.nbytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+		c.increment(e)
+	} else {
+		size := int64(len(key)) + int64(value.Len()) + c.overhead
+		for c.maxBytes != 0 && c.maxBytes < c.nbytes+size && c.Len() > 0 {
+			c.RemoveOldest()
+		}
+		front := c.freqs.Front()
+		if front == nil || front.Value.(*freqNode).freq != 1 {
+			front = c.freqs.PushFront(&freqNode{freq: 1, items: list.New()})
+		}
+		e := &lfuEntry{key: key, value: value, freqEle: front}
+		e.ele = front.Value.(*freqNode).items.PushFront(e)
+		c.cache[key] = e
+		c.nbytes += size
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+//RemoveOldest 淘汰访问次数最少的记录，次数相同时淘汰最久未访问的
+func (c *LFU) RemoveOldest() {
+	front := c.freqs.Front()
+	if front == nil {
+		return
+	}
+	ele := front.Value.(*freqNode).items.Back()
+	c.removeEntry(ele.Value.(*lfuEntry))
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做
+func (c *LFU) Remove(key string) {
+	if e, ok := c.cache[key]; ok {
+		c.removeEntry(e)
+	}
+}
+
+func (c *LFU) removeEntry(e *lfuEntry) {
+	node := e.freqEle.Value.(*freqNode)
+	node.items.Remove(e.ele)
+	if node.items.Len() == 0 {
+		c.freqs.Remove(e.freqEle)
+	}
+	delete(c.cache, e.key)
+	c.nbytes -= int64(len(e.key)) + int64(e.value.Len()) + c.overhead
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+}
+
+//Resize 修改允许使用的最大内存，0 表示不限制，缩小时依次淘汰访问次数最少的记录直到不超过新的上限
+func (c *LFU) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+//Len 返回记录数
+func (c *LFU) Len() int {
+	return len(c.cache)
+}
+
+//Bytes 返回当前已使用的内存
+func (c *LFU) Bytes() int64 {
+	return c.nbytes
+}
+
+//Range 从访问次数最多的记录开始依次遍历，不改变访问次数，fn 返回 false 时停止遍历
+//遍历过程中不能修改 LFU
+func (c *LFU) Range(fn func(key string, value Value) bool) {
+	for f := c.freqs.Back(); f != nil; f = f.Prev() {
+		for ele := f.Value.(*freqNode).items.Front(); ele != nil; ele = ele.Next() {
+			e := ele.Value.(*lfuEntry)
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package LRU_Cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+//访问次数最少的记录先被淘汰，次数相同时淘汰最久未访问的
+func TestLFURemoveOldest(t *testing.T) {
+	var evicted []string
+	size := EntryBytes("k1", String("v1"))
+	lfu := NewLFU(3*size, func(key string, value Value) {
+		evicted = append(evicted, key)
+	})
+	lfu.Add("k1", String("v1"))
+	lfu.Add("k2", String("v2"))
+	lfu.Add("k3", String("v3"))
+	lfu.Get("k1")
+	lfu.Get("k1")
+	lfu.Get("k3")
+
+	lfu.Add("k4", String("v4"))
+	lfu.Add("k5", String("v5"))
+	if want := []string{"k2", "k4"}; !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+	for _, key := range []string{"k1", "k3", "k5"} {
+		if _, ok := lfu.Get(key); !ok {
+			t.Fatalf("%s should still be cached", key)
+		}
+	}
+	if lfu.Len() != 3 || lfu.Bytes() != 3*size {
+		t.Fatalf("Len() = %d, Bytes() = %d", lfu.Len(), lfu.Bytes())
+	}
+}
+
+func TestLFUAddRemove(t *testing.T) {
+	lfu := NewLFU(0, nil)
+	lfu.Add("key", String("1"))
+	lfu.Add("key", String("1234"))
+	if v, ok := lfu.Get("key"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key=1234 failed")
+	}
+	if lfu.Bytes() != EntryBytes("key", String("1234")) {
+		t.Fatalf("Bytes() = %d", lfu.Bytes())
+	}
+
+	lfu.Remove("key")
+	lfu.Remove("missing")
+	lfu.RemoveOldest()
+	if _, ok := lfu.Get("key"); ok || lfu.Len() != 0 || lfu.Bytes() != 0 {
+		t.Fatalf("Remove failed, Len() = %d, Bytes() = %d", lfu.Len(), lfu.Bytes())
+	}
+}
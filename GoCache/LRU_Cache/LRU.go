@@ -10,6 +10,7 @@ type Cache struct {
 	maxBytes int64                    //允许使用的最大内存
 	nbytes   int64                    //当前已使用的内存
 	ll       *list.List               //内置双向链表
+	overhead int64                    //创建时的 EntryOverhead
 	cache    map[string]*list.Element //键是字符串，值是双向链表中对应节点的指针
 	//当条目被清除时执行。
 	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
@@ -21,6 +22,16 @@ type entry struct {
 	value Value
 }
 
+//EntryOverhead 是每条记录除 key 和 value 之外额外占用内存的估计值(链表节点、map 槽位、接口值等)，与 key、value 一起计入 maxBytes。
+//key 很短、记录很多时，只统计 key 和 value 会严重低估实际内存。
+//每个 Cache 在创建时读取一次，修改只影响之后创建的 Cache，设置为 0 时只统计 key 和 value
+var EntryOverhead int64 = 64
+
+//EntryBytes 返回一条记录计入 maxBytes 的内存，即 len(key) + value.Len() + EntryOverhead
+func EntryBytes(key string, value Value) int64 {
+	return int64(len(key)) + int64(value.Len()) + EntryOverhead
+}
+
 //该接口只包含了一个方法 Len() int，用于返回值所占用的内存大小。
 type Value interface {
 	Len() int //为了通用性，允许值是实现了 Value 接口的任意类型
@@ -31,6 +42,7 @@ func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
 	return &Cache{
 		maxBytes:  maxBytes,
 		ll:        list.New(),
+		overhead:  EntryOverhead,
 		cache:     make(map[string]*list.Element),
 		OnEvicted: onEvicted,
 	}
@@ -49,18 +61,37 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 	return
 }
 
+//Peek 与 Get 相同，但不把节点移动到队尾，不影响淘汰顺序
+func (c *Cache) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		return ele.Value.(*entry).value, true
+	}
+	return
+}
+
 //删除
 //实际上是缓存淘汰。即移除最近最少访问的节点（队首）
 func (c *Cache) RemoveOldest() {
 	ele := c.ll.Back()
 	if ele != nil {
-		c.ll.Remove(ele) //c.ll.Back() 取到队首节点，从链表中删除
-		kv := ele.Value.(*entry)
-		delete(c.cache, kv.key)                                //delete(c.cache, kv.key)，从字典中 c.cache 删除该节点的映射关系
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) //更新当前所用的内存 c.nbytes
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value) //如果回调函数 OnEvicted 不为 nil，则调用回调函数
-		}
+		c.removeElement(ele) //c.ll.Back() 取到队首节点，从链表中删除
+	}
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+	}
+}
+
+func (c *Cache) removeElement(ele *list.Element) {
+	c.ll.Remove(ele)
+	kv := ele.Value.(*entry)
+	delete(c.cache, kv.key)                                             //delete(c.cache, kv.key)，从字典中 c.cache 删除该节点的映射关系
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) + c.overhead //更新当前所用的内存 c.nbytes
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value) //如果回调函数 OnEvicted 不为 nil，则调用回调函数
 	}
 }
 
@@ -74,7 +105,7 @@ func (c *Cache) Add(key string, value Value) {
 	} else { //不存在则是新增场景，首先队尾添加新节点 &entry{key, value}, 并字典中添加 key 和节点的映射关系
 		ele := c.ll.PushFront(&entry{key, value})
 		c.cache[key] = ele
-		c.nbytes += int64(len(key)) + int64(value.Len())
+		c.nbytes += int64(len(key)) + int64(value.Len()) + c.overhead
 	}
 	//更新 c.nbytes，如果超过了设定的最大值 c.maxBytes，则移除最少访问的节点
 	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
@@ -82,7 +113,31 @@ func (c *Cache) Add(key string, value Value) {
 	}
 }
 
+//Resize 修改允许使用的最大内存，0 表示不限制，缩小时依次移除最少访问的节点直到不超过新的上限
+func (c *Cache) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
 //为了方便测试，实现 Len() 用来获取添加了多少条数据。
 func (c *Cache) Len() int {
 	return c.ll.Len()
 }
+
+//Bytes 返回当前已使用的内存
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
+
+//Range 从最近访问到最久未访问依次遍历所有记录，不改变访问顺序，fn 返回 false 时停止遍历
+//遍历过程中不能修改 Cache
+func (c *Cache) Range(fn func(key string, value Value) bool) {
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		kv := ele.Value.(*entry)
+		if !fn(kv.key, kv.value) {
+			return
+		}
+	}
+}
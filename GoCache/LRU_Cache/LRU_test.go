@@ -26,8 +26,8 @@ func TestGet(t *testing.T) {
 func TestRemoveoldest(t *testing.T) {
 	k1, k2, k3 := "key1", "key2", "k3"
 	v1, v2, v3 := "value1", "value2", "v3"
-	cap := len(k1 + k2 + v1 + v2)
-	lru := New(int64(cap), nil)
+	cap := EntryBytes(k1, String(v1)) + EntryBytes(k2, String(v2))
+	lru := New(cap, nil)
 	lru.Add(k1, String(v1))
 	lru.Add(k2, String(v2))
 	lru.Add(k3, String(v3))
@@ -42,7 +42,8 @@ func TestOnEvicted(t *testing.T) {
 	callback := func(key string, value Value) {
 		keys = append(keys, key)
 	}
-	lru := New(int64(10), callback)
+	//能放下 key1，或者 k2 和 k3 两条记录
+	lru := New(10+2*EntryOverhead, callback)
 	lru.Add("key1", String("123456"))
 	lru.Add("k2", String("k2"))
 	lru.Add("k3", String("k3"))
@@ -60,7 +61,7 @@ func TestAdd(t *testing.T) {
 	lru.Add("key", String("1"))
 	lru.Add("key", String("111"))
 
-	if lru.nbytes != int64(len("key")+len("111")) {
-		t.Fatal("expected 6 but got", lru.nbytes)
+	if want := EntryBytes("key", String("111")); lru.nbytes != want {
+		t.Fatal("expected", want, "but got", lru.nbytes)
 	}
 }
@@ -0,0 +1,110 @@
+package LRU_Cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFIFO(t *testing.T) {
+	var evicted []string
+	c := NewFIFO(3*EntryBytes("k1", String("v")), func(key string, value Value) { evicted = append(evicted, key) })
+	c.Add("k1", String("v"))
+	c.Add("k2", String("v"))
+	c.Add("k3", String("v"))
+	//访问和覆盖都不改变写入顺序
+	c.Get("k1")
+	c.Add("k1", String("v"))
+	c.Add("k4", String("v"))
+	if _, ok := c.Get("k1"); ok || len(evicted) != 1 || evicted[0] != "k1" {
+		t.Fatalf("expect k1 to be evicted first, evicted %v", evicted)
+	}
+	c.Remove("k2")
+	if c.Len() != 2 || c.Bytes() != 2*EntryBytes("k1", String("v")) {
+		t.Fatalf("Len() = %d, Bytes() = %d", c.Len(), c.Bytes())
+	}
+	var keys []string
+	c.Range(func(key string, value Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if fmt.Sprint(keys) != "[k4 k3]" {
+		t.Fatalf("Range = %v, want newest first", keys)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	size := EntryBytes("k00", String("v"))
+	var evicted int
+	c := NewRandom(10*size, func(string, Value) { evicted++ })
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("k%02d", i)
+		c.Add(key, String("v"))
+		//刚写入的记录不会被淘汰
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s was evicted right after Add", key)
+		}
+	}
+	if c.Len() != 10 || c.Bytes() != 10*size || evicted != 90 {
+		t.Fatalf("Len() = %d, Bytes() = %d, evicted = %d", c.Len(), c.Bytes(), evicted)
+	}
+	n := 0
+	c.Range(func(key string, value Value) bool {
+		c2, ok := c.Peek(key)
+		if !ok || c2 != value {
+			t.Fatalf("Range returned %s not found by Peek", key)
+		}
+		n++
+		return true
+	})
+	c.Resize(5 * size)
+	c.Remove("missing")
+	if n != 10 || c.Len() != 5 || c.Bytes() != 5*size {
+		t.Fatalf("Range visited %d, Len() = %d, Bytes() = %d after Resize", n, c.Len(), c.Bytes())
+	}
+}
+
+type evictPolicy interface {
+	cacher
+	Remove(key string)
+}
+
+//BenchmarkPolicies 比较各淘汰策略在缓存写满后持续写入新记录（每次 Add 都淘汰一条记录）时的吞吐
+//go test -bench Policies ./LRU_Cache
+func BenchmarkPolicies(b *testing.B) {
+	const capacity = 10000
+	size := capacity * EntryBytes("key0000000", String("v"))
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%07d", i)
+	}
+	for _, bc := range []struct {
+		name string
+		new  func() evictPolicy
+	}{
+		{"LRU", func() evictPolicy { return New(size, nil) }},
+		{"FIFO", func() evictPolicy { return NewFIFO(size, nil) }},
+		{"Random", func() evictPolicy { return NewRandom(size, nil) }},
+		{"LFU", func() evictPolicy { return NewLFU(size, nil) }},
+		{"TinyLFU", func() evictPolicy { return NewTinyLFU(size, 0, 0, nil) }},
+		{"ARC", func() evictPolicy { return NewARC(size, nil) }},
+		{"2Q", func() evictPolicy { return NewTwoQueue(size, 0, 0, nil) }},
+	} {
+		b.Run(bc.name+"/Add", func(b *testing.B) {
+			c := bc.new()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Add(keys[i&(len(keys)-1)], String("v"))
+			}
+		})
+		b.Run(bc.name+"/GetAdd", func(b *testing.B) {
+			c := bc.new()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				key := keys[i&(len(keys)-1)]
+				if _, ok := c.Get(keys[(i/2)&(len(keys)-1)]); !ok {
+					c.Add(key, String("v"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package LRU_Cache
+
+import (
+	"math/rand"
+	"time"
+)
+
+//Random 实现随机淘汰策略，写满时随机淘汰一条记录，Get 不需要维护任何顺序，Add、Remove、RemoveOldest 都是 O(1) 的。
+//记录保存在切片中，删除时用最后一条记录填补空位。与 Cache 一样，并发访问不安全
+type Random struct {
+	maxBytes int64          //允许使用的最大内存
+	nbytes   int64          //当前已使用的内存
+	entries  []entry        //所有记录，顺序没有意义
+	index    map[string]int //键是字符串，值是记录在 entries 中的下标
+	overhead int64          //创建时的 EntryOverhead
+	rand     *rand.Rand
+	//当条目被清除时执行。
+	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
+}
+
+//NewRandom 创建 Random 实例
+func NewRandom(maxBytes int64, onEvicted func(string, Value)) *Random {
+	return &Random{
+		maxBytes:  maxBytes,
+		index:     make(map[string]int),
+		overhead:  EntryOverhead,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		OnEvicted: onEvicted,
+	}
+}
+
+//查找功能，不影响淘汰
+func (c *Random) Get(key string) (value Value, ok bool) {
+	if i, ok := c.index[key]; ok {
+		return c.entries[i].value, true
+	}
+	return
+}
+
+//Peek 与 Get 相同
+func (c *Random) Peek(key string) (value Value, ok bool) {
+	return c.Get(key)
+}
+
+//RemoveOldest 随机移除一条记录
+func (c *Random) RemoveOldest() {
+	if len(c.entries) > 0 {
+		c.removeAt(c.rand.Intn(len(c.entries)))
+	}
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做
+func (c *Random) Remove(key string) {
+	if i, ok := c.index[key]; ok {
+		c.removeAt(i)
+	}
+}
+
+func (c *Random) removeAt(i int) {
+	kv := c.entries[i]
+	last := len(c.entries) - 1
+	if i != last {
+		c.entries[i] = c.entries[last]
+		c.index[c.entries[i].key] = i
+	}
+	c.entries[last] = entry{}
+	c.entries = c.entries[:last]
+	delete(c.index, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) + c.overhead
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
+
+//新增 or 修改
+func (c *Random) Add(key string, value Value) {
+	if i, ok := c.index[key]; ok {
+		c.nbytes += int64(value.Len()) - int64(c.entries[i].value.Len())
+		c.entries[i].value = value
+	} else {
+		c.index[key] = len(c.entries)
+		c.entries = append(c.entries, entry{key, value})
+		c.nbytes += int64(len(key)) + int64(value.Len()) + c.overhead
+	}
+	//优先淘汰其他记录，刚写入的记录只有在它是唯一的记录时才会被淘汰
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		if len(c.entries) == 1 {
+			c.removeAt(0)
+			continue
+		}
+		skip := c.index[key]
+		i := c.rand.Intn(len(c.entries) - 1)
+		if i >= skip {
+			i++
+		}
+		c.removeAt(i)
+	}
+}
+
+//Resize 修改允许使用的最大内存，0 表示不限制，缩小时随机移除记录直到不超过新的上限
+func (c *Random) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+func (c *Random) Len() int {
+	return len(c.entries)
+}
+
+//Bytes 返回当前已使用的内存
+func (c *Random) Bytes() int64 {
+	return c.nbytes
+}
+
+//Range 以任意顺序遍历所有记录，fn 返回 false 时停止遍历
+//遍历过程中不能修改 Random
+func (c *Random) Range(fn func(key string, value Value) bool) {
+	for _, kv := range c.entries {
+		if !fn(kv.key, kv.value) {
+			return
+		}
+	}
+}
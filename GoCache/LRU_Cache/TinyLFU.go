@@ -0,0 +1,298 @@
+package LRU_Cache
+
+import (
+	"container/list"
+	"hash/fnv"
+)
+
+//TinyLFU 实现 W-TinyLFU 淘汰策略，并发访问不安全。
+//新记录先进入一个小的 LRU 窗口(window)，被挤出窗口时与主区(SLRU)的淘汰候选比较 count-min sketch 中估计的访问频率，
+//频率更高的一方留下。一次性扫描产生的大量冷记录只会挤占窗口，不会把主区中的热点记录冲掉。
+//主区分为 probation 和 protected 两段，probation 中的记录再次被访问后晋升到 protected。
+type TinyLFU struct {
+	maxBytes     int64 //允许使用的最大内存
+	windowRatio  float64
+	windowBytes  int64 //窗口允许使用的内存
+	protectBytes int64 //protected 段允许使用的内存
+	nbytes       int64 //当前已使用的内存
+	overhead     int64 //创建时的 EntryOverhead
+	sizes        [3]int64
+	lists        [3]*list.List //window、probation、protected 三段，front 为最近访问的记录
+	cache        map[string]*list.Element
+	sketch       *cmSketch
+	//当条目被清除时执行。
+	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
+}
+
+const (
+	segWindow = iota
+	segProbation
+	segProtected
+)
+
+type tinyEntry struct {
+	key   string
+	value Value
+	seg   int
+}
+
+const (
+	DefaultWindowRatio = 0.01    //窗口默认占总内存的比例
+	DefaultSketchWidth = 1 << 14 //count-min sketch 默认的计数器宽度
+	protectedRatio     = 0.8     //protected 段占主区的比例
+)
+
+//NewTinyLFU 创建 TinyLFU 实例
+//windowRatio 为窗口占 maxBytes 的比例，取值 (0, 1)，否则使用 DefaultWindowRatio；
+//sketchWidth 为 count-min sketch 每行的计数器个数，会向上取整为 2 的幂，<= 0 时使用 DefaultSketchWidth，
+//宽度应不小于缓存中的记录数，否则频率估计会偏高
+func NewTinyLFU(maxBytes int64, windowRatio float64, sketchWidth int, onEvicted func(string, Value)) *TinyLFU {
+	if windowRatio <= 0 || windowRatio >= 1 {
+		windowRatio = DefaultWindowRatio
+	}
+	if sketchWidth <= 0 {
+		sketchWidth = DefaultSketchWidth
+	}
+	c := &TinyLFU{
+		windowRatio: windowRatio,
+		overhead:    EntryOverhead,
+		cache:       make(map[string]*list.Element),
+		sketch:      newCMSketch(sketchWidth),
+		OnEvicted:   onEvicted,
+	}
+	for i := range c.lists {
+		c.lists[i] = list.New()
+	}
+	c.Resize(maxBytes)
+	return c
+}
+
+//Resize 修改允许使用的最大内存并按比例调整各段的容量，0 表示不限制，
+//缩小时依次淘汰记录直到不超过新的上限，各段超出的部分在之后的访问和写入中逐步调整
+func (c *TinyLFU) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	c.windowBytes = int64(float64(maxBytes) * c.windowRatio)
+	c.protectBytes = int64(float64(maxBytes-c.windowBytes) * protectedRatio)
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+//查找功能，每次查找(无论是否命中)都会计入访问频率
+func (c *TinyLFU) Get(key string) (value Value, ok bool) {
+	c.sketch.increment(key)
+	if ele, ok := c.cache[key]; ok {
+		c.access(ele)
+		return ele.Value.(*tinyEntry).value, true
+	}
+	return
+}
+
+//Peek 与 Get 相同，但不计入访问频率，也不移动记录
+func (c *TinyLFU) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		return ele.Value.(*tinyEntry).value, true
+	}
+	return
+}
+
+//access 处理命中：窗口和 protected 中的记录移到段首，probation 中的记录晋升到 protected
+func (c *TinyLFU) access(ele *list.Element) {
+	e := ele.Value.(*tinyEntry)
+	if e.seg != segProbation {
+		c.lists[e.seg].MoveToFront(ele)
+		return
+	}
+	c.move(ele, segProtected)
+	//protected 超出容量时，把最久未访问的记录降级回 probation
+	for c.sizes[segProtected] > c.protectBytes && c.lists[segProtected].Len() > 1 {
+		c.move(c.lists[segProtected].Back(), segProbation)
+	}
+}
+
+//move 把记录移动到 seg 段的段首
+func (c *TinyLFU) move(ele *list.Element, seg int) {
+	e := ele.Value.(*tinyEntry)
+	size := c.entrySize(e)
+	c.lists[e.seg].Remove(ele)
+	c.sizes[e.seg] -= size
+	e.seg = seg
+	c.cache[e.key] = c.lists[seg].PushFront(e)
+	c.sizes[seg] += size
+}
+
+//新增 or 修改
+func (c *TinyLFU) Add(key string, value Value) {
+	if ele, ok := c.cache[key]; ok {
+		e := ele.Value.(*tinyEntry)
+		delta := int64(value.Len()) - int64(e.value.Len())
+		c.sizes[e.seg] += delta
+		c.nbytes += delta
+		e.value = value
+		c.access(ele)
+	} else {
+		c.sketch.increment(key)
+		c.cache[key] = c.lists[segWindow].PushFront(&tinyEntry{key: key, value: value, seg: segWindow})
+		size := int64(len(key)) + int64(value.Len()) + c.overhead
+		c.sizes[segWindow] += size
+		c.nbytes += size
+	}
+	if c.maxBytes == 0 {
+		return
+	}
+	//被挤出窗口的记录作为候选进入主区
+	for c.sizes[segWindow] > c.windowBytes && c.lists[segWindow].Len() > 0 {
+		c.admit(c.lists[segWindow].Back())
+	}
+	for c.maxBytes < c.nbytes {
+		c.RemoveOldest()
+	}
+}
+
+//admit 决定候选记录能否进入主区：主区放得下时直接进入 probation，
+//否则与主区的淘汰候选比较访问频率，候选频率更高时淘汰对方，反之淘汰候选自己
+func (c *TinyLFU) admit(candidate *list.Element) {
+	e := candidate.Value.(*tinyEntry)
+	size := c.entrySize(e)
+	mainBytes := c.maxBytes - c.windowBytes
+	for c.sizes[segProbation]+c.sizes[segProtected]+size > mainBytes {
+		victim := c.victim()
+		if victim == nil || c.sketch.estimate(e.key) <= c.sketch.estimate(victim.Value.(*tinyEntry).key) {
+			c.removeElement(candidate)
+			return
+		}
+		c.removeElement(victim)
+	}
+	c.move(candidate, segProbation)
+}
+
+//victim 返回主区的淘汰候选，优先从 probation 中选
+func (c *TinyLFU) victim() *list.Element {
+	if ele := c.lists[segProbation].Back(); ele != nil {
+		return ele
+	}
+	return c.lists[segProtected].Back()
+}
+
+//RemoveOldest 淘汰一条记录，优先淘汰主区的候选，主区为空时淘汰窗口中最久未访问的记录
+func (c *TinyLFU) RemoveOldest() {
+	ele := c.victim()
+	if ele == nil {
+		ele = c.lists[segWindow].Back()
+	}
+	if ele != nil {
+		c.removeElement(ele)
+	}
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做
+func (c *TinyLFU) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+	}
+}
+
+func (c *TinyLFU) removeElement(ele *list.Element) {
+	e := ele.Value.(*tinyEntry)
+	size := c.entrySize(e)
+	c.lists[e.seg].Remove(ele)
+	c.sizes[e.seg] -= size
+	delete(c.cache, e.key)
+	c.nbytes -= size
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+}
+
+func (c *TinyLFU) entrySize(e *tinyEntry) int64 {
+	return int64(len(e.key)) + int64(e.value.Len()) + c.overhead
+}
+
+//Len 返回记录数
+func (c *TinyLFU) Len() int {
+	return len(c.cache)
+}
+
+//Bytes 返回当前已使用的内存
+func (c *TinyLFU) Bytes() int64 {
+	return c.nbytes
+}
+
+//Range 依次遍历 protected、probation 和窗口中的记录，不计入访问频率，fn 返回 false 时停止遍历
+//遍历过程中不能修改 TinyLFU
+func (c *TinyLFU) Range(fn func(key string, value Value) bool) {
+	for _, seg := range []int{segProtected, segProbation, segWindow} {
+		for ele := c.lists[seg].Front(); ele != nil; ele = ele.Next() {
+			e := ele.Value.(*tinyEntry)
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+//cmSketch 是 4 行的 count-min sketch，计数器上限为 15，
+//累计增加 10 倍宽度次后所有计数器减半，让访问频率随时间衰减
+type cmSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCMSketch(width int) *cmSketch {
+	w := 1
+	for w < width {
+		w <<= 1
+	}
+	s := &cmSketch{mask: uint64(w - 1), resetAt: 10 * w}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+//indexes 用双重哈希为每一行计算计数器下标
+func (s *cmSketch) indexes(key string) [4]uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum, sum>>32|1
+	var idx [4]uint64
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) & s.mask
+	}
+	return idx
+}
+
+func (s *cmSketch) increment(key string) {
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+//estimate 返回 key 访问频率的估计值，取各行计数器中的最小值
+func (s *cmSketch) estimate(key string) uint8 {
+	min := uint8(15)
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < min {
+			min = s.rows[i][j]
+		}
+	}
+	return min
+}
+
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
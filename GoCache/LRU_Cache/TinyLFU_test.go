@@ -0,0 +1,84 @@
+package LRU_Cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+//热点记录不会被一次性扫描挤出缓存
+func TestTinyLFUScanResistance(t *testing.T) {
+	lfu := NewTinyLFU(100*EntryBytes("hot00", String("v")), 0.1, 0, nil)
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 50; j++ {
+			key := fmt.Sprintf("hot%02d", j)
+			if _, ok := lfu.Get(key); !ok {
+				lfu.Add(key, String("v"))
+			}
+		}
+	}
+	for j := 0; j < 1000; j++ {
+		key := fmt.Sprintf("s%04d", j)
+		lfu.Get(key)
+		lfu.Add(key, String("v"))
+	}
+	for j := 0; j < 50; j++ {
+		if _, ok := lfu.Get(fmt.Sprintf("hot%02d", j)); !ok {
+			t.Fatalf("hot%02d was evicted by the scan", j)
+		}
+	}
+	if lfu.Bytes() > lfu.maxBytes {
+		t.Fatalf("Bytes() = %d exceeds maxBytes %d", lfu.Bytes(), lfu.maxBytes)
+	}
+}
+
+func TestTinyLFUAddRemove(t *testing.T) {
+	var evicted int
+	lfu := NewTinyLFU(4*EntryBytes("k1", String("1234")), 0.5, 16, func(string, Value) { evicted++ })
+	lfu.Add("k1", String("v1"))
+	lfu.Add("k1", String("1234"))
+	if v, ok := lfu.Get("k1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit k1=1234 failed")
+	}
+	lfu.Remove("k1")
+	lfu.Remove("missing")
+	if lfu.Len() != 0 || lfu.Bytes() != 0 || evicted != 1 {
+		t.Fatalf("Len() = %d, Bytes() = %d, evicted = %d", lfu.Len(), lfu.Bytes(), evicted)
+	}
+}
+
+type cacher interface {
+	Add(key string, value Value)
+	Get(key string) (Value, bool)
+}
+
+//BenchmarkHitRatio 在 Zipf 分布的访问序列上比较 LRU 与 TinyLFU 的命中率
+//go test -bench HitRatio ./LRU_Cache
+func BenchmarkHitRatio(b *testing.B) {
+	const keys, capacity = 100000, 1000
+	size := capacity * EntryBytes("key00000", String("v"))
+	for _, bc := range []struct {
+		name string
+		new  func() cacher
+	}{
+		{"LRU", func() cacher { return New(size, nil) }},
+		{"TinyLFU", func() cacher { return NewTinyLFU(size, 0, 0, nil) }},
+		{"ARC", func() cacher { return NewARC(size, nil) }},
+		{"2Q", func() cacher { return NewTwoQueue(size, 0, 0, nil) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			c := bc.new()
+			zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, keys-1)
+			var hits int
+			for i := 0; i < b.N; i++ {
+				key := fmt.Sprintf("key%05d", zipf.Uint64())
+				if _, ok := c.Get(key); ok {
+					hits++
+				} else {
+					c.Add(key, String("v"))
+				}
+			}
+			b.ReportMetric(float64(hits)/float64(b.N)*100, "hit%")
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package LRU_Cache
+
+import "container/list"
+
+//TwoQueue 实现 2Q 淘汰策略（Johnson & Shasha 的完整版本），并发访问不安全。
+//新记录先进入一个小的 FIFO 队列 in，在 in 中再次被访问不改变顺序；被挤出 in 的记录只保留 key 和大小，进入幽灵队列 out。
+//out 中的 key 再次写入时说明它不是只访问一次的记录，直接进入按 LRU 淘汰的主区 main。
+//一次性扫描产生的冷记录只会经过 in 和 out，不会把 main 中的热点记录冲掉；每次访问只移动一个链表节点，比 ARC 简单。
+//out 中的记录不计入 Bytes
+type TwoQueue struct {
+	maxBytes   int64 //允许使用的最大内存
+	inRatio    float64
+	ghostRatio float64
+	overhead   int64 //创建时的 EntryOverhead
+	sizes      [3]int64
+	lists      [3]*list.List //in、main、out，front 为最新的记录
+	cache      map[string]*list.Element
+	//当条目被清除时执行。
+	OnEvicted func(key string, value Value) //某条记录被移除时的回调函数，可以为 nil
+}
+
+const (
+	queueIn = iota
+	queueMain
+	queueOut
+)
+
+type queueEntry struct {
+	key   string
+	value Value //在 out 中时为 nil
+	size  int64
+	queue int
+}
+
+const (
+	DefaultInRatio    = 0.25 //in 队列默认占总内存的比例
+	DefaultGhostRatio = 0.5  //out 队列中记录的大小之和默认不超过总内存的比例
+)
+
+//NewTwoQueue 创建 TwoQueue 实例
+//inRatio 为 in 队列占 maxBytes 的比例，取值 (0, 1)，否则使用 DefaultInRatio；
+//ghostRatio 为 out 队列中记录原来的大小之和占 maxBytes 的比例，> 0，否则使用 DefaultGhostRatio。
+//ghostRatio 越大，越久之前访问过一次的记录再次访问时越能进入 main，out 本身只保存 key
+func NewTwoQueue(maxBytes int64, inRatio, ghostRatio float64, onEvicted func(string, Value)) *TwoQueue {
+	if inRatio <= 0 || inRatio >= 1 {
+		inRatio = DefaultInRatio
+	}
+	if ghostRatio <= 0 {
+		ghostRatio = DefaultGhostRatio
+	}
+	c := &TwoQueue{
+		maxBytes:   maxBytes,
+		inRatio:    inRatio,
+		ghostRatio: ghostRatio,
+		overhead:   EntryOverhead,
+		cache:      make(map[string]*list.Element),
+		OnEvicted:  onEvicted,
+	}
+	for i := range c.lists {
+		c.lists[i] = list.New()
+	}
+	return c
+}
+
+//查找功能，main 中命中的记录移到队首，in 中命中的记录不改变顺序
+func (c *TwoQueue) Get(key string) (value Value, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	e := ele.Value.(*queueEntry)
+	switch e.queue {
+	case queueMain:
+		c.lists[queueMain].MoveToFront(ele)
+	case queueOut:
+		return nil, false
+	}
+	return e.value, true
+}
+
+//Peek 与 Get 相同，但不影响淘汰顺序
+func (c *TwoQueue) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		if e := ele.Value.(*queueEntry); e.queue != queueOut {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+//新增 or 修改
+func (c *TwoQueue) Add(key string, value Value) {
+	size := int64(len(key)) + int64(value.Len()) + c.overhead
+	ele, ok := c.cache[key]
+	if !ok {
+		c.push(&queueEntry{key: key, value: value, size: size, queue: queueIn})
+	} else {
+		e := ele.Value.(*queueEntry)
+		switch e.queue {
+		case queueOut:
+			//被挤出 in 之后再次写入，进入 main
+			c.unlink(ele)
+			c.push(&queueEntry{key: key, value: value, size: size, queue: queueMain})
+		case queueMain:
+			c.lists[queueMain].MoveToFront(ele)
+			fallthrough
+		default:
+			c.sizes[e.queue] += size - e.size
+			e.value, e.size = value, size
+		}
+	}
+	c.evict()
+}
+
+//push 把记录放到所在队列的队首
+func (c *TwoQueue) push(e *queueEntry) {
+	c.cache[e.key] = c.lists[e.queue].PushFront(e)
+	c.sizes[e.queue] += e.size
+}
+
+//unlink 把记录从所在队列和索引中删除，不触发回调
+func (c *TwoQueue) unlink(ele *list.Element) {
+	e := ele.Value.(*queueEntry)
+	c.lists[e.queue].Remove(ele)
+	c.sizes[e.queue] -= e.size
+	delete(c.cache, e.key)
+}
+
+//evict 淘汰记录直到不超过 maxBytes
+func (c *TwoQueue) evict() {
+	for c.maxBytes != 0 && c.Bytes() > c.maxBytes {
+		c.RemoveOldest()
+	}
+}
+
+//RemoveOldest 淘汰一条记录：in 超过它的份额（或 main 为空）时淘汰 in 中最早写入的记录并把 key 放入 out，
+//否则淘汰 main 中最久未访问的记录
+func (c *TwoQueue) RemoveOldest() {
+	in := c.lists[queueIn].Back()
+	if in != nil && (float64(c.sizes[queueIn]) > c.inRatio*float64(c.maxBytes) || c.lists[queueMain].Len() == 0) {
+		e := in.Value.(*queueEntry)
+		value := e.value
+		c.unlink(in)
+		c.push(&queueEntry{key: e.key, size: e.size, queue: queueOut})
+		for float64(c.sizes[queueOut]) > c.ghostRatio*float64(c.maxBytes) {
+			c.unlink(c.lists[queueOut].Back())
+		}
+		if c.OnEvicted != nil {
+			c.OnEvicted(e.key, value)
+		}
+		return
+	}
+	if ele := c.lists[queueMain].Back(); ele != nil {
+		e := ele.Value.(*queueEntry)
+		c.unlink(ele)
+		if c.OnEvicted != nil {
+			c.OnEvicted(e.key, e.value)
+		}
+	}
+}
+
+//删除指定 key 对应的记录，key 不存在时什么也不做，out 中的 key 也会被删除
+func (c *TwoQueue) Remove(key string) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	e := ele.Value.(*queueEntry)
+	c.unlink(ele)
+	if e.queue != queueOut && c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value)
+	}
+}
+
+//Resize 修改允许使用的最大内存，0 表示不限制，缩小时立即淘汰超出的记录
+func (c *TwoQueue) Resize(maxBytes int64) {
+	c.maxBytes = maxBytes
+	c.evict()
+	for maxBytes != 0 && float64(c.sizes[queueOut]) > c.ghostRatio*float64(maxBytes) {
+		c.unlink(c.lists[queueOut].Back())
+	}
+}
+
+func (c *TwoQueue) Len() int {
+	return c.lists[queueIn].Len() + c.lists[queueMain].Len()
+}
+
+//Bytes 返回当前已使用的内存，只包括 in 和 main
+func (c *TwoQueue) Bytes() int64 {
+	return c.sizes[queueIn] + c.sizes[queueMain]
+}
+
+//Range 先遍历 main 再遍历 in，每个队列从新到旧，fn 返回 false 时停止遍历
+//遍历过程中不能修改 TwoQueue
+func (c *TwoQueue) Range(fn func(key string, value Value) bool) {
+	for _, queue := range []int{queueMain, queueIn} {
+		for ele := c.lists[queue].Front(); ele != nil; ele = ele.Next() {
+			e := ele.Value.(*queueEntry)
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package LRU_Cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+//已经进入 main 的热点记录不会被一次性扫描挤出缓存
+func TestTwoQueueScanResistance(t *testing.T) {
+	size := EntryBytes("hot00", String("v"))
+	q := NewTwoQueue(100*size, 0, 0, nil)
+	//热点记录第一次写入后被挤出 in，再次写入时从 out 进入 main
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 50; j++ {
+			q.Add(fmt.Sprintf("hot%02d", j), String("v"))
+		}
+		for j := 0; j < 100; j++ {
+			q.Add(fmt.Sprintf("w%04d", j+i*100), String("v"))
+		}
+	}
+	for j := 0; j < 1000; j++ {
+		key := fmt.Sprintf("s%04d", j)
+		if _, ok := q.Get(key); !ok {
+			q.Add(key, String("v"))
+		}
+	}
+	for j := 0; j < 50; j++ {
+		if _, ok := q.Peek(fmt.Sprintf("hot%02d", j)); !ok {
+			t.Fatalf("hot%02d was evicted by the scan", j)
+		}
+	}
+	if q.Bytes() > q.maxBytes || q.Len() != 100 {
+		t.Fatalf("Bytes() = %d, Len() = %d exceeds maxBytes %d", q.Bytes(), q.Len(), q.maxBytes)
+	}
+	if ghosts := float64(q.sizes[queueOut]); ghosts > DefaultGhostRatio*float64(q.maxBytes) {
+		t.Fatalf("out holds %v bytes, more than its share", ghosts)
+	}
+}
+
+//out 的容量决定多久之前被挤出 in 的 key 再次写入时还能进入 main
+func TestTwoQueueGhostRatio(t *testing.T) {
+	size := EntryBytes("k00", String("v"))
+	for _, tt := range []struct {
+		ghostRatio float64
+		promoted   bool
+	}{
+		{0.1, false},
+		{1, true},
+	} {
+		var evicted []string
+		q := NewTwoQueue(10*size, 0.5, tt.ghostRatio, func(key string, value Value) { evicted = append(evicted, key) })
+		for j := 0; j < 16; j++ {
+			q.Add(fmt.Sprintf("k%02d", j), String("v"))
+		}
+		if len(evicted) != 6 || evicted[0] != "k00" {
+			t.Fatalf("ghostRatio %v: evicted %v", tt.ghostRatio, evicted)
+		}
+		if _, ok := q.Get("k00"); ok {
+			t.Fatalf("ghostRatio %v: ghost entries should not be returned", tt.ghostRatio)
+		}
+		q.Add("k00", String("v"))
+		if promoted := q.cache["k00"].Value.(*queueEntry).queue == queueMain; promoted != tt.promoted {
+			t.Fatalf("ghostRatio %v: k00 promoted to main = %v, want %v", tt.ghostRatio, promoted, tt.promoted)
+		}
+	}
+}
+
+func TestTwoQueueAddRemove(t *testing.T) {
+	var evicted int
+	q := NewTwoQueue(4*EntryBytes("k1", String("1234")), 0, 0, func(string, Value) { evicted++ })
+	q.Add("k1", String("v1"))
+	q.Add("k1", String("1234"))
+	if v, ok := q.Get("k1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit k1=1234 failed")
+	}
+	q.Add("k2", String("v2"))
+	q.Remove("k1")
+	q.Remove("missing")
+	q.Resize(1)
+	if q.Len() != 0 || q.Bytes() != 0 || evicted != 2 {
+		t.Fatalf("Len() = %d, Bytes() = %d, evicted = %d", q.Len(), q.Bytes(), evicted)
+	}
+}
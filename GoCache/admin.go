@@ -0,0 +1,171 @@
+package GoCache
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	//adminPath 是管理接口相对 basePath 的前缀，见 WithAdmin
+	adminPath = "admin/"
+	//defaultAdminKeys 和 maxAdminKeys 是 keys 接口默认和最多返回的 key 数
+	defaultAdminKeys = 100
+	maxAdminKeys     = 10000
+)
+
+//WithAdmin 开启只读的管理接口，返回 JSON，方便排查缓存的状态：
+//	GET <basePath>admin/groups                  所有 group 的容量、用量、记录数、统计和本节点看到的远程节点状态
+//	GET <basePath>admin/keys?group=X&limit=N    通过 Group.Range 采样 group 中已缓存的 key，默认 100 个，最多 10000 个
+//管理接口与节点间请求使用相同的 WithAuthToken 令牌。开启后名为 admin 的 group 无法通过 HTTP 访问。默认关闭
+func WithAdmin(enabled bool) PoolOption {
+	return func(p *HTTPPool) {
+		p.admin = enabled
+	}
+}
+
+type adminGroup struct {
+	Name      string     `json:"name"`
+	MaxBytes  int64      `json:"max_bytes"`
+	UsedBytes int64      `json:"used_bytes"`
+	Entries   int        `json:"entries"`
+	Stats     adminStats `json:"stats"`
+}
+
+type adminStats struct {
+	Hits               int64 `json:"hits"`
+	HotHits            int64 `json:"hot_hits"`
+	Misses             int64 `json:"misses"`
+	LocalLoads         int64 `json:"local_loads"`
+	PeerLoads          int64 `json:"peer_loads"`
+	PeerErrors         int64 `json:"peer_errors"`
+	LoaderDedups       int64 `json:"loader_dedups"`
+	PeerCircuitOpen    int64 `json:"peer_circuit_open"`
+	WriteBehindDropped int64 `json:"write_behind_dropped"`
+	WriteBehindErrors  int64 `json:"write_behind_errors"`
+}
+
+type adminPeer struct {
+	Addr    string `json:"addr"`
+	Self    bool   `json:"self"`
+	Up      bool   `json:"up"`                //健康检查是否通过，没有开启 WithHealthCheck 时总是 true
+	Circuit string `json:"circuit,omitempty"` //熔断器状态，没有开启 WithCircuitBreaker 时为空
+	Conns   int    `json:"conns"`             //当前打开的连接数，见 HTTPPool.PeerConns
+	//LastError 是最近一次失败的错误，LatencyMs 是成功请求的平均耗时（毫秒），见 HTTPPool.PeerInfos
+	LastError string  `json:"last_error,omitempty"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+}
+
+type adminGroupsResponse struct {
+	Groups []adminGroup `json:"groups"`
+	Peers  []adminPeer  `json:"peers"`
+}
+
+type adminKey struct {
+	Key     string     `json:"key"`
+	Bytes   int        `json:"bytes"`
+	Expires *time.Time `json:"expires,omitempty"` //为空表示永不过期
+}
+
+type adminKeysResponse struct {
+	Group string     `json:"group"`
+	Keys  []adminKey `json:"keys"`
+}
+
+//serveAdmin 处理 <basePath>admin/ 下的请求，name 为 admin/ 之后的部分
+func (p *HTTPPool) serveAdmin(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch name {
+	case "groups":
+		res := adminGroupsResponse{Groups: []adminGroup{}, Peers: p.adminPeers()}
+		for _, g := range Groups() {
+			s := g.Stats()
+			res.Groups = append(res.Groups, adminGroup{
+				Name:      g.Name(),
+				MaxBytes:  g.MaxBytes(),
+				UsedBytes: g.UsedBytes(),
+				Entries:   g.Len(),
+				Stats: adminStats{
+					Hits:               s.Hits,
+					HotHits:            s.HotHits,
+					Misses:             s.Misses,
+					LocalLoads:         s.LocalLoads,
+					PeerLoads:          s.PeerLoads,
+					PeerErrors:         s.PeerErrors,
+					LoaderDedups:       s.LoaderDedups,
+					PeerCircuitOpen:    s.PeerCircuitOpen,
+					WriteBehindDropped: s.WriteBehindDropped,
+					WriteBehindErrors:  s.WriteBehindErrors,
+				},
+			})
+		}
+		writeJSON(w, res)
+	case "keys":
+		group := GetGroup(r.URL.Query().Get("group"))
+		if group == nil {
+			http.Error(w, "no such group:"+r.URL.Query().Get("group"), http.StatusNotFound)
+			return
+		}
+		limit := defaultAdminKeys
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				http.Error(w, "bad limit: "+s, http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if limit > maxAdminKeys {
+			limit = maxAdminKeys
+		}
+		res := adminKeysResponse{Group: group.Name(), Keys: []adminKey{}}
+		group.Range(func(key string, value ByteView) bool {
+			k := adminKey{Key: key, Bytes: value.Len()}
+			if e := value.Expire(); !e.IsZero() {
+				k.Expires = &e
+			}
+			res.Keys = append(res.Keys, k)
+			return len(res.Keys) < limit
+		})
+		writeJSON(w, res)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+//adminPeers 返回按地址排序的所有节点及其状态
+func (p *HTTPPool) adminPeers() []adminPeer {
+	infos := p.PeerInfos()
+	peers := make([]adminPeer, 0, len(infos))
+	for _, info := range infos {
+		peer := adminPeer{
+			Addr:      info.Addr,
+			Self:      info.Self,
+			Up:        info.Up,
+			Conns:     info.Conns,
+			LatencyMs: float64(info.Latency) / float64(time.Millisecond),
+		}
+		if info.LastError != nil {
+			peer.LastError = info.LastError.Error()
+		}
+		if p.breakerThreshold > 0 && !info.Self {
+			peer.Circuit = info.Circuit.String()
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,94 @@
+package GoCache
+
+import (
+	"sync"
+	"time"
+)
+
+//CircuitState 是访问某个远程节点的熔断器状态
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota //正常请求
+	CircuitOpen                         //连续失败次数达到阈值，冷却期内的请求直接失败
+	CircuitHalfOpen                     //冷却期结束，只放行一个探测请求，成功后关闭，失败后重新打开
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+//circuitBreaker 在连续 threshold 次失败后打开 cooldown 时间，避免节点宕机时每个请求都等待超时
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int //连续失败次数
+	state     CircuitState
+	openedAt  time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+//allow 判断是否可以发出请求，冷却期结束后第一个调用者成为探测请求
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		//探测请求还没有结果
+		return false
+	}
+	return true
+}
+
+//record 记录一次请求的结果
+func (b *circuitBreaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		b.failures = 0
+		b.state = CircuitClosed
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+//release 在请求的结果不能说明节点状态时调用，不改变连续失败次数。
+//这个请求是半开状态的探测请求时回到打开状态，冷却期已经结束，下一个请求重新成为探测请求
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.state = CircuitOpen
+	}
+}
+
+func (b *circuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
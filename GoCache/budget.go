@@ -0,0 +1,126 @@
+package GoCache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//globalBudget 是所有 group 共用的内存上限，见 SetGlobalMemoryLimit
+var globalBudget struct {
+	limit int64 //0 表示不限制，通过 sync/atomic 读写
+	used  int64 //所有分片已使用的内存之和，通过 sync/atomic 读写
+	//evicting 保证同时只有一个协程在淘汰，其他协程写入后发现超出上限时直接返回
+	evicting sync.Mutex
+}
+
+//SetGlobalMemoryLimit 设置进程内所有 group（包括 mainCache 和 hotCache）共用的内存上限，0 表示不限制。
+//总用量超过上限时，从最久没有被访问的分片中按该分片的淘汰策略淘汰记录，直到总用量不超过上限，
+//空闲的 group 因此会把内存让给访问频繁的 group。被淘汰的记录以 EvictCapacity 触发 OnEvicted 回调，固定的记录不会被淘汰；
+//开启 WithDiskTier 的 group 把内存中的记录移入磁盘，磁盘中的记录不占用内存，不会因为全局上限被淘汰。
+//每个 group 的 cacheBytes 仍然是该 group 的上限，设为 0 时只受全局上限约束。
+//设置的上限小于当前用量时立即淘汰
+func SetGlobalMemoryLimit(bytes int64) {
+	if bytes < 0 {
+		bytes = 0
+	}
+	atomic.StoreInt64(&globalBudget.limit, bytes)
+	enforceGlobalLimit()
+}
+
+//GlobalMemoryUsage 返回所有 group 已使用的内存之和，包括 mainCache 和 hotCache
+func GlobalMemoryUsage() int64 {
+	return atomic.LoadInt64(&globalBudget.used)
+}
+
+func globalLimit() int64 {
+	return atomic.LoadInt64(&globalBudget.limit)
+}
+
+//account 把分片用量的变化计入 globalBudget，返回用量是否增加。调用方需持有 s.mu
+func (s *shard) account() bool {
+	var bytes int64
+	if s.store != nil {
+		bytes = s.store.Bytes() + s.pinnedBytes
+	}
+	delta := bytes - atomic.LoadInt64(&s.accounted)
+	if delta == 0 {
+		return false
+	}
+	atomic.StoreInt64(&s.accounted, bytes)
+	atomic.AddInt64(&globalBudget.used, delta)
+	return delta > 0
+}
+
+//touch 记录分片最近一次被访问的时间，只有设置了全局上限时才需要
+func (s *shard) touch() {
+	if globalLimit() > 0 {
+		atomic.StoreInt64(&s.atime, time.Now().UnixNano())
+	}
+}
+
+//memorySpiller 是 evictor 可选实现的接口，例如 tieredStore：磁盘中的记录不计入 Bytes，
+//全局上限只需要释放内存，因此把内存中的记录移入磁盘，而不是按 RemoveOldest 先淘汰磁盘中的记录
+type memorySpiller interface {
+	//SpillOldest 把内存中最先被淘汰的记录移入磁盘，内存中没有记录时返回 false
+	SpillOldest() bool
+}
+
+//evictOldest 按淘汰策略淘汰一条不是固定的记录，store 中没有可以释放内存的记录时返回 false
+func (s *shard) evictOldest() bool {
+	s.mu.Lock()
+	defer s.unlock()
+	if s.store == nil || s.store.Len() == 0 {
+		return false
+	}
+	if sp, ok := s.store.(memorySpiller); ok {
+		return sp.SpillOldest()
+	}
+	s.store.RemoveOldest()
+	return true
+}
+
+//enforceGlobalLimit 在总用量超过全局上限时淘汰记录。
+//每次选择最久没有被访问的分片并从中淘汰，直到总用量不超过上限或该分片没有可以淘汰的记录，然后重新选择
+func enforceGlobalLimit() {
+	limit := globalLimit()
+	if limit <= 0 || atomic.LoadInt64(&globalBudget.used) <= limit {
+		return
+	}
+	if !globalBudget.evicting.TryLock() {
+		return
+	}
+	defer globalBudget.evicting.Unlock()
+	skip := make(map[*shard]bool)
+	for atomic.LoadInt64(&globalBudget.used) > limit {
+		victim := oldestShard(skip)
+		if victim == nil {
+			return
+		}
+		for atomic.LoadInt64(&globalBudget.used) > limit {
+			if !victim.evictOldest() {
+				skip[victim] = true
+				break
+			}
+		}
+	}
+}
+
+//oldestShard 返回所有 group 中有记录且最久没有被访问的分片，skip 中的分片除外
+func oldestShard(skip map[*shard]bool) *shard {
+	var victim *shard
+	var oldest int64
+	for _, g := range Groups() {
+		for _, c := range []*cache{&g.mainCache, &g.hotCache} {
+			for _, s := range c.shards {
+				if skip[s] || atomic.LoadInt64(&s.accounted) == 0 {
+					continue
+				}
+				if atime := atomic.LoadInt64(&s.atime); victim == nil || atime < oldest {
+					victim, oldest = s, atime
+				}
+			}
+		}
+	}
+	return victim
+}
@@ -0,0 +1,52 @@
+package GoCache
+
+import "fmt"
+
+//InvalidationBus 是节点间广播失效 key 的发布订阅通道，例如 Redis 的 channel 或 NATS 的 subject，见 redisbus。
+//一个 InvalidationBus 只用于一个 Group，Publish 发出的 key 需要送达所有节点（包括发布者自己）通过 Subscribe 注册的回调。
+//实现需要并发安全，回调可能在任意协程中被调用
+type InvalidationBus interface {
+	//Publish 广播一个失效的 key，返回时不要求其他节点已经收到
+	Publish(key string) error
+	//Subscribe 注册收到 key 时的回调，Group 创建时调用一次
+	Subscribe(fn func(key string)) error
+}
+
+//WithInvalidationBus 让 Invalidate、InvalidateMany 和 InvalidateByTag 通过 bus 广播失效的 key：
+//本节点删除后把 key 发布到 bus，每个节点收到后删除本节点 mainCache 和 hotCache 中的记录（DeleteLocal）。
+//节点较多时，代替逐个向每个远程节点发送删除请求，发布者只发出一次消息，但各节点的删除是最终一致的。
+//集群中所有节点都需要使用连接到同一个通道的 bus，bus 的关闭由调用者负责
+func WithInvalidationBus(bus InvalidationBus) Option {
+	return func(g *Group) {
+		g.bus = bus
+	}
+}
+
+//subscribe 在 bus 上注册 DeleteLocal，失败时只记录日志，Invalidate 仍然会发布，但本节点收不到其他节点的失效通知
+func (g *Group) subscribe() {
+	err := g.bus.Subscribe(func(key string) {
+		g.logger.logf(LevelDebug, "[GoCache] invalidated %s by bus", key)
+		g.DeleteLocal(key)
+	})
+	if err != nil {
+		g.logger.logf(LevelError, "[GoCache] subscribe invalidation bus for %s: %v", g.name, err)
+	}
+}
+
+//publish 依次发布 keys，一个 key 失败时继续发布其余的 key，返回失败的数量和第一个错误
+func (g *Group) publish(keys []string) error {
+	var failed int
+	var first error
+	for _, key := range keys {
+		if err := g.bus.Publish(key); err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("publish %d of %d keys: %v", failed, len(keys), first)
+	}
+	return nil
+}
@@ -1,9 +1,39 @@
 package GoCache
 
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
 //缓存值的抽象与封装
 
 type ByteView struct {
-	b []byte //存储真实的缓存值,选择 byte 类型是为了能够支持任意的数据类型的存储，例如字符串、图片等。
+	b         []byte            //存储真实的缓存值,选择 byte 类型是为了能够支持任意的数据类型的存储，例如字符串、图片等。
+	e         time.Time         //过期时间，零值表示永不过期
+	tombstone bool              //负缓存的墓碑记录，表示 key 在数据源中不存在
+	meta      map[string]string //SetWithMeta 写入的元数据，只读，不计入 cacheBytes
+	stale     bool              //Get 重新加载失败时返回的过期值，见 WithStaleOnError
+	shared    bool              //WithNoCopy 写入的值，ByteSlice 直接返回 b，不复制
+	//compressed 为 true 时 b 是压缩后的数据，只出现在 cache 内部，读取时由 cache 解压，见 WithValueCompression
+	compressed bool
+}
+
+//常用的元数据 key，ServeHTTP 使用它们设置响应头
+const (
+	MetaContentType = "Content-Type"
+	MetaETag        = "ETag"
+)
+
+//NewByteView 用 b 的拷贝创建 ByteView，之后修改 b 不会影响 ByteView，例如用于在包外构造缓存值
+func NewByteView(b []byte) ByteView {
+	return ByteView{b: cloneBytes(b)}
+}
+
+//WrapByteView 直接用 b 创建 ByteView，不复制 b。调用者把 b 的所有权交给 ByteView，之后不能再修改 b，
+//用于 ByteViewGetter 返回新分配的数据，避免一次复制
+func WrapByteView(b []byte) ByteView {
+	return ByteView{b: b}
 }
 
 func (v ByteView) Len() int {
@@ -11,6 +41,9 @@ func (v ByteView) Len() int {
 }
 
 func (v ByteView) ByteSlice() []byte {
+	if v.shared {
+		return v.b //WithNoCopy 由调用者保证不修改，直接返回缓存中的切片
+	}
 	return cloneBytes(v.b) //b 是只读的，使用 ByteSlice() 方法返回一个拷贝，防止缓存值被外部程序修改
 }
 
@@ -18,8 +51,68 @@ func (v ByteView) String() string {
 	return string(v.b)
 }
 
+//Reader 返回读取缓存值的 io.Reader，不会复制缓存值，例如用于计算哈希或 io.Copy 到 http.ResponseWriter
+func (v ByteView) Reader() io.Reader {
+	return bytes.NewReader(v.b)
+}
+
+//WriteTo 把缓存值写入 w，实现 io.WriterTo。与 ByteSlice 不同，不会复制缓存值，w 不能修改或保留传入的切片
+func (v ByteView) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(v.b)
+	return int64(n), err
+}
+
+//Equal 判断两个缓存值的内容是否相同，不比较过期时间
+func (v ByteView) Equal(other ByteView) bool {
+	return bytes.Equal(v.b, other.b)
+}
+
+//Meta 返回名为 name 的元数据，不存在时返回空字符串
+func (v ByteView) Meta(name string) string {
+	return v.meta[name]
+}
+
+//Metadata 返回所有元数据的拷贝，没有元数据时返回 nil
+func (v ByteView) Metadata() map[string]string {
+	return cloneMeta(v.meta)
+}
+
+//Stale 返回值是否已经过期，只有开启 WithStaleOnError 后重新加载失败时 Get 才会返回过期的值
+func (v ByteView) Stale() bool {
+	return v.stale
+}
+
+//Expire 返回缓存值的过期时间，零值表示永不过期
+func (v ByteView) Expire() time.Time {
+	return v.e
+}
+
+//expired 判断缓存值在 now 时刻是否已经过期
+func (v ByteView) expired(now time.Time) bool {
+	return !v.e.IsZero() && !now.Before(v.e)
+}
+
+func cloneMeta(meta map[string]string) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(meta))
+	for k, v := range meta {
+		c[k] = v
+	}
+	return c
+}
+
 func cloneBytes(b []byte) []byte {
 	c := make([]byte, len(b))
 	copy(c, b)
 	return c
 }
+
+//expireAt 根据 ttl 计算过期时间，ttl <= 0 表示永不过期，返回零值
+func expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
@@ -2,36 +2,724 @@ package GoCache
 
 import (
 	"GoCache/LRU_Cache"
+	"fmt"
+	"math/rand"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
-//实例化 lru，封装 get 和 add 方法，并添加互斥锁 mu
+//evictor 是 cache 底层的淘汰策略，LRU_Cache 中的 Cache、LFU、TinyLFU、FIFO、Random 都实现了该接口
+//实现不需要并发安全，由所在分片的 mu 保护；记录被删除时需要调用创建时传入的回调
+type evictor interface {
+	Add(key string, value LRU_Cache.Value)
+	Get(key string) (LRU_Cache.Value, bool)
+	Peek(key string) (LRU_Cache.Value, bool) //与 Get 相同，但不影响淘汰顺序
+	Remove(key string)
+	RemoveOldest()
+	Len() int
+	Bytes() int64
+	Resize(maxBytes int64) //修改容量，缩小时立即淘汰超出的记录
+	Range(fn func(key string, value LRU_Cache.Value) bool)
+}
+
+//expiredRemover 是 evictor 可选实现的接口，不通过 Range 读取所有记录的值就能删除过期记录，例如 tieredStore。
+//返回剩余记录中最早的过期时间，没有设置过期时间的记录时返回零值
+type expiredRemover interface {
+	RemoveExpired(now time.Time) time.Time
+}
+
+//EvictionPolicy 选择缓存写满时的淘汰策略
+type EvictionPolicy int
+
+const (
+	PolicyLRU     EvictionPolicy = iota //淘汰最久未访问的记录，默认策略
+	PolicyLFU                           //淘汰访问次数最少的记录，适合热点稳定的访问模式
+	PolicyTinyLFU                       //W-TinyLFU，按估计的访问频率决定新记录能否进入缓存，能抵抗扫描污染
+	PolicyFIFO                          //按写入顺序淘汰，忽略访问，适合写多读少、类似队列的缓存
+	PolicyRandom                        //随机淘汰，O(1) 且 Get 没有额外开销，适合访问没有明显规律的缓存
+	PolicyARC                           //Adaptive Replacement Cache，根据命中的幽灵记录自动调整最近访问与访问频率两段的大小
+	Policy2Q                            //2Q，新记录先进入 FIFO 队列，被挤出后再次写入才进入 LRU 主区，比 ARC 简单，同样能抵抗扫描污染
+)
+
+const (
+	DefaultShards = 256     //cache 默认的分片数
+	minShardBytes = 1 << 16 //每个分片至少分到的容量，容量较小时减少分片数，避免每个分片只能放下很少的记录
+)
+
+//cache 把 key 哈希到若干个分片，每个分片有独立的锁和 store，并发访问不同分片时互不阻塞。
+//cacheBytes 平均分给各个分片，淘汰在分片内进行，因此整体上是近似的 LRU/LFU。
+//以下配置字段由 Group 的选项设置，之后调用 init 创建分片，init 之后不再修改
 type cache struct {
+	cacheBytes int64
+	nshards    int            //分片数，<= 0 表示 DefaultShards
+	policy     EvictionPolicy //创建 store 时使用的淘汰策略
+	tinyLFU    tinyLFUConfig  //policy 为 PolicyTinyLFU 时的参数
+	twoQueue   twoQueueConfig //policy 为 Policy2Q 时的参数
+	diskDir    string         //磁盘层的目录，为空表示不使用磁盘层，见 WithDiskTier
+	diskBytes  int64          //磁盘层的容量，与 cacheBytes 一样平均分给各个分片
+	staleFor   time.Duration  //记录过期后继续保留的时间，见 WithStaleOnError
+	jitter     float64        //写入时剩余有效期随机调整的比例，见 WithTTLJitter
+	//compressor 不为 nil 时压缩不小于 compressAbove 字节的值，见 WithValueCompression
+	compressor    Compressor
+	compressAbove int
+	//记录离开缓存时的回调，在释放分片的锁之后调用，回调中可以安全地访问缓存
+	onEvict func(key string, value ByteView, reason EvictReason)
+	//onRemove 与 onEvict 相同，但只传入 key，供 Group 内部维护与记录相关的状态，例如 tagIndex
+	onRemove func(key string)
+	shards   []*shard
+
+	mu       sync.Mutex    //保护 stop，以及 init 之后通过 resize 修改的 cacheBytes
+	stop     chan struct{} //关闭后通知后台清理协程退出，为 nil 表示没有启动后台清理
+	resizeMu sync.Mutex    //保证并发的 resize 按顺序作用到所有分片
+}
+
+//shard 是 cache 的一个分片，实例化 store，封装 get 和 add 方法，并添加互斥锁 mu
+type shard struct {
 	mu         sync.Mutex
-	lru        *LRU_Cache.Cache
+	store      evictor
+	policy     EvictionPolicy
+	tinyLFU    tinyLFUConfig
+	twoQueue   twoQueueConfig
 	cacheBytes int64
+	disk       *diskTier     //磁盘层，为 nil 表示不使用
+	staleFor   time.Duration //记录过期后继续保留的时间，期间可以通过 getStale 读取
+	ttls       int           //store 中设置了过期时间的记录数，为 0 时后台清理直接跳过
+	//nextExpiry 是 store 中最早过期时间的下界，还没到这个时间时不需要扫描过期记录，见 mayHaveExpired
+	nextExpiry time.Time
+	onEvict    func(key string, value ByteView, reason EvictReason)
+	onRemove   func(key string)
+	reason     EvictReason    //当前操作删除记录的原因，由持有 mu 的操作设置
+	pending    []evictedEntry //持有 mu 期间被删除、等待回调的记录
+	//pins 是通过 Pin 固定的 key，pinned 是其中已经缓存的记录，不在 store 中，不会被淘汰或过期清理，
+	//store 的容量为 cacheBytes 减去 pinnedBytes，见 storeBytes
+	pins        map[string]bool
+	pinned      map[string]ByteView
+	pinnedBytes int64
+	moving      bool //为 true 时 store 删除的记录正在移入 pinned，不触发回调
+	//accounted 是已经计入 globalBudget 的用量，atime 是最近一次访问的时间（UnixNano），都通过 sync/atomic 读写
+	accounted int64
+	atime     int64
 }
 
-func (c *cache) add(key string, value ByteView) {
+//init 按 cacheBytes 和 nshards 创建分片，各分片的 store 仍然延迟到第一次写入时创建
+func (c *cache) init() {
+	n := c.nshards
+	if n <= 0 {
+		n = DefaultShards
+	}
+	if c.cacheBytes > 0 && c.cacheBytes/int64(n) < minShardBytes {
+		n = int(c.cacheBytes / minShardBytes)
+		if n < 1 {
+			n = 1
+		}
+	}
+	onEvict := c.onEvict
+	if c.compressor != nil && onEvict != nil {
+		//回调收到的是解压后的值，解压失败时仍然传入压缩的值
+		onEvict = func(key string, value ByteView, reason EvictReason) {
+			if v, err := c.decompress(value); err == nil {
+				value = v
+			}
+			c.onEvict(key, value, reason)
+		}
+	}
+	c.shards = make([]*shard, n)
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			policy:     c.policy,
+			tinyLFU:    c.tinyLFU,
+			twoQueue:   c.twoQueue,
+			cacheBytes: shardBytes(c.cacheBytes, n, i),
+			staleFor:   c.staleFor,
+			onEvict:    onEvict,
+			onRemove:   c.onRemove,
+		}
+		if c.diskDir != "" {
+			dir := filepath.Join(c.diskDir, fmt.Sprintf("shard-%03d", i))
+			c.shards[i].disk = newDiskTier(dir, shardBytes(c.diskBytes, n, i), c.onEvict != nil)
+		}
+	}
+}
+
+//shardBytes 返回 n 个分片中第 i 个分片的容量，除不尽的部分分给前面的分片，保证各分片容量之和等于 total
+func shardBytes(total int64, n, i int) int64 {
+	bytes := total / int64(n)
+	if int64(i) < total%int64(n) {
+		bytes++
+	}
+	return bytes
+}
+
+//maxBytes 返回当前的容量，0 表示不限制
+func (c *cache) maxBytes() int64 {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	//判断了 c.lru 是否为 nil，如果等于 nil 再创建实例。
-	//这种方法称之为延迟初始化(Lazy Initialization)，一个对象的延迟初始化意味着该对象的创建将会延迟至第一次使用该对象时。
-	//主要用于提高性能，并减少程序内存要求。
-	if c.lru == nil {
-		c.lru = LRU_Cache.New(c.cacheBytes, nil)
+	return c.cacheBytes
+}
+
+//resize 修改容量并平均分给现有的分片，分片数不变。
+//缩小时各分片立即淘汰超出的记录，并以 EvictCapacity 调用 onEvict
+func (c *cache) resize(cacheBytes int64) {
+	c.resizeMu.Lock()
+	defer c.resizeMu.Unlock()
+	c.mu.Lock()
+	c.cacheBytes = cacheBytes
+	c.mu.Unlock()
+	for i, s := range c.shards {
+		s.resize(shardBytes(cacheBytes, len(c.shards), i))
+	}
+}
+
+//len 返回所有分片的记录数之和
+func (c *cache) len() int {
+	var n int
+	for _, s := range c.shards {
+		n += s.len()
+	}
+	return n
+}
+
+//shardFor 用 FNV-1a 把 key 哈希到分片，不分配内存
+func (c *cache) shardFor(key string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
 	}
-	c.lru.Add(key, value)
+	return c.shards[h%uint32(len(c.shards))]
+}
+
+func (c *cache) add(key string, value ByteView) {
+	c.shardFor(key).add(key, c.compress(c.jittered(value)))
+}
+
+//jittered 把 value 的剩余有效期随机调整 ±jitter，永不过期的记录不变
+func (c *cache) jittered(value ByteView) ByteView {
+	if c.jitter <= 0 || value.e.IsZero() {
+		return value
+	}
+	if remaining := time.Until(value.e); remaining > 0 {
+		value.e = value.e.Add(time.Duration((rand.Float64()*2 - 1) * c.jitter * float64(remaining)))
+	}
+	return value
 }
 
 func (c *cache) get(key string) (value ByteView, ok bool) {
+	return c.decompressed(c.shardFor(key).get(key))
+}
+
+//getStale 返回已经过期、但仍在 staleFor 内的记录，不影响淘汰顺序
+func (c *cache) getStale(key string) (value ByteView, ok bool) {
+	return c.decompressed(c.shardFor(key).getStale(key))
+}
+
+//peek 与 get 相同，但不影响淘汰顺序
+func (c *cache) peek(key string) (value ByteView, ok bool) {
+	return c.decompressed(c.shardFor(key).peek(key))
+}
+
+//getOrAdd 返回 key 已有的未过期记录和 true，否则写入 value 并返回 value 和 false，见 Group.GetOrStore
+func (c *cache) getOrAdd(key string, value ByteView) (ByteView, bool) {
+	value = c.jittered(value)
+	actual, loaded := c.shardFor(key).getOrAdd(key, c.compress(value))
+	if !loaded {
+		return value, false
+	}
+	if v, ok := c.decompressed(actual, true); ok {
+		return v, true
+	}
+	//已有的记录解压失败时视为不存在
+	c.add(key, value)
+	return value, false
+}
+
+//extend 把未过期记录的过期时间改为 now+ttl，见 Group.Touch
+func (c *cache) extend(key string, ttl time.Duration) bool {
+	return c.shardFor(key).extend(key, ttl)
+}
+
+//remove 删除 key 对应的记录，key 不存在时什么也不做
+func (c *cache) remove(key string) {
+	c.shardFor(key).remove(key)
+}
+
+//bytes 返回所有分片已使用的内存之和
+func (c *cache) bytes() int64 {
+	var n int64
+	for _, s := range c.shards {
+		n += s.bytes()
+	}
+	return n
+}
+
+//clear 丢弃所有记录，释放各分片 store 占用的内存，每次只持有一个分片的锁
+func (c *cache) clear() {
+	for _, s := range c.shards {
+		s.clear()
+	}
+}
+
+//rangeEntries 依次遍历各分片中未过期的记录，跳过墓碑记录，fn 返回 false 时停止遍历。
+//每个分片在持有锁时复制记录，fn 在锁外调用，因此 fn 中可以访问缓存
+func (c *cache) rangeEntries(fn func(key string, value ByteView) bool) {
+	for _, s := range c.shards {
+		for _, e := range s.snapshot(time.Now()) {
+			v, ok := c.decompressed(e.value, true)
+			if !ok {
+				continue
+			}
+			if !fn(e.key, v) {
+				return
+			}
+		}
+	}
+}
+
+//cleanup 依次清理各分片中已过期的记录，每次只持有一个分片的锁
+func (c *cache) cleanup() {
+	for _, s := range c.shards {
+		s.cleanup()
+	}
+}
+
+//EvictReason 记录离开缓存的原因
+type EvictReason int
+
+const (
+	EvictCapacity EvictReason = iota //超出容量被淘汰
+	EvictExpired                     //过期后被删除
+	EvictDeleted                     //被 Delete 等操作主动删除
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "evicted"
+	case EvictExpired:
+		return "expired"
+	case EvictDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+//tinyLFUConfig 的零值表示使用 LRU_Cache 中的默认参数
+type tinyLFUConfig struct {
+	windowRatio float64
+	sketchWidth int
+}
+
+//twoQueueConfig 的零值同样表示使用默认参数
+type twoQueueConfig struct {
+	inRatio    float64
+	ghostRatio float64
+}
+
+type keyValue struct {
+	key   string
+	value ByteView
+}
+
+type evictedEntry struct {
+	key    string
+	value  ByteView
+	reason EvictReason
+}
+
+//startCleanup 启动后台清理协程，每隔 interval 扫描一次并删除已过期的记录，主动释放内存
+func (c *cache) startCleanup(interval time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.lru == nil {
+	if c.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	c.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.cleanup()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+//stopCleanup 停止后台清理协程，可以重复调用
+func (c *cache) stopCleanup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+//unlock 释放 mu，然后依次调用等待中的 onEvict 回调，用于替代 s.mu.Unlock()
+func (s *shard) unlock() {
+	pending := s.pending
+	s.pending = nil
+	s.reason = EvictCapacity
+	grew := s.account()
+	s.mu.Unlock()
+	for _, e := range pending {
+		if s.onRemove != nil {
+			s.onRemove(e.key)
+		}
+		if s.onEvict != nil {
+			s.onEvict(e.key, e.value, e.reason)
+		}
+	}
+	if grew {
+		enforceGlobalLimit()
+	}
+}
+
+func (s *shard) add(key string, value ByteView) {
+	s.mu.Lock()
+	defer s.unlock()
+	//判断了 s.store 是否为 nil，如果等于 nil 再创建实例。
+	//这种方法称之为延迟初始化(Lazy Initialization)，一个对象的延迟初始化意味着该对象的创建将会延迟至第一次使用该对象时。
+	//主要用于提高性能，并减少程序内存要求。
+	if s.store == nil {
+		s.store = s.newStore()
+	}
+	s.touch()
+	s.addLocked(key, value)
+}
+
+//addLocked 写入记录，调用前需要持有 mu 并创建 store
+func (s *shard) addLocked(key string, value ByteView) {
+	if s.pins[key] && s.addPinned(key, value) {
 		return
 	}
-	if v, ok := c.lru.Get(key); ok {
+	//覆盖已有记录时，旧记录不会触发 onEvicted，需要在这里维护 ttls。使用 Peek，覆盖不算一次访问
+	if old, ok := s.store.Peek(key); ok && !old.(ByteView).e.IsZero() {
+		s.ttls--
+	}
+	//写入后将超出容量时，先清理已过期的记录，避免过期记录占用 cacheBytes 而挤掉仍然有效的记录
+	if s.cacheBytes != 0 && s.store.Bytes()+LRU_Cache.EntryBytes(key, value) > s.storeBytes() {
+		if now := time.Now(); s.mayHaveExpired(now) {
+			s.removeExpired(now)
+		}
+	}
+	if !value.e.IsZero() {
+		s.ttls++
+		s.trackExpiry(value.e)
+	}
+	s.store.Add(key, value)
+}
+
+//trackExpiry 在写入过期时间为 e 的记录时更新 nextExpiry
+func (s *shard) trackExpiry(e time.Time) {
+	s.nextExpiry = earliestExpiry(s.nextExpiry, e)
+}
+
+//mayHaveExpired 判断 store 中是否可能有在 now 时刻需要删除的过期记录，
+//没有设置过期时间的记录或者还没到 nextExpiry 时返回 false，避免每次写满都扫描整个分片
+func (s *shard) mayHaveExpired(now time.Time) bool {
+	if s.ttls == 0 {
+		return false
+	}
+	return s.nextExpiry.IsZero() || (ByteView{e: s.nextExpiry}).expired(now.Add(-s.staleFor))
+}
+
+func (s *shard) get(key string) (value ByteView, ok bool) {
+	s.mu.Lock()
+	defer s.unlock()
+	s.touch()
+	if v, ok := s.pinned[key]; ok {
+		//过期的固定记录视为未命中，但不删除，重新加载后被覆盖
+		return v, !v.expired(time.Now())
+	}
+	if s.store == nil {
+		return
+	}
+	if v, ok := s.store.Get(key); ok {
+		//过期的记录视为未命中，并顺便从缓存中删除，在 staleFor 内的过期记录保留给 getStale
+		if now := time.Now(); v.(ByteView).expired(now) {
+			if v.(ByteView).expired(now.Add(-s.staleFor)) {
+				s.reason = EvictExpired
+				s.store.Remove(key)
+			}
+			return ByteView{}, false
+		}
 		return v.(ByteView), ok
 	}
 	return
 }
+
+//peek 只读取记录，过期的记录视为未命中，但不会删除，因此也不会触发 onEvict
+func (s *shard) peek(key string) (value ByteView, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.pinned[key]; ok {
+		return v, !v.expired(time.Now())
+	}
+	if s.store == nil {
+		return
+	}
+	if v, ok := s.store.Peek(key); ok && !v.(ByteView).expired(time.Now()) {
+		return v.(ByteView), true
+	}
+	return
+}
+
+//getOrAdd 在一次持有 mu 期间完成查找和写入：已有未过期的记录时返回它和 true，否则写入 value 并返回 false。
+//过期的记录和墓碑记录视为不存在，被 value 覆盖
+func (s *shard) getOrAdd(key string, value ByteView) (ByteView, bool) {
+	s.mu.Lock()
+	defer s.unlock()
+	if s.store == nil {
+		s.store = s.newStore()
+	}
+	s.touch()
+	now := time.Now()
+	if v, ok := s.pinned[key]; ok && !v.expired(now) && !v.tombstone {
+		return v, true
+	}
+	if lv, ok := s.store.Get(key); ok {
+		if v := lv.(ByteView); !v.expired(now) && !v.tombstone {
+			return v, true
+		}
+	}
+	s.addLocked(key, value)
+	return value, false
+}
+
+//extend 在持有 mu 时修改过期时间，记录的内容不变（压缩的值不需要解压）。
+//修改后的记录重新写入 store，与 get 相同算作一次访问，各淘汰策略覆盖已有记录时不会触发回调
+func (s *shard) extend(key string, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.unlock()
+	now := time.Now()
+	e := expireAt(ttl)
+	if v, ok := s.pinned[key]; ok {
+		if v.expired(now) || v.tombstone {
+			return false
+		}
+		v.e = e
+		s.pinned[key] = v
+		return true
+	}
+	if s.store == nil {
+		return false
+	}
+	lv, ok := s.store.Peek(key)
+	if !ok {
+		return false
+	}
+	v := lv.(ByteView)
+	if v.expired(now) || v.tombstone {
+		return false
+	}
+	s.touch()
+	if !v.e.IsZero() {
+		s.ttls--
+	}
+	if !e.IsZero() {
+		s.ttls++
+		s.trackExpiry(e)
+	}
+	v.e = e
+	s.store.Add(key, v)
+	return true
+}
+
+func (s *shard) getStale(key string) (value ByteView, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.staleFor <= 0 || s.store == nil {
+		return
+	}
+	v, ok := s.pinned[key]
+	if !ok {
+		var lv LRU_Cache.Value
+		if lv, ok = s.store.Peek(key); !ok {
+			return
+		}
+		v = lv.(ByteView)
+	}
+	if now := time.Now(); v.expired(now) && !v.expired(now.Add(-s.staleFor)) {
+		return v, true
+	}
+	return ByteView{}, false
+}
+
+//newStore 按 policy 创建底层淘汰策略，配置了磁盘层时在外面包一层 tieredStore
+func (s *shard) newStore() evictor {
+	if s.disk != nil {
+		return newTieredStore(s.newMemStore, s.disk, s.onEvicted)
+	}
+	return s.newMemStore(s.onEvicted)
+}
+
+//newMemStore 按 policy 创建内存中的淘汰策略，记录被删除时调用 onEvicted
+func (s *shard) newMemStore(onEvicted func(string, LRU_Cache.Value)) evictor {
+	switch s.policy {
+	case PolicyLFU:
+		return LRU_Cache.NewLFU(s.storeBytes(), onEvicted)
+	case PolicyTinyLFU:
+		return LRU_Cache.NewTinyLFU(s.storeBytes(), s.tinyLFU.windowRatio, s.tinyLFU.sketchWidth, onEvicted)
+	case PolicyFIFO:
+		return LRU_Cache.NewFIFO(s.storeBytes(), onEvicted)
+	case PolicyRandom:
+		return LRU_Cache.NewRandom(s.storeBytes(), onEvicted)
+	case PolicyARC:
+		return LRU_Cache.NewARC(s.storeBytes(), onEvicted)
+	case Policy2Q:
+		return LRU_Cache.NewTwoQueue(s.storeBytes(), s.twoQueue.inRatio, s.twoQueue.ghostRatio, onEvicted)
+	default:
+		return LRU_Cache.New(s.storeBytes(), onEvicted)
+	}
+}
+
+//bytes 返回当前已使用的内存，包括固定的记录
+func (s *shard) bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		return 0
+	}
+	return s.store.Bytes() + s.pinnedBytes
+}
+
+//remove 删除 key 对应的记录，key 不存在时什么也不做
+func (s *shard) remove(key string) {
+	s.mu.Lock()
+	defer s.unlock()
+	s.reason = EvictDeleted
+	if v, ok := s.pinned[key]; ok {
+		s.removePinned(key, v)
+		return
+	}
+	if s.store == nil {
+		return
+	}
+	s.store.Remove(key)
+}
+
+//len 返回记录数，包括固定的记录和已过期但尚未被清理的记录
+func (s *shard) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		return 0
+	}
+	return s.store.Len() + len(s.pinned)
+}
+
+func (s *shard) resize(cacheBytes int64) {
+	s.mu.Lock()
+	defer s.unlock()
+	s.cacheBytes = cacheBytes
+	s.resizeStore()
+}
+
+//snapshot 返回在 now 时刻未过期的记录，不包括墓碑记录，也不影响淘汰顺序
+func (s *shard) snapshot(now time.Time) []keyValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		return nil
+	}
+	entries := make([]keyValue, 0, s.store.Len()+len(s.pinned))
+	for key, v := range s.pinned {
+		if !v.tombstone && !v.expired(now) {
+			entries = append(entries, keyValue{key: key, value: v})
+		}
+	}
+	s.store.Range(func(key string, value LRU_Cache.Value) bool {
+		if v := value.(ByteView); !v.tombstone && !v.expired(now) {
+			entries = append(entries, keyValue{key: key, value: v})
+		}
+		return true
+	})
+	return entries
+}
+
+//clear 丢弃所有记录，释放 store 占用的内存，设置了 onEvict 时每条记录以 EvictDeleted 触发回调
+func (s *shard) clear() {
+	s.mu.Lock()
+	defer s.unlock()
+	if s.store != nil && s.notify() {
+		s.store.Range(func(key string, value LRU_Cache.Value) bool {
+			if v := value.(ByteView); !v.tombstone {
+				s.pending = append(s.pending, evictedEntry{key: key, value: v, reason: EvictDeleted})
+			}
+			return true
+		})
+		for key, v := range s.pinned {
+			if !v.tombstone {
+				s.pending = append(s.pending, evictedEntry{key: key, value: v, reason: EvictDeleted})
+			}
+		}
+	}
+	s.store = nil
+	s.pinned, s.pinnedBytes = nil, 0
+	if s.disk != nil {
+		s.disk.clear()
+	}
+	s.ttls = 0
+	s.nextExpiry = time.Time{}
+}
+
+//removeExpired 删除所有在 now 时刻已经过期的记录，过期不足 staleFor 的记录除外，调用方需持有 s.mu
+func (s *shard) removeExpired(now time.Time) {
+	now = now.Add(-s.staleFor)
+	reason := s.reason
+	s.reason = EvictExpired
+	defer func() { s.reason = reason }()
+	if r, ok := s.store.(expiredRemover); ok {
+		s.nextExpiry = r.RemoveExpired(now)
+		return
+	}
+	var keys []string
+	var next time.Time
+	s.store.Range(func(key string, value LRU_Cache.Value) bool {
+		if v := value.(ByteView); v.expired(now) {
+			keys = append(keys, key)
+		} else {
+			next = earliestExpiry(next, v.e)
+		}
+		return true
+	})
+	for _, key := range keys {
+		s.store.Remove(key)
+	}
+	s.nextExpiry = next
+}
+
+//onEvicted 在记录被 store 删除时调用，调用时已持有 s.mu，onEvict 回调推迟到 unlock 时调用
+func (s *shard) onEvicted(key string, value LRU_Cache.Value) {
+	v := value.(ByteView)
+	if !v.e.IsZero() {
+		s.ttls--
+	}
+	if s.moving {
+		return
+	}
+	//负缓存的墓碑记录只在内部使用，不通知调用方
+	if s.notify() && !v.tombstone {
+		s.pending = append(s.pending, evictedEntry{key: key, value: v, reason: s.reason})
+	}
+}
+
+//notify 判断记录被删除时是否需要回调
+func (s *shard) notify() bool {
+	return s.onEvict != nil || s.onRemove != nil
+}
+
+//cleanup 与 add/get 使用同一把锁，没有任何记录设置过期时间时不做任何事
+func (s *shard) cleanup() {
+	s.mu.Lock()
+	defer s.unlock()
+	if now := time.Now(); s.store != nil && s.mayHaveExpired(now) {
+		s.removeExpired(now)
+	}
+}
@@ -0,0 +1,79 @@
+package GoCache
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"strings"
+)
+
+//Codec 负责节点间 HTTP 请求体和响应体的编解码，消息都是 gocachepb 中的类型。
+//请求方通过 Content-Type 声明请求体的格式，通过 Accept 选择响应的格式，见 WithCodec
+type Codec interface {
+	Marshal(m proto.Message) ([]byte, error)
+	Unmarshal(data []byte, m proto.Message) error
+	ContentType() string
+}
+
+//ProtobufCodec 使用 protobuf 二进制格式，是节点间通信的默认 Codec
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Marshal(m proto.Message) ([]byte, error) {
+	return proto.Marshal(m)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, m proto.Message) error {
+	return proto.Unmarshal(data, m)
+}
+
+func (ProtobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+//ProtoJSONCodec 使用 protobuf 的 JSON 映射，bytes 类型的字段编码为 base64，方便非 Go 的服务对接和用 curl 调试，例如：
+//	curl -H 'Accept: application/json' http://localhost:8001/_gocache/scores/Tom
+type ProtoJSONCodec struct{}
+
+func (ProtoJSONCodec) Marshal(m proto.Message) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+func (ProtoJSONCodec) Unmarshal(data []byte, m proto.Message) error {
+	return protojson.Unmarshal(data, m)
+}
+
+func (ProtoJSONCodec) ContentType() string {
+	return "application/json"
+}
+
+//codecFor 返回 Content-Type 对应的 Codec，custom 为通过 WithCodec 设置的 Codec。
+//没有匹配时使用 ProtobufCodec，早期版本的节点使用 application/octet-stream 发送 protobuf，同样按 protobuf 处理
+func codecFor(contentType string, custom Codec) Codec {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if c, ok := matchCodec(mediaType, custom); ok {
+		return c
+	}
+	return ProtobufCodec{}
+}
+
+//negotiateCodec 按 Accept 中列出的顺序选择第一个支持的 Codec，忽略 q 参数，都不支持时使用 ProtobufCodec
+func negotiateCodec(accept string, custom Codec) Codec {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if c, ok := matchCodec(mediaType, custom); ok {
+			return c
+		}
+	}
+	return ProtobufCodec{}
+}
+
+func matchCodec(mediaType string, custom Codec) (Codec, bool) {
+	if custom != nil && strings.EqualFold(mediaType, custom.ContentType()) {
+		return custom, true
+	}
+	for _, c := range []Codec{ProtobufCodec{}, ProtoJSONCodec{}} {
+		if strings.EqualFold(mediaType, c.ContentType()) {
+			return c, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,109 @@
+package GoCache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/golang/snappy"
+	"io/ioutil"
+)
+
+//Compressor 负责缓存值在内存中的压缩和解压，见 WithValueCompression
+type Compressor interface {
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+//SnappyCompressor 使用 snappy 压缩，速度快、压缩率适中，是 WithValueCompression 默认的 Compressor
+type SnappyCompressor struct{}
+
+func (SnappyCompressor) Compress(b []byte) ([]byte, error) {
+	return snappy.Encode(nil, b), nil
+}
+
+func (SnappyCompressor) Decompress(b []byte) ([]byte, error) {
+	return snappy.Decode(nil, b)
+}
+
+//GzipCompressor 使用 gzip 压缩，压缩率比 snappy 高，但压缩和解压都慢得多。Level 为 0 时使用 gzip.DefaultCompression
+type GzipCompressor struct {
+	Level int
+}
+
+func (c GzipCompressor) Compress(b []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GzipCompressor) Decompress(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+//WithValueCompression 在内存中压缩不小于 threshold 字节的值，Get 时解压，容量按压缩后的大小计算，
+//适合 JSON 等压缩率高的值，用 CPU 换取更多的缓存记录。c 为 nil 时使用 SnappyCompressor。
+//压缩后没有变小的值按原样保存。mainCache、hotCache 和磁盘层中都保存压缩后的值，
+//OnEvicted 回调、Range 和 SaveSnapshot 得到的是解压后的值。threshold <= 0 时不压缩
+func WithValueCompression(threshold int, c Compressor) Option {
+	return func(g *Group) {
+		if threshold <= 0 {
+			c = nil
+		} else if c == nil {
+			c = SnappyCompressor{}
+		}
+		g.mainCache.compressor, g.mainCache.compressAbove = c, threshold
+		g.hotCache.compressor, g.hotCache.compressAbove = c, threshold
+	}
+}
+
+//compress 压缩不小于 compressAbove 字节的值，失败或没有变小时返回原值
+func (c *cache) compress(v ByteView) ByteView {
+	if c.compressor == nil || v.compressed || v.Len() < c.compressAbove {
+		return v
+	}
+	b, err := c.compressor.Compress(v.b)
+	if err != nil || len(b) >= v.Len() {
+		return v
+	}
+	v.b, v.compressed = b, true
+	return v
+}
+
+//decompress 解压 compress 压缩过的值
+func (c *cache) decompress(v ByteView) (ByteView, error) {
+	if !v.compressed {
+		return v, nil
+	}
+	b, err := c.compressor.Decompress(v.b)
+	if err != nil {
+		return ByteView{}, err
+	}
+	v.b, v.compressed = b, false
+	return v, nil
+}
+
+//decompressed 用于 get 等读取操作，解压失败的记录视为未命中
+func (c *cache) decompressed(v ByteView, ok bool) (ByteView, bool) {
+	if !ok || !v.compressed {
+		return v, ok
+	}
+	v, err := c.decompress(v)
+	return v, err == nil
+}
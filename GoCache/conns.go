@@ -0,0 +1,117 @@
+package GoCache
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+//WithMaxConnsPerHost 限制与每个远程节点同时建立的连接数（包括正在使用和空闲的），
+//达到上限后新的请求排队等待已有连接空闲，而不是返回错误，等待时间计入 WithTimeout 和调用方 ctx。
+//用于避免流量集中到同一个节点时连接数无限增长耗尽文件描述符。n <= 0 表示不限制，默认不限制。
+//与 WithMaxIdleConnsPerHost、WithIdleConnTimeout 相同，设置了 WithHTTPClient 时不生效
+func WithMaxConnsPerHost(n int) PoolOption {
+	return func(p *HTTPPool) {
+		p.maxConnsPerHost = n
+	}
+}
+
+//WithMaxIdleConnsPerHost 设置与每个远程节点保持的空闲连接数，默认为 defaultMaxIdleConnsPerHost。
+//n <= 0 时使用 http.Transport 的默认值 2
+func WithMaxIdleConnsPerHost(n int) PoolOption {
+	return func(p *HTTPPool) {
+		p.maxIdleConnsPerHost = n
+	}
+}
+
+//WithIdleConnTimeout 设置空闲连接被关闭前保持的时间，默认为 defaultIdleConnTimeout，d <= 0 表示不关闭
+func WithIdleConnTimeout(d time.Duration) PoolOption {
+	return func(p *HTTPPool) {
+		p.idleConnTimeout = d
+	}
+}
+
+//connTracker 记录 HTTPPool 创建的客户端与每个地址（host:port）之间当前打开的连接数
+type connTracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{counts: make(map[string]int)}
+}
+
+//wrap 包装 dial，建立的连接被计入 addr 的连接数，关闭时减去
+func (t *connTracker) wrap(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.counts[addr]++
+		t.mu.Unlock()
+		return &trackedConn{Conn: conn, release: func() { t.release(addr) }}, nil
+	}
+}
+
+func (t *connTracker) release(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.counts[addr]--; t.counts[addr] <= 0 {
+		delete(t.counts, addr)
+	}
+}
+
+func (t *connTracker) count(addr string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[addr]
+}
+
+//trackedConn 在第一次 Close 时通知 connTracker
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+//dialAddr 返回 Transport 访问节点 peer 时拨号的地址，没有端口时按协议补上默认端口
+func dialAddr(peer string) string {
+	u, err := url.Parse(peerURL(peer))
+	if err != nil || u.Host == "" {
+		return peer
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+//PeerConns 返回与每个远程节点当前打开的连接数，包括正在使用和空闲的连接。
+//只统计 HTTPPool 自己创建的客户端，设置了 WithHTTPClient 时返回 nil
+func (p *HTTPPool) PeerConns() map[string]int {
+	if p.conns == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	conns := make(map[string]int, len(p.httpGetters))
+	for peer := range p.httpGetters {
+		if peer != p.self {
+			conns[peer] = p.conns.count(dialAddr(peer))
+		}
+	}
+	return conns
+}
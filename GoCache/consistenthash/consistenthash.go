@@ -2,8 +2,10 @@ package consistenthash
 
 import (
 	"hash/crc32"
+	"math"
 	"sort"
 	"strconv"
+	"sync"
 )
 
 //哈希将字节映射到uint32
@@ -17,19 +19,32 @@ type Hash func(data []byte) uint32
 //虚拟节点倍数 replicas；
 //哈希环 keys；
 //虚拟节点与真实节点的映射表 hashMap，键是虚拟节点的哈希值，值是真实节点的名称。
+//Map 可以并发使用：Get、GetN 持有读锁，互相不阻塞；Add、Remove 持有写锁，与查询互斥，
+//查询总是看到某次修改完成之前或之后的完整哈希环。
+//哈希环只由真实节点及其权重决定，与 Add、Remove 的调用顺序无关，见 rebuild。
 type Map struct {
+	mu       sync.RWMutex //保护 keys、hashMap 和 nodes
 	hash     Hash
 	replicas int
 	keys     []int
 	hashMap  map[int]string
+	nodes    map[string]int //真实节点的名称及其虚拟节点数
+	//LoadFactor 是 GetBounded 允许单个节点承担的负载相对平均负载的倍数，New 设置为 DefaultLoadFactor，
+	//需要在并发使用前设置，必须大于 1
+	LoadFactor float64
 }
 
+//DefaultLoadFactor 是 GetBounded 默认的负载倍数
+const DefaultLoadFactor = 1.25
+
 //新建创建一个Map实例,构造函数 New() 允许自定义虚拟节点倍数和 Hash 函数
 func New(replicas int, fn Hash) *Map {
 	m := &Map{
-		replicas: replicas,
-		hash:     fn,
-		hashMap:  make(map[int]string),
+		replicas:   replicas,
+		hash:       fn,
+		hashMap:    make(map[int]string),
+		nodes:      make(map[string]int),
+		LoadFactor: DefaultLoadFactor,
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
@@ -37,25 +52,79 @@ func New(replicas int, fn Hash) *Map {
 	return m
 }
 
-//Add 函数允许传入 0 或 多个真实节点的名称
+//Add 函数允许传入 0 或 多个真实节点的名称，每个节点的权重为 1，已经在环上的节点权重被重置为 1
 func (m *Map) Add(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, key := range keys {
-		//对每一个真实节点 key，对应创建 m.replicas 个虚拟节点
-		for i := 0; i < m.replicas; i++ {
-			//虚拟节点的名称是：strconv.Itoa(i) + key，即通过添加编号的方式区分不同虚拟节点。
-			//使用 m.hash() 计算虚拟节点的哈希值
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
-			//使用 append(m.keys, hash) 添加到环上。
-			m.keys = append(m.keys, hash)
-			//在 hashMap 中增加虚拟节点和真实节点的映射关系
-			m.hashMap[hash] = key
-		}
+		m.nodes[key] = m.replicas
+	}
+	m.rebuild()
+}
+
+//AddWeighted 添加一个权重为 weight 的真实节点，对应 replicas * weight 个虚拟节点，
+//权重越大的节点在环上占的比例越大，适合内存大小不同的节点。weight <= 0 时什么也不做
+func (m *Map) AddWeighted(key string, weight int) {
+	if weight <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[key] = m.replicas * weight
+	m.rebuild()
+}
+
+//rebuild 按 nodes 重新生成哈希环，调用方需持有写锁。
+//虚拟节点的哈希值冲突时需要线性探测，探测的结果取决于先放入的是哪个节点，
+//因此每次都按节点名称的顺序放入，使所有节点上的哈希环相同，不受各自 Add 顺序的影响
+func (m *Map) rebuild() {
+	names := make([]string, 0, len(m.nodes))
+	for name := range m.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m.keys = m.keys[:0]
+	m.hashMap = make(map[int]string, len(m.hashMap))
+	for _, name := range names {
+		m.add(name, m.nodes[name])
 	}
 	//最后一步，环上的哈希值排序
 	sort.Ints(m.keys)
 }
 
+//add 为真实节点 key 创建 replicas 个虚拟节点，调用方需持有写锁并在之后排序
+func (m *Map) add(key string, replicas int) {
+	for i := 0; i < replicas; i++ {
+		//虚拟节点的名称是：strconv.Itoa(i) + key，即通过添加编号的方式区分不同虚拟节点。
+		//使用 m.hash() 计算虚拟节点的哈希值
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		//与已有虚拟节点冲突时线性探测下一个空闲位置，否则会覆盖 hashMap 中其他节点的虚拟节点，使分布倾斜
+		for _, ok := m.hashMap[hash]; ok; _, ok = m.hashMap[hash] {
+			hash = int(uint32(hash + 1))
+		}
+		//使用 append(m.keys, hash) 添加到环上。
+		m.keys = append(m.keys, hash)
+		//在 hashMap 中增加虚拟节点和真实节点的映射关系
+		m.hashMap[hash] = key
+	}
+}
+
+//Remove 从哈希环上删除真实节点 key 的所有虚拟节点，原本属于该节点的 key 顺时针落到下一个节点上。
+//key 不在环上时什么也不做
+func (m *Map) Remove(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.nodes[key]; !ok {
+		return
+	}
+	delete(m.nodes, key)
+	//与 key 冲突而被探测到其他位置的虚拟节点需要回到原来的位置，因此重新生成哈希环
+	m.rebuild()
+}
+
 func (m *Map) Get(key string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if len(m.keys) == 0 {
 		return ""
 	}
@@ -69,3 +138,134 @@ func (m *Map) Get(key string) string {
 	//第三步，通过 hashMap 映射得到真实的节点。
 	return m.hashMap[m.keys[idx%len(m.keys)]]
 }
+
+//GetN 从 key 的哈希值开始顺时针遍历哈希环，返回最多 n 个不同的真实节点，第一个即 Get 返回的节点，
+//可以用于副本读写和失败重试。只有环上的真实节点少于 n 个时才返回少于 n 个节点
+func (m *Map) GetN(key string, n int) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	//多个虚拟节点可能对应同一个真实节点，需要去重；最多绕环一圈
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+//GetBounded 实现有界负载的一致性哈希(consistent hashing with bounded loads)：
+//从 key 的位置顺时针查找，跳过当前负载已达到上限的节点，返回第一个还能承担负载的节点。
+//loads 由调用方提供，记录每个真实节点当前的负载（例如正在处理的请求数），没有记录的节点负载为 0；
+//上限为 ceil(LoadFactor * (总负载 + 1) / 节点数)，因此总能找到一个节点，热点 key 不会把单个节点压垮
+func (m *Map) GetBounded(key string, loads map[string]int64) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.keys) == 0 {
+		return ""
+	}
+	nodes := make(map[string]bool)
+	for _, node := range m.hashMap {
+		nodes[node] = true
+	}
+	var total int64
+	for node := range nodes {
+		total += loads[node]
+	}
+	limit := int64(math.Ceil(m.LoadFactor * float64(total+1) / float64(len(nodes))))
+
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	for i := 0; i < len(m.keys); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if loads[node]+1 <= limit {
+			return node
+		}
+	}
+	//LoadFactor 不大于 1 时可能所有节点都达到上限，退化为普通的一致性哈希
+	return m.hashMap[m.keys[idx%len(m.keys)]]
+}
+
+//Nodes 返回环上所有真实节点的名称，按字典序排列
+func (m *Map) Nodes() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, node := range m.hashMap {
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+//Contains 判断真实节点 key 是否在环上
+func (m *Map) Contains(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, node := range m.hashMap {
+		if node == key {
+			return true
+		}
+	}
+	return false
+}
+
+//IsEmpty 判断环上是否没有任何节点
+func (m *Map) IsEmpty() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.keys) == 0
+}
+
+//Distribution 统计 sampleKeys 中每个真实节点负责的 key 数，用于评估哈希环是否均衡，不修改哈希环
+func (m *Map) Distribution(sampleKeys []string) map[string]int {
+	counts := make(map[string]int)
+	for _, key := range sampleKeys {
+		if node := m.Get(key); node != "" {
+			counts[node]++
+		}
+	}
+	return counts
+}
+
+//KeysAffectedByAdd 返回 sampleKeys 中在添加节点 node（权重为 1）之后会更换负责节点的 key，
+//len(结果)/len(sampleKeys) 即大致需要迁移的 key 比例。在哈希环的副本上计算，不修改哈希环
+func (m *Map) KeysAffectedByAdd(node string, sampleKeys []string) []string {
+	m.mu.RLock()
+	next := &Map{
+		hash:       m.hash,
+		replicas:   m.replicas,
+		hashMap:    make(map[int]string, len(m.hashMap)),
+		nodes:      make(map[string]int, len(m.nodes)+1),
+		LoadFactor: m.LoadFactor,
+	}
+	for n, replicas := range m.nodes {
+		next.nodes[n] = replicas
+	}
+	m.mu.RUnlock()
+	next.Add(node)
+
+	var affected []string
+	for _, key := range sampleKeys {
+		if m.Get(key) != next.Get(key) {
+			affected = append(affected, key)
+		}
+	}
+	return affected
+}
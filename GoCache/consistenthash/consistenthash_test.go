@@ -1,7 +1,10 @@
 package consistenthash
 
 import (
+	"hash/crc32"
+	"reflect"
 	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -41,3 +44,259 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+func TestGetN(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	if nodes := hash.GetN("11", 2); !reflect.DeepEqual(nodes, []string{"2", "4"}) {
+		t.Errorf("Asking for 2 nodes of 11, should have yielded [2 4], got %v", nodes)
+	}
+	if nodes := hash.GetN("27", 5); !reflect.DeepEqual(nodes, []string{"2", "4", "6"}) {
+		t.Errorf("Asking for 5 nodes of 27, should have yielded [2 4 6], got %v", nodes)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	// Removes 4, 14, 24: keys owned by 4 move clockwise to 6, the others stay.
+	hash.Remove("4")
+	testCases := map[string]string{
+		"2":  "2",
+		"3":  "6",
+		"13": "6",
+		"23": "6",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+	if len(hash.keys) != 6 || len(hash.hashMap) != 6 {
+		t.Errorf("expect 6 virtual nodes after removal, got %d keys and %d mappings", len(hash.keys), len(hash.hashMap))
+	}
+
+	// Removing a node that was never added is a no-op.
+	hash.Remove("8")
+	if len(hash.keys) != 6 {
+		t.Errorf("removing unknown node should not change the ring")
+	}
+
+	hash.Remove("6")
+	hash.Remove("2")
+	if hash.Get("1") != "" {
+		t.Errorf("empty ring should yield no node")
+	}
+}
+
+// Run with -race: membership changes must not race with lookups.
+func TestConcurrentAddRemoveGet(t *testing.T) {
+	hash := New(50, nil)
+	hash.Add("node0")
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := strconv.Itoa(i*100000 + j)
+				if hash.Get(key) == "" || len(hash.GetN(key, 2)) == 0 {
+					t.Errorf("lookup of %s found no node while node0 is on the ring", key)
+					return
+				}
+			}
+		}(i)
+	}
+	for i := 1; i <= 100; i++ {
+		node := "node" + strconv.Itoa(i)
+		hash.Add(node)
+		hash.Remove(node)
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestAddWeighted(t *testing.T) {
+	hash := New(100, nil)
+	hash.AddWeighted("small", 1)
+	hash.AddWeighted("medium", 2)
+	hash.AddWeighted("large", 4)
+	hash.AddWeighted("ignored", 0)
+
+	const samples = 100000
+	owned := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		owned[hash.Get("key"+strconv.Itoa(i))]++
+	}
+	for node, weight := range map[string]int{"small": 1, "medium": 2, "large": 4} {
+		expect := float64(weight) / 7
+		if got := float64(owned[node]) / samples; got < expect*0.8 || got > expect*1.2 {
+			t.Errorf("%s should own about %.2f of keys, got %.2f", node, expect, got)
+		}
+	}
+	if owned["ignored"] != 0 {
+		t.Errorf("node with weight 0 should not own keys")
+	}
+}
+
+func TestGetNDistinct(t *testing.T) {
+	hash := New(50, nil)
+	hash.Add("a", "b", "c", "d")
+	hash.AddWeighted("e", 3)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		nodes := hash.GetN(key, 3)
+		if len(nodes) != 3 || nodes[0] != hash.Get(key) {
+			t.Fatalf("GetN(%s, 3) = %v, want 3 nodes starting with %s", key, nodes, hash.Get(key))
+		}
+		seen := make(map[string]bool)
+		for _, node := range nodes {
+			if seen[node] {
+				t.Fatalf("GetN(%s, 3) = %v returned duplicate node %s", key, nodes, node)
+			}
+			seen[node] = true
+		}
+		if nodes := hash.GetN(key, 10); len(nodes) != 5 {
+			t.Fatalf("GetN(%s, 10) should return all 5 nodes, got %v", key, nodes)
+		}
+	}
+	if nodes := New(3, nil).GetN("key", 2); len(nodes) != 0 {
+		t.Fatalf("empty ring should yield no nodes, got %v", nodes)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+	samples := []string{"1", "3", "5", "11", "13", "23", "27"}
+
+	if dist := hash.Distribution(samples); !reflect.DeepEqual(dist, map[string]int{"2": 3, "4": 3, "6": 1}) {
+		t.Errorf("unexpected distribution %v", dist)
+	}
+
+	// Adds 8, 18, 28: only 27 moves from 2 to 8.
+	if keys := hash.KeysAffectedByAdd("8", samples); !reflect.DeepEqual(keys, []string{"27"}) {
+		t.Errorf("expect [27] to move, got %v", keys)
+	}
+	if hash.Get("27") != "2" || len(hash.keys) != 9 {
+		t.Errorf("KeysAffectedByAdd should not modify the ring")
+	}
+}
+
+func TestNodes(t *testing.T) {
+	hash := New(3, nil)
+	if !hash.IsEmpty() || hash.Nodes() != nil || hash.Contains("a") {
+		t.Fatal("new ring should be empty")
+	}
+	hash.Add("c", "a")
+	hash.AddWeighted("b", 2)
+	if hash.IsEmpty() || !reflect.DeepEqual(hash.Nodes(), []string{"a", "b", "c"}) {
+		t.Fatalf("expect nodes [a b c], got %v", hash.Nodes())
+	}
+	hash.Remove("a")
+	if hash.Contains("a") || !hash.Contains("b") {
+		t.Fatal("Contains should reflect removal")
+	}
+}
+
+func TestCollision(t *testing.T) {
+	// Every virtual node hashes to 10, so all but the first must be probed to a free slot.
+	hash := New(3, func(key []byte) uint32 { return 10 })
+	hash.Add("a", "b")
+
+	replicas := make(map[string]int)
+	for _, node := range hash.hashMap {
+		replicas[node]++
+	}
+	if len(hash.keys) != 6 || !reflect.DeepEqual(replicas, map[string]int{"a": 3, "b": 3}) {
+		t.Fatalf("expect 3 virtual nodes each, got %v on %v", replicas, hash.keys)
+	}
+	hash.Remove("a")
+	if !reflect.DeepEqual(hash.Nodes(), []string{"b"}) || len(hash.keys) != 3 {
+		t.Fatalf("expect b to keep its 3 virtual nodes after removing a, got %v", hash.keys)
+	}
+
+	// Probing wraps around the top of the hash space.
+	hash = New(2, func(key []byte) uint32 { return 1<<32 - 1 })
+	hash.Add("a")
+	if !reflect.DeepEqual(hash.keys, []int{0, 1<<32 - 1}) {
+		t.Fatalf("expect probe to wrap to 0, got %v", hash.keys)
+	}
+}
+
+func TestAddOrderIndependent(t *testing.T) {
+	// A tiny hash space makes virtual nodes collide, so placement depends on how collisions are resolved.
+	fn := func(key []byte) uint32 { return crc32.ChecksumIEEE(key) % 32 }
+	first := New(10, fn)
+	first.Add("a", "b", "c")
+	second := New(10, fn)
+	second.Add("c")
+	second.AddWeighted("b", 1)
+	second.Add("a", "d")
+	second.Remove("d")
+
+	if !reflect.DeepEqual(first.keys, second.keys) {
+		t.Fatalf("rings differ: %v vs %v", first.keys, second.keys)
+	}
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		if first.Get(key) != second.Get(key) {
+			t.Fatalf("%s maps to %s and %s depending on Add order", key, first.Get(key), second.Get(key))
+		}
+	}
+}
+
+func TestGetBounded(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	if node := hash.GetBounded("11", nil); node != "2" {
+		t.Errorf("without load, 11 should go to its owner 2, got %s", node)
+	}
+	// Average load with the next request is (8+1)/3 = 3, limit is ceil(1.25*3) = 4: 2 is full.
+	loads := map[string]int64{"2": 8}
+	if node := hash.GetBounded("11", loads); node != "4" {
+		t.Errorf("overloaded 2 should be skipped for 4, got %s", node)
+	}
+	loads["4"] = 8
+	// Limit is ceil(1.25*17/3) = 8: both 2 and 4 are full.
+	if node := hash.GetBounded("11", loads); node != "6" {
+		t.Errorf("overloaded 2 and 4 should be skipped for 6, got %s", node)
+	}
+
+	// Simulate assigning many requests for a single hot key: no node exceeds the bound.
+	loads = make(map[string]int64)
+	for i := 0; i < 300; i++ {
+		loads[hash.GetBounded("11", loads)]++
+	}
+	for node, load := range loads {
+		if load > 125 {
+			t.Errorf("%s got %d of 300 requests, expect at most 125", node, load)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package consistenthash
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+//JumpHash 实现 Google 的跳跃一致性哈希（Lamping & Veach, "A Fast, Minimal Memory, Consistent Hash Algorithm"）：
+//把 key 映射到 [0, numBuckets) 中的一个桶，不占用额外内存，查找复杂度为 O(ln N)，分布均匀。
+//桶数从 N 增加到 N+1 时只有约 1/(N+1) 的 key 移动到新的桶。零值可以直接使用，可以并发使用
+type JumpHash struct {
+	hash Hash
+}
+
+//NewJumpHash 创建 JumpHash，fn 为 nil 时使用 64 位的 FNV-1a
+func NewJumpHash(fn Hash) JumpHash {
+	return JumpHash{hash: fn}
+}
+
+//Get 返回 key 所在的桶，numBuckets <= 0 时返回 -1
+func (j JumpHash) Get(key string, numBuckets int) int {
+	if numBuckets <= 0 {
+		return -1
+	}
+	var k uint64
+	if j.hash != nil {
+		k = uint64(j.hash([]byte(key)))
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		k = h.Sum64()
+	}
+	var b, next int64 = -1, 0
+	for next < int64(numBuckets) {
+		b = next
+		k = k*2862933555777941757 + 1
+		next = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return int(b)
+}
+
+//JumpRing 把 JumpHash 的桶按添加顺序映射到节点，实现 Ring，适合节点编号固定（0..N-1）、只在末尾扩缩容的集群，
+//例如 StatefulSet。不需要虚拟节点，内存占用只有节点列表。节点的编号即 Add 的顺序，所有节点需要以相同的顺序添加，
+//例如 HTTPPool.Set 的参数按 Pod 序号排列。
+//
+//限制：跳跃一致性哈希只能在末尾增删桶。Remove 最后添加的节点时只有该节点的 key 移动；
+//删除中间的节点会让之后的节点编号整体前移，大量 key 会移动到其他节点，需要任意增删节点时使用 Map 或 Rendezvous
+type JumpRing struct {
+	mu    sync.RWMutex
+	jump  JumpHash
+	nodes []string
+}
+
+//NewJumpRing 创建 JumpRing 实例，fn 为 nil 时使用 64 位的 FNV-1a
+func NewJumpRing(fn Hash) *JumpRing {
+	return &JumpRing{jump: NewJumpHash(fn)}
+}
+
+//Add 按顺序把节点追加为新的桶，已经存在的节点被忽略
+func (r *JumpRing) Add(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		if !r.contains(key) {
+			r.nodes = append(r.nodes, key)
+		}
+	}
+}
+
+func (r *JumpRing) contains(key string) bool {
+	for _, node := range r.nodes {
+		if node == key {
+			return true
+		}
+	}
+	return false
+}
+
+//Remove 删除节点，节点不存在时什么也不做，删除的不是最后一个节点时见 JumpRing 的限制
+func (r *JumpRing) Remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, node := range r.nodes {
+		if node == key {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			return
+		}
+	}
+}
+
+//Get 返回 key 所在桶对应的节点，没有节点时返回 ""
+func (r *JumpRing) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	return r.nodes[r.jump.Get(key, len(r.nodes))]
+}
+
+//GetN 返回 key 所在的节点及其之后的节点，最多 n 个，超过末尾后从第一个节点继续
+func (r *JumpRing) GetN(key string, n int) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if n <= 0 || len(r.nodes) == 0 {
+		return nil
+	}
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	b := r.jump.Get(key, len(r.nodes))
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = r.nodes[(b+i)%len(r.nodes)]
+	}
+	return nodes
+}
+
+//Nodes 按桶的编号返回所有节点
+func (r *JumpRing) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.nodes...)
+}
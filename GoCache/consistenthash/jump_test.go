@@ -0,0 +1,72 @@
+package consistenthash
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestJumpHash(t *testing.T) {
+	var j JumpHash
+	if j.Get("key", 0) != -1 {
+		t.Fatal("no bucket should yield -1")
+	}
+	const samples, buckets = 100000, 10
+	counts := make([]int, buckets)
+	for i := 0; i < samples; i++ {
+		key := "key" + strconv.Itoa(i)
+		b := j.Get(key, buckets)
+		counts[b]++
+		//桶数增加时 key 要么不动，要么移动到新增的桶
+		if next := j.Get(key, buckets+1); next != b && next != buckets {
+			t.Fatalf("%s moved from bucket %d to %d", key, b, next)
+		}
+	}
+	for b, n := range counts {
+		if n < samples/buckets*9/10 || n > samples/buckets*11/10 {
+			t.Errorf("bucket %d holds %d of %d keys, expect about a tenth", b, n, samples)
+		}
+	}
+}
+
+func TestJumpRing(t *testing.T) {
+	r := NewJumpRing(nil)
+	if r.Get("key") != "" || r.GetN("key", 2) != nil {
+		t.Fatal("empty JumpRing should yield no node")
+	}
+	r.Add("a", "b", "c", "d", "a")
+	if !reflect.DeepEqual(r.Nodes(), []string{"a", "b", "c", "d"}) {
+		t.Fatalf("nodes = %v", r.Nodes())
+	}
+
+	const samples = 40000
+	owners := make(map[string]string, samples)
+	moved := 0
+	for i := 0; i < samples; i++ {
+		key := "key" + strconv.Itoa(i)
+		owners[key] = r.Get(key)
+		if nodes := r.GetN(key, 3); len(nodes) != 3 || nodes[0] != owners[key] || nodes[1] == nodes[0] {
+			t.Fatalf("GetN(%s, 3) = %v should start with %s", key, nodes, owners[key])
+		}
+	}
+	r.Add("e")
+	for key, owner := range owners {
+		if got := r.Get(key); got != owner {
+			if got != "e" {
+				t.Fatalf("%s moved from %s to %s after adding e", key, owner, got)
+			}
+			moved++
+		}
+	}
+	if moved < samples/5*9/10 || moved > samples/5*11/10 {
+		t.Errorf("%d of %d keys moved to e, expect about a fifth", moved, samples)
+	}
+
+	//删除最后的节点，key 回到原来的节点
+	r.Remove("e")
+	for key, owner := range owners {
+		if got := r.Get(key); got != owner {
+			t.Fatalf("%s should move back to %s after removing e, got %s", key, owner, got)
+		}
+	}
+}
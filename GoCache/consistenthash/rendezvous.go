@@ -0,0 +1,132 @@
+package consistenthash
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+//Ring 是根据 key 选择节点的算法需要提供的方法，Map（一致性哈希）、Rendezvous（最高随机权重哈希）和 JumpRing（跳跃一致性哈希）都实现了该接口，
+//PeerPicker 的实现可以据此选择使用哪一种。实现需要可以并发使用
+type Ring interface {
+	//添加 0 或多个真实节点
+	Add(keys ...string)
+	//删除真实节点，节点不存在时什么也不做
+	Remove(key string)
+	//返回负责 key 的节点，没有节点时返回 ""
+	Get(key string) string
+	//返回负责 key 的最多 n 个不同节点，第一个即 Get 返回的节点
+	GetN(key string, n int) []string
+}
+
+var (
+	_ Ring = (*Map)(nil)
+	_ Ring = (*Rendezvous)(nil)
+	_ Ring = (*JumpRing)(nil)
+)
+
+//Rendezvous 实现最高随机权重(Highest Random Weight)哈希：对每个节点计算 hash(node + key)，得分最高的节点负责该 key。
+//不需要虚拟节点，也不需要排序，节点增删时只有原本属于该节点的 key 会移动，分布天然均匀，不存在虚拟节点冲突；
+//代价是每次查找需要遍历所有节点，复杂度为 O(N)，适合节点数不多的集群。可以并发使用
+type Rendezvous struct {
+	mu    sync.RWMutex
+	hash  Hash
+	nodes []string
+}
+
+//NewRendezvous 创建 Rendezvous 实例，fn 为 nil 时使用 64 位的 FNV-1a 并打散结果
+func NewRendezvous(fn Hash) *Rendezvous {
+	return &Rendezvous{hash: fn}
+}
+
+//score 计算节点 node 对 key 的权重
+func (r *Rendezvous) score(node, key string) uint64 {
+	if r.hash != nil {
+		return uint64(r.hash([]byte(node + key)))
+	}
+	h := fnv.New64a()
+	h.Write([]byte(node))
+	h.Write([]byte(key))
+	//FNV 的低位雪崩效果不好，用 murmur3 的 fmix64 打散，避免节点名相近时得分相关
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+//Add 添加 0 或多个真实节点，已经存在的节点被忽略
+func (r *Rendezvous) Add(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		if !r.contains(key) {
+			r.nodes = append(r.nodes, key)
+		}
+	}
+}
+
+func (r *Rendezvous) contains(key string) bool {
+	for _, node := range r.nodes {
+		if node == key {
+			return true
+		}
+	}
+	return false
+}
+
+//Remove 删除真实节点，节点不存在时什么也不做
+func (r *Rendezvous) Remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, node := range r.nodes {
+		if node == key {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			return
+		}
+	}
+}
+
+//Get 返回得分最高的节点，得分相同时取名称较小的节点，保证结果与添加顺序无关
+func (r *Rendezvous) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var best string
+	var bestScore uint64
+	for _, node := range r.nodes {
+		s := r.score(node, key)
+		if best == "" || s > bestScore || (s == bestScore && node < best) {
+			best, bestScore = node, s
+		}
+	}
+	return best
+}
+
+//GetN 按得分从高到低返回最多 n 个节点
+func (r *Rendezvous) GetN(key string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	r.mu.RLock()
+	nodes := append([]string(nil), r.nodes...)
+	r.mu.RUnlock()
+	if len(nodes) == 0 {
+		return nil
+	}
+	scores := make(map[string]uint64, len(nodes))
+	for _, node := range nodes {
+		scores[node] = r.score(node, key)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if scores[nodes[i]] != scores[nodes[j]] {
+			return scores[nodes[i]] > scores[nodes[j]]
+		}
+		return nodes[i] < nodes[j]
+	})
+	if len(nodes) > n {
+		nodes = nodes[:n]
+	}
+	return nodes
+}
@@ -0,0 +1,40 @@
+package consistenthash
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRendezvous(t *testing.T) {
+	r := NewRendezvous(nil)
+	if r.Get("key") != "" || r.GetN("key", 2) != nil {
+		t.Fatal("empty Rendezvous should yield no node")
+	}
+	r.Add("a", "b", "c", "d", "a")
+
+	const samples = 40000
+	owners := make(map[string]string, samples)
+	counts := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		key := "key" + strconv.Itoa(i)
+		owner := r.Get(key)
+		owners[key] = owner
+		counts[owner]++
+		if nodes := r.GetN(key, 3); len(nodes) != 3 || nodes[0] != owner {
+			t.Fatalf("GetN(%s, 3) = %v should start with %s", key, nodes, owner)
+		}
+	}
+	for node, n := range counts {
+		if n < samples/4*9/10 || n > samples/4*11/10 {
+			t.Errorf("%s owns %d of %d keys, expect about a quarter", node, n, samples)
+		}
+	}
+
+	// Removing a node only moves the keys that node owned.
+	r.Remove("b")
+	for key, owner := range owners {
+		if got := r.Get(key); owner != "b" && got != owner {
+			t.Fatalf("%s moved from %s to %s after removing b", key, owner, got)
+		}
+	}
+}
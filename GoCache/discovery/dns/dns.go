@@ -0,0 +1,171 @@
+//Package dns 通过定期解析 DNS SRV 记录发现节点，适合没有 etcd 的环境，例如 Kubernetes 的 headless service：
+//
+//	pool := GoCache.NewHTTPPool("http://gocache-0.gocache.default.svc.cluster.local:8001")
+//	d := dns.New("_http._tcp.gocache.default.svc.cluster.local", pool)
+//	d.Start()
+//	defer d.Stop()
+//
+//每条 SRV 记录对应一个节点，地址为 scheme + target + ":" + port，target 末尾的 "." 会被去掉，
+//本节点的 self 需要使用相同的写法
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultInterval 是默认的解析间隔
+	DefaultInterval = 30 * time.Second
+	//DefaultScheme 是节点地址的默认前缀，与 HTTPPool 的节点地址一致
+	DefaultScheme = "http://"
+)
+
+//PeerUpdater 是 Discovery 更新节点的目标，GoCache.HTTPPool 实现了该接口
+type PeerUpdater interface {
+	AddPeer(addr string)
+	RemovePeer(addr string)
+}
+
+//Discovery 定期解析 SRV 记录，把结果与当前的节点集合比较后调用 AddPeer 和 RemovePeer
+type Discovery struct {
+	name     string
+	interval time.Duration
+	scheme   string
+	pool     PeerUpdater
+	//lookup 解析 SRV 记录，默认使用 net.DefaultResolver
+	lookup func(ctx context.Context, name string) ([]*net.SRV, error)
+
+	mu    sync.Mutex
+	peers map[string]bool //最近一次解析成功时的节点集合
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+//Option 用于在创建 Discovery 时修改可选配置
+type Option func(d *Discovery)
+
+//WithInterval 设置解析间隔，默认为 DefaultInterval
+func WithInterval(interval time.Duration) Option {
+	return func(d *Discovery) {
+		d.interval = interval
+	}
+}
+
+//WithScheme 设置节点地址的前缀，默认为 DefaultScheme，开启 TLS 时使用 "https://"
+func WithScheme(scheme string) Option {
+	return func(d *Discovery) {
+		d.scheme = scheme
+	}
+}
+
+//WithResolver 设置解析使用的 net.Resolver，例如指定 DNS 服务器
+func WithResolver(r *net.Resolver) Option {
+	return func(d *Discovery) {
+		d.lookup = func(ctx context.Context, name string) ([]*net.SRV, error) {
+			_, srvs, err := r.LookupSRV(ctx, "", "", name)
+			return srvs, err
+		}
+	}
+}
+
+//New 创建 Discovery，name 是完整的 SRV 名称，例如 _http._tcp.gocache.default.svc.cluster.local
+func New(name string, pool PeerUpdater, opts ...Option) *Discovery {
+	d := &Discovery{
+		name:     name,
+		interval: DefaultInterval,
+		scheme:   DefaultScheme,
+		pool:     pool,
+		peers:    make(map[string]bool),
+	}
+	WithResolver(net.DefaultResolver)(d)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+//Start 立即解析一次，然后启动后台协程每隔 interval 解析一次，直到调用 Stop。
+//返回第一次解析的错误，即使失败后台协程也会启动，例如节点就绪前 headless service 中还没有记录
+func (d *Discovery) Start() error {
+	err := d.Refresh(context.Background())
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.run()
+	return err
+}
+
+//Stop 停止后台解析，已经加入的节点保持不变
+func (d *Discovery) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+}
+
+func (d *Discovery) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), d.interval)
+			if err := d.Refresh(ctx); err != nil {
+				log.Printf("[GoCache] resolve %s: %v, keeping %d known peers", d.name, err, d.known())
+			}
+			cancel()
+		}
+	}
+}
+
+func (d *Discovery) known() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.peers)
+}
+
+//errNoRecords 表示解析成功但没有任何记录，与解析失败相同处理，避免 DNS 短暂异常时清空所有节点
+var errNoRecords = errors.New("no SRV records")
+
+//Refresh 解析一次 SRV 记录并更新节点。解析失败或没有任何记录时保留上一次成功的结果，返回错误
+func (d *Discovery) Refresh(ctx context.Context) error {
+	srvs, err := d.lookup(ctx, d.name)
+	if err == nil && len(srvs) == 0 {
+		err = errNoRecords
+	}
+	if err != nil {
+		return fmt.Errorf("lookup SRV %s: %v", d.name, err)
+	}
+	peers := make(map[string]bool, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		peers[d.scheme+net.JoinHostPort(host, strconv.Itoa(int(srv.Port)))] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for peer := range d.peers {
+		if !peers[peer] {
+			d.pool.RemovePeer(peer)
+		}
+	}
+	for peer := range peers {
+		if !d.peers[peer] {
+			d.pool.AddPeer(peer)
+		}
+	}
+	d.peers = peers
+	return nil
+}
@@ -0,0 +1,109 @@
+package dns
+
+import (
+	"GoCache"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakePool struct {
+	mu    sync.Mutex
+	peers map[string]bool
+	calls int
+}
+
+func (p *fakePool) AddPeer(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.peers[addr] = true
+	p.calls++
+}
+
+func (p *fakePool) RemovePeer(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.peers, addr)
+	p.calls++
+}
+
+func (p *fakePool) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var peers []string
+	for peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+var _ PeerUpdater = (*GoCache.HTTPPool)(nil)
+
+func TestRefresh(t *testing.T) {
+	var records []*net.SRV
+	var lookupErr error
+	pool := &fakePool{peers: make(map[string]bool)}
+	d := New("_http._tcp.gocache", pool)
+	d.lookup = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		return records, lookupErr
+	}
+
+	records = []*net.SRV{{Target: "a.gocache.", Port: 8001}, {Target: "b.gocache.", Port: 8001}}
+	if err := d.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"http://a.gocache:8001", "http://b.gocache:8001"}; !reflect.DeepEqual(pool.list(), want) {
+		t.Fatalf("peers = %v, want %v", pool.list(), want)
+	}
+
+	//只处理变化的节点
+	pool.calls = 0
+	records = []*net.SRV{{Target: "b.gocache.", Port: 8001}, {Target: "c.gocache.", Port: 8001}}
+	d.Refresh(context.Background())
+	if want := []string{"http://b.gocache:8001", "http://c.gocache:8001"}; !reflect.DeepEqual(pool.list(), want) || pool.calls != 2 {
+		t.Fatalf("peers = %v after %d calls, want %v after 2", pool.list(), pool.calls, want)
+	}
+
+	//解析失败或没有记录时保留上一次的结果
+	known := pool.list()
+	lookupErr = errors.New("timeout")
+	if err := d.Refresh(context.Background()); err == nil || !reflect.DeepEqual(pool.list(), known) {
+		t.Fatalf("failed lookup should keep peers, got %v, %v", pool.list(), err)
+	}
+	lookupErr, records = nil, nil
+	if err := d.Refresh(context.Background()); err == nil || !reflect.DeepEqual(pool.list(), known) {
+		t.Fatalf("empty answer should keep peers, got %v, %v", pool.list(), err)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	pool := &fakePool{peers: make(map[string]bool)}
+	d := New("_http._tcp.gocache", pool, WithInterval(5*time.Millisecond), WithScheme("https://"))
+	var mu sync.Mutex
+	target := "a.gocache."
+	d.lookup = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []*net.SRV{{Target: target, Port: 443}}, nil
+	}
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+	mu.Lock()
+	target = "b.gocache."
+	mu.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for !reflect.DeepEqual(pool.list(), []string{"https://b.gocache:443"}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("peers = %v, want the refreshed record", pool.list())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,248 @@
+//Package etcd 使用 etcd 实现节点的服务发现：
+//本节点以租约的形式注册在 prefix 下，并监听 prefix 下所有节点的加入和离开，节点列表变化时调用 onChange，例如：
+//
+//	cli, _ := clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:2379"}})
+//	pool := GoCache.NewHTTPPool(self)
+//	d := etcd.New(cli, self, func(peers []string) { pool.Set(peers...) })
+//	if err := d.Start(); err != nil { ... }
+//	defer d.Close()
+//
+//节点崩溃后不再续约，租约在 TTL 后过期，对应的 key 被 etcd 删除，其他节点随之把它移出哈希环
+package etcd
+
+import (
+	"context"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultPrefix 是节点注册的默认前缀，每个节点的 key 为 prefix + 节点地址，value 为节点地址
+	DefaultPrefix = "/gocache/peers/"
+	//DefaultTTL 是租约的默认有效期，单位为秒，节点崩溃后最多经过 TTL 被移除
+	DefaultTTL = 10
+	//retryInterval 是重新注册或重新同步失败后的等待时间
+	retryInterval = time.Second
+)
+
+//Discovery 负责注册本节点并监听其他节点
+type Discovery struct {
+	cli      *clientv3.Client
+	self     string
+	prefix   string
+	ttl      int64
+	onChange func(peers []string)
+
+	mu     sync.Mutex
+	peers  map[string]string //etcd key -> 节点地址
+	last   []string          //上一次传给 onChange 的节点列表
+	lease  clientv3.LeaseID
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+//Option 用于在创建 Discovery 时修改可选配置
+type Option func(d *Discovery)
+
+//WithPrefix 设置节点注册的前缀，默认为 DefaultPrefix，同一个集群的节点需要使用相同的前缀
+func WithPrefix(prefix string) Option {
+	return func(d *Discovery) {
+		d.prefix = prefix
+	}
+}
+
+//WithTTL 设置租约的有效期，单位为秒，默认为 DefaultTTL
+func WithTTL(seconds int64) Option {
+	return func(d *Discovery) {
+		d.ttl = seconds
+	}
+}
+
+//New 创建 Discovery，self 是本节点的地址，需要与 HTTPPool 的 self 相同。
+//onChange 在节点列表变化时被调用，参数为按地址排序的所有节点（包括本节点），调用是串行的
+func New(cli *clientv3.Client, self string, onChange func(peers []string), opts ...Option) *Discovery {
+	d := &Discovery{
+		cli:      cli,
+		self:     self,
+		prefix:   DefaultPrefix,
+		ttl:      DefaultTTL,
+		onChange: onChange,
+		peers:    make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+//Start 注册本节点并开始监听，返回前已经用当前的节点列表调用过一次 onChange
+func (d *Discovery) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	keepalive, err := d.register(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	rev, err := d.sync(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	go d.run(ctx, keepalive, rev)
+	return nil
+}
+
+//Close 停止监听并撤销租约，本节点立即从其他节点的列表中移除
+func (d *Discovery) Close() error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+	<-d.done
+	d.cancel = nil
+	ctx, cancel := context.WithTimeout(context.Background(), retryInterval)
+	defer cancel()
+	_, err := d.cli.Revoke(ctx, d.lease)
+	return err
+}
+
+//register 创建租约并写入本节点的 key，返回续约的响应
+func (d *Discovery) register(ctx context.Context) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	lease, err := d.cli.Grant(ctx, d.ttl)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = d.cli.Put(ctx, d.prefix+d.self, d.self, clientv3.WithLease(lease.ID)); err != nil {
+		return nil, err
+	}
+	keepalive, err := d.cli.KeepAlive(ctx, lease.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.lease = lease.ID
+	return keepalive, nil
+}
+
+//sync 读取 prefix 下的所有节点，返回读取时的版本号
+func (d *Discovery) sync(ctx context.Context) (int64, error) {
+	res, err := d.cli.Get(ctx, d.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	peers := make(map[string]string, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		peers[string(kv.Key)] = string(kv.Value)
+	}
+	d.mu.Lock()
+	d.peers = peers
+	d.mu.Unlock()
+	d.notify()
+	return res.Header.Revision, nil
+}
+
+//run 处理续约和 watch 事件，直到 ctx 结束
+func (d *Discovery) run(ctx context.Context, keepalive <-chan *clientv3.LeaseKeepAliveResponse, rev int64) {
+	defer close(d.done)
+	watch := d.cli.Watch(ctx, d.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-keepalive:
+			if ok {
+				continue
+			}
+			//续约中断（例如与 etcd 断开超过 TTL），租约可能已经过期，重新注册
+			log.Printf("[GoCache] etcd lease for %s lost, registering again", d.self)
+			for {
+				var err error
+				if keepalive, err = d.register(ctx); err == nil {
+					break
+				}
+				if !sleep(ctx, retryInterval) {
+					return
+				}
+			}
+		case res, ok := <-watch:
+			if ok && res.Err() == nil {
+				d.apply(res.Events)
+				continue
+			}
+			//watch 被关闭或版本已经被压缩，重新全量读取后从新的版本开始监听
+			for {
+				rev, err := d.sync(ctx)
+				if err == nil {
+					watch = d.cli.Watch(ctx, d.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+					break
+				}
+				if !sleep(ctx, retryInterval) {
+					return
+				}
+			}
+		}
+	}
+}
+
+//apply 把 watch 事件应用到节点列表上
+func (d *Discovery) apply(events []*clientv3.Event) {
+	d.mu.Lock()
+	for _, ev := range events {
+		key := string(ev.Kv.Key)
+		if ev.Type == clientv3.EventTypePut {
+			d.peers[key] = string(ev.Kv.Value)
+		} else {
+			delete(d.peers, key)
+		}
+	}
+	d.mu.Unlock()
+	d.notify()
+}
+
+//notify 在节点列表与上一次不同时调用 onChange
+func (d *Discovery) notify() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	seen := make(map[string]bool, len(d.peers))
+	peers := make([]string, 0, len(d.peers))
+	for _, addr := range d.peers {
+		if !seen[addr] {
+			seen[addr] = true
+			peers = append(peers, addr)
+		}
+	}
+	sort.Strings(peers)
+	if d.last != nil && equal(peers, d.last) {
+		return
+	}
+	d.last = peers
+	d.onChange(peers)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//sleep 等待 d，ctx 先结束时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
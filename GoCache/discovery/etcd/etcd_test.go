@@ -0,0 +1,69 @@
+package etcd
+
+import (
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func event(typ mvccpb.Event_EventType, addr string) *clientv3.Event {
+	return &clientv3.Event{Type: typ, Kv: &mvccpb.KeyValue{Key: []byte(DefaultPrefix + addr), Value: []byte(addr)}}
+}
+
+func TestApply(t *testing.T) {
+	var calls [][]string
+	d := New(nil, "a", func(peers []string) { calls = append(calls, peers) })
+	d.apply([]*clientv3.Event{event(mvccpb.PUT, "b"), event(mvccpb.PUT, "a")})
+	//重复的 PUT（例如续约后重新写入）不会触发 onChange
+	d.apply([]*clientv3.Event{event(mvccpb.PUT, "a")})
+	d.apply([]*clientv3.Event{event(mvccpb.DELETE, "b")})
+	want := [][]string{{"a", "b"}, {"a"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("onChange calls = %v, want %v", calls, want)
+	}
+}
+
+//TestDiscovery 需要一个可用的 etcd，通过 GOCACHE_ETCD_ENDPOINTS（逗号分隔）指定，没有设置时跳过
+func TestDiscovery(t *testing.T) {
+	endpoints := os.Getenv("GOCACHE_ETCD_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("GOCACHE_ETCD_ENDPOINTS not set")
+	}
+	cli, err := clientv3.New(clientv3.Config{Endpoints: strings.Split(endpoints, ","), DialTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	prefix := "/gocache-test/" + time.Now().Format("150405.000000") + "/"
+
+	changes := make(chan []string, 10)
+	a := New(cli, "a", func(peers []string) { changes <- peers }, WithPrefix(prefix), WithTTL(2))
+	if err := a.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	expect := func(want ...string) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("peers = %v, want %v", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for peers %v", want)
+		}
+	}
+	expect("a")
+
+	b := New(cli, "b", func([]string) {}, WithPrefix(prefix), WithTTL(2))
+	if err := b.Start(); err != nil {
+		t.Fatal(err)
+	}
+	expect("a", "b")
+	b.Close()
+	expect("a")
+}
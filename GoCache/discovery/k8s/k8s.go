@@ -0,0 +1,322 @@
+//Package k8s 通过监听 headless service 的 EndpointSlice 发现节点，Pod 就绪或离开时调用 AddPeer 和 RemovePeer：
+//
+//	pool := GoCache.NewHTTPPool("http://" + os.Getenv("POD_IP") + ":8001")
+//	d := k8s.New("gocache", pool)
+//	if err := d.Start(); err != nil { ... }
+//	defer d.Stop()
+//
+//默认使用 Pod 内的 ServiceAccount 访问 API Server（rest.InClusterConfig），需要有读取所在 namespace 中
+//endpointslices 的 list 和 watch 权限。只有 ready 的地址会成为节点，地址为 scheme + Pod IP + ":" + 端口，
+//本节点的 self 需要使用相同的写法
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listers "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultScheme 是节点地址的默认前缀，与 HTTPPool 的节点地址一致
+	DefaultScheme = "http://"
+	//DefaultDebounce 是成员变化后等待的时间，这段时间内的多次变化合并为一次更新
+	DefaultDebounce = time.Second
+	//DefaultResync 是 informer 重新同步的间隔，重新同步时按 API Server 的全量结果校正节点
+	DefaultResync = 5 * time.Minute
+	//DefaultSyncTimeout 是 Start 等待第一次同步的最长时间
+	DefaultSyncTimeout = 30 * time.Second
+	//namespaceFile 是 Pod 内 ServiceAccount 所在 namespace 的文件
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+//PeerUpdater 是 Discovery 更新节点的目标，GoCache.HTTPPool 实现了该接口
+type PeerUpdater interface {
+	AddPeer(addr string)
+	RemovePeer(addr string)
+}
+
+//Discovery 监听 service 的 EndpointSlice，把 ready 的地址与当前的节点集合比较后调用 AddPeer 和 RemovePeer
+type Discovery struct {
+	service     string
+	namespace   string
+	port        string //端口名，为空表示使用每个 EndpointSlice 的第一个端口
+	scheme      string
+	debounce    time.Duration
+	resync      time.Duration
+	syncTimeout time.Duration
+	client      kubernetes.Interface
+	pool        PeerUpdater
+
+	mu      sync.Mutex
+	peers   map[string]bool //已经加入 pool 的节点
+	lister  listers.EndpointSliceLister
+	changed chan struct{} //informer 收到事件后通知 run，容量为 1，多次通知合并
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+//Option 用于在创建 Discovery 时修改可选配置
+type Option func(d *Discovery)
+
+//WithNamespace 设置 service 所在的 namespace，默认为 Pod 所在的 namespace，读取失败时为 default
+func WithNamespace(namespace string) Option {
+	return func(d *Discovery) {
+		d.namespace = namespace
+	}
+}
+
+//WithClient 设置访问 API Server 的客户端，默认使用 rest.InClusterConfig 创建，例如在集群外使用 kubeconfig
+func WithClient(client kubernetes.Interface) Option {
+	return func(d *Discovery) {
+		d.client = client
+	}
+}
+
+//WithPortName 设置节点使用的端口名，与 service 中 ports 的 name 对应，默认使用第一个端口
+func WithPortName(name string) Option {
+	return func(d *Discovery) {
+		d.port = name
+	}
+}
+
+//WithScheme 设置节点地址的前缀，默认为 DefaultScheme，开启 TLS 时使用 "https://"
+func WithScheme(scheme string) Option {
+	return func(d *Discovery) {
+		d.scheme = scheme
+	}
+}
+
+//WithDebounce 设置成员变化后等待的时间，默认为 DefaultDebounce。滚动更新或 Pod 反复就绪、未就绪时，
+//等待期间的变化合并为一次更新，避免哈希环频繁重建；为 0 表示每次变化立即更新
+func WithDebounce(d time.Duration) Option {
+	return func(disc *Discovery) {
+		disc.debounce = d
+	}
+}
+
+//WithResync 设置 informer 重新同步的间隔，默认为 DefaultResync，为 0 表示不重新同步
+func WithResync(interval time.Duration) Option {
+	return func(d *Discovery) {
+		d.resync = interval
+	}
+}
+
+//New 创建 Discovery，service 是 headless service 的名称
+func New(service string, pool PeerUpdater, opts ...Option) *Discovery {
+	d := &Discovery{
+		service:     service,
+		scheme:      DefaultScheme,
+		debounce:    DefaultDebounce,
+		resync:      DefaultResync,
+		syncTimeout: DefaultSyncTimeout,
+		pool:        pool,
+		peers:       make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.namespace == "" {
+		d.namespace = podNamespace()
+	}
+	return d
+}
+
+//podNamespace 返回 Pod 所在的 namespace，不在 Pod 中运行时返回 default
+func podNamespace() string {
+	if b, err := os.ReadFile(namespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(b)); ns != "" {
+			return ns
+		}
+	}
+	return metav1.NamespaceDefault
+}
+
+//Start 启动 informer 并等待第一次同步完成，立即按同步的结果更新节点，之后的变化经过 debounce 后更新，直到调用 Stop。
+//没有设置 WithClient 时使用 rest.InClusterConfig 创建客户端，失败时返回错误
+func (d *Discovery) Start() error {
+	if d.client == nil {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return fmt.Errorf("k8s: in-cluster config: %v", err)
+		}
+		if d.client, err = kubernetes.NewForConfig(config); err != nil {
+			return fmt.Errorf("k8s: create client: %v", err)
+		}
+	}
+	//只监听属于 service 的 EndpointSlice
+	factory := informers.NewSharedInformerFactoryWithOptions(d.client, d.resync,
+		informers.WithNamespace(d.namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = discoveryv1.LabelServiceName + "=" + d.service
+		}))
+	informer := factory.Discovery().V1().EndpointSlices()
+	d.lister = informer.Lister()
+	d.changed = make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case d.changed <- struct{}{}:
+		default:
+		}
+	}
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { notify() },
+		UpdateFunc: func(interface{}, interface{}) { notify() },
+		DeleteFunc: func(interface{}) { notify() },
+	})
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	factory.Start(d.stop)
+
+	//同步超时或者返回后关闭 syncStop，等待的协程随之退出
+	stop, syncStop, syncDone := d.stop, make(chan struct{}), make(chan struct{})
+	go func() {
+		timeout := time.NewTimer(d.syncTimeout)
+		defer timeout.Stop()
+		select {
+		case <-timeout.C:
+		case <-stop:
+		case <-syncDone:
+		}
+		close(syncStop)
+	}()
+	synced := cache.WaitForCacheSync(syncStop, informer.Informer().HasSynced)
+	close(syncDone)
+	//第一次同步产生的事件已经包含在 Refresh 中
+	select {
+	case <-d.changed:
+	default:
+	}
+	err := errors.New("k8s: timed out waiting for endpointslices to sync")
+	if synced {
+		err = d.Refresh()
+	}
+	go d.run()
+	return err
+}
+
+//Stop 停止 informer 和后台更新，已经加入的节点保持不变
+func (d *Discovery) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+}
+
+func (d *Discovery) run() {
+	defer close(d.done)
+	var (
+		timer *time.Timer
+		fire  <-chan time.Time
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-d.changed:
+			//每次变化都重新计时，成员稳定 debounce 之后才更新
+			if timer == nil {
+				timer = time.NewTimer(d.debounce)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(d.debounce)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			if err := d.Refresh(); err != nil {
+				log.Printf("[GoCache] discover %s/%s: %v, keeping %d known peers", d.namespace, d.service, err, d.known())
+			}
+		}
+	}
+}
+
+func (d *Discovery) known() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.peers)
+}
+
+//errNoEndpoints 表示没有任何 ready 的地址，保留现有的节点，避免所有 Pod 短暂未就绪时清空哈希环
+var errNoEndpoints = errors.New("no ready endpoints")
+
+//Refresh 按 informer 缓存中的 EndpointSlice 更新节点，只调用变化的节点。
+//没有任何 ready 的地址时保留现有的节点，返回错误
+func (d *Discovery) Refresh() error {
+	slices, err := d.lister.EndpointSlices(d.namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	peers := make(map[string]bool)
+	for _, slice := range slices {
+		port, ok := d.slicePort(slice)
+		if !ok {
+			continue
+		}
+		for _, ep := range slice.Endpoints {
+			//Ready 为 nil 时按 ready 处理
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			for _, addr := range ep.Addresses {
+				peers[d.scheme+net.JoinHostPort(addr, strconv.Itoa(int(port)))] = true
+			}
+		}
+	}
+	if len(peers) == 0 {
+		return errNoEndpoints
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for peer := range d.peers {
+		if !peers[peer] {
+			d.pool.RemovePeer(peer)
+		}
+	}
+	for peer := range peers {
+		if !d.peers[peer] {
+			d.pool.AddPeer(peer)
+		}
+	}
+	d.peers = peers
+	return nil
+}
+
+//slicePort 返回 EndpointSlice 中节点使用的端口
+func (d *Discovery) slicePort(slice *discoveryv1.EndpointSlice) (int32, bool) {
+	for _, p := range slice.Ports {
+		if p.Port == nil {
+			continue
+		}
+		if d.port == "" || (p.Name != nil && *p.Name == d.port) {
+			return *p.Port, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"GoCache"
+	"context"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakePool struct {
+	mu    sync.Mutex
+	peers map[string]bool
+	calls int
+}
+
+func (p *fakePool) AddPeer(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.peers[addr] = true
+	p.calls++
+}
+
+func (p *fakePool) RemovePeer(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.peers, addr)
+	p.calls++
+}
+
+func (p *fakePool) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var peers []string
+	for peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+func (p *fakePool) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+var _ PeerUpdater = (*GoCache.HTTPPool)(nil)
+
+func endpointSlice(name, service string, ready map[string]bool) *discoveryv1.EndpointSlice {
+	portName, port := "http", int32(8001)
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "cache",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+	}
+	for addr, ok := range ready {
+		ok := ok
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ok},
+		})
+	}
+	return slice
+}
+
+func waitPeers(t *testing.T, pool *fakePool, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !reflect.DeepEqual(pool.list(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("peers = %v, want %v", pool.list(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		endpointSlice("gocache-a", "gocache", map[string]bool{"10.0.0.1": true, "10.0.0.2": false}),
+		endpointSlice("gocache-b", "gocache", map[string]bool{"10.0.0.3": true}),
+		endpointSlice("other", "other", map[string]bool{"10.0.0.9": true}),
+	)
+	pool := &fakePool{peers: make(map[string]bool)}
+	d := New("gocache", pool, WithClient(client), WithNamespace("cache"), WithPortName("http"), WithDebounce(50*time.Millisecond))
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+	//Start 返回时已经按第一次同步的结果更新，只包含 ready 的地址和属于 service 的 EndpointSlice
+	if want := []string{"http://10.0.0.1:8001", "http://10.0.0.3:8001"}; !reflect.DeepEqual(pool.list(), want) {
+		t.Fatalf("peers = %v, want %v", pool.list(), want)
+	}
+
+	//debounce 期间的多次变化合并为一次更新
+	slices := client.DiscoveryV1().EndpointSlices("cache")
+	ctx := context.Background()
+	calls := pool.callCount()
+	slices.Update(ctx, endpointSlice("gocache-a", "gocache", map[string]bool{"10.0.0.1": false, "10.0.0.2": false}), metav1.UpdateOptions{})
+	slices.Update(ctx, endpointSlice("gocache-a", "gocache", map[string]bool{"10.0.0.1": true, "10.0.0.2": true}), metav1.UpdateOptions{})
+	waitPeers(t, pool, []string{"http://10.0.0.1:8001", "http://10.0.0.2:8001", "http://10.0.0.3:8001"})
+	if n := pool.callCount() - calls; n != 1 {
+		t.Fatalf("expect flapping changes to be merged into 1 call, got %d", n)
+	}
+
+	slices.Delete(ctx, "gocache-b", metav1.DeleteOptions{})
+	waitPeers(t, pool, []string{"http://10.0.0.1:8001", "http://10.0.0.2:8001"})
+
+	//没有任何 ready 的地址时保留现有的节点
+	slices.Update(ctx, endpointSlice("gocache-a", "gocache", map[string]bool{"10.0.0.1": false}), metav1.UpdateOptions{})
+	time.Sleep(150 * time.Millisecond)
+	if want := []string{"http://10.0.0.1:8001", "http://10.0.0.2:8001"}; !reflect.DeepEqual(pool.list(), want) {
+		t.Fatalf("peers = %v, want %v to be kept", pool.list(), want)
+	}
+}
+
+func TestStopWithoutStart(t *testing.T) {
+	d := New("gocache", &fakePool{peers: make(map[string]bool)}, WithNamespace("cache"))
+	d.Stop()
+	if err := d.Start(); err == nil {
+		d.Stop()
+		t.Fatal("Start outside a cluster should fail without WithClient")
+	}
+}
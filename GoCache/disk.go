@@ -0,0 +1,360 @@
+package GoCache
+
+import (
+	"GoCache/LRU_Cache"
+	"container/list"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+//tieredStore 在内存中的淘汰策略之下加了一层磁盘：内存写满时被淘汰的记录写入磁盘，而不是直接丢弃，
+//Get 在内存中未命中时再查找磁盘，命中后把记录移回内存。磁盘层有独立的容量，写满时按 LRU 淘汰。
+//对 shard 来说 tieredStore 与其他 evictor 没有区别，只有离开磁盘层（或被删除、过期）的记录才会触发回调。
+//Bytes 只统计内存中的记录，Len 和 Range 包括磁盘中的记录
+type tieredStore struct {
+	mem       evictor
+	disk      *diskTier
+	onEvicted func(key string, value LRU_Cache.Value)
+	//spilling 为 true 时 mem 因容量淘汰的记录写入磁盘，否则（删除、过期）直接通知 onEvicted
+	spilling bool
+}
+
+//newTieredStore 使用 newMem 创建内存中的 evictor，onEvicted 为 shard 的回调
+func newTieredStore(newMem func(onEvicted func(string, LRU_Cache.Value)) evictor, disk *diskTier, onEvicted func(string, LRU_Cache.Value)) *tieredStore {
+	t := &tieredStore{disk: disk, onEvicted: onEvicted}
+	t.mem = newMem(t.memEvicted)
+	disk.onEvicted = func(key string, value ByteView) {
+		onEvicted(key, value)
+	}
+	return t
+}
+
+func (t *tieredStore) memEvicted(key string, value LRU_Cache.Value) {
+	if t.spilling {
+		t.disk.put(key, value.(ByteView))
+		return
+	}
+	t.onEvicted(key, value)
+}
+
+//addMem 写入内存，写入过程中被挤出的记录进入磁盘
+func (t *tieredStore) addMem(key string, value ByteView) {
+	t.spilling = true
+	t.mem.Add(key, value)
+	t.spilling = false
+}
+
+func (t *tieredStore) Add(key string, value LRU_Cache.Value) {
+	//与覆盖内存中的记录相同，磁盘中的旧记录被覆盖时不触发回调
+	t.disk.discard(key)
+	t.addMem(key, value.(ByteView))
+}
+
+func (t *tieredStore) Get(key string) (LRU_Cache.Value, bool) {
+	if v, ok := t.mem.Get(key); ok {
+		return v, true
+	}
+	v, ok := t.disk.take(key)
+	if !ok {
+		return nil, false
+	}
+	t.addMem(key, v)
+	return v, true
+}
+
+func (t *tieredStore) Peek(key string) (LRU_Cache.Value, bool) {
+	if v, ok := t.mem.Peek(key); ok {
+		return v, true
+	}
+	if v, ok := t.disk.peek(key); ok {
+		return v, true
+	}
+	return nil, false
+}
+
+func (t *tieredStore) Remove(key string) {
+	if _, ok := t.mem.Peek(key); ok {
+		t.mem.Remove(key)
+		return
+	}
+	t.disk.remove(key)
+}
+
+//RemoveOldest 磁盘中的记录都比内存中的旧，优先淘汰磁盘中最久未访问的记录
+func (t *tieredStore) RemoveOldest() {
+	if t.disk.len() > 0 {
+		t.disk.removeOldest()
+		return
+	}
+	t.mem.RemoveOldest()
+}
+
+//SpillOldest 实现 memorySpiller，按内存的淘汰策略把一条记录移入磁盘
+func (t *tieredStore) SpillOldest() bool {
+	if t.mem.Len() == 0 {
+		return false
+	}
+	t.spilling = true
+	t.mem.RemoveOldest()
+	t.spilling = false
+	return true
+}
+
+func (t *tieredStore) Len() int {
+	return t.mem.Len() + t.disk.len()
+}
+
+func (t *tieredStore) Bytes() int64 {
+	return t.mem.Bytes()
+}
+
+//Resize 只修改内存的容量，缩小时超出的记录进入磁盘
+func (t *tieredStore) Resize(maxBytes int64) {
+	t.spilling = true
+	t.mem.Resize(maxBytes)
+	t.spilling = false
+}
+
+//Range 先遍历内存中的记录，再遍历磁盘中的记录，磁盘中的记录需要读取文件，读取失败的记录被跳过
+func (t *tieredStore) Range(fn func(key string, value LRU_Cache.Value) bool) {
+	stopped := false
+	t.mem.Range(func(key string, value LRU_Cache.Value) bool {
+		stopped = !fn(key, value)
+		return !stopped
+	})
+	if !stopped {
+		t.disk.rangeEntries(func(key string, value ByteView) bool {
+			return fn(key, value)
+		})
+	}
+}
+
+//RemoveExpired 实现 expiredRemover，磁盘中的记录只根据索引中的过期时间判断，不读取文件
+func (t *tieredStore) RemoveExpired(now time.Time) time.Time {
+	var keys []string
+	var next time.Time
+	t.mem.Range(func(key string, value LRU_Cache.Value) bool {
+		if v := value.(ByteView); v.expired(now) {
+			keys = append(keys, key)
+		} else {
+			next = earliestExpiry(next, v.e)
+		}
+		return true
+	})
+	for _, key := range keys {
+		t.mem.Remove(key)
+	}
+	return earliestExpiry(next, t.disk.removeExpired(now))
+}
+
+//earliestExpiry 返回 a 和 b 中较早的过期时间，零值表示永不过期
+func earliestExpiry(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+//diskTier 是一个分片的磁盘层，每条记录的值保存为 dir 下的一个文件，key、过期时间等保存在内存的索引中。
+//进程重启后索引丢失，dir 在第一次写入时被清空，需要跨重启保留缓存时使用 SaveSnapshot。
+//与 evictor 相同，diskTier 不是并发安全的，由所在分片的 mu 保护
+type diskTier struct {
+	dir      string
+	maxBytes int64 //0 表示不限制
+	nbytes   int64
+	ll       *list.List
+	index    map[string]*list.Element
+	seq      uint64
+	ready    bool //dir 是否已经清空并创建
+	//记录离开磁盘层时的回调，needValue 为 true 时先读取文件，把完整的值传给回调
+	onEvicted func(key string, value ByteView)
+	needValue bool
+}
+
+type diskEntry struct {
+	key       string
+	file      string //为空表示值为空，没有创建文件
+	size      int64
+	e         time.Time
+	tombstone bool
+	meta      map[string]string
+	//compressed 为 true 时文件中是压缩后的数据，读回时需要保留该标记，由 cache 解压
+	compressed bool
+}
+
+func newDiskTier(dir string, maxBytes int64, needValue bool) *diskTier {
+	return &diskTier{
+		dir:       dir,
+		maxBytes:  maxBytes,
+		ll:        list.New(),
+		index:     make(map[string]*list.Element),
+		needValue: needValue,
+	}
+}
+
+//put 把记录写入磁盘，写入失败或记录超过磁盘层的容量时直接淘汰
+func (d *diskTier) put(key string, value ByteView) {
+	d.discard(key)
+	e := &diskEntry{key: key, size: int64(len(key) + value.Len()), e: value.e, tombstone: value.tombstone, meta: value.meta, compressed: value.compressed}
+	if d.maxBytes != 0 && e.size > d.maxBytes {
+		d.onEvicted(key, value)
+		return
+	}
+	if value.Len() > 0 {
+		if err := d.prepare(); err != nil {
+			d.onEvicted(key, value)
+			return
+		}
+		d.seq++
+		e.file = filepath.Join(d.dir, strconv.FormatUint(d.seq, 36))
+		if err := os.WriteFile(e.file, value.b, 0o600); err != nil {
+			os.Remove(e.file)
+			d.onEvicted(key, value)
+			return
+		}
+	}
+	d.index[key] = d.ll.PushFront(e)
+	d.nbytes += e.size
+	for d.maxBytes != 0 && d.nbytes > d.maxBytes {
+		d.removeOldest()
+	}
+}
+
+//prepare 在第一次写入时清空并创建 dir，删除上一次运行遗留的文件
+func (d *diskTier) prepare() error {
+	if d.ready {
+		return nil
+	}
+	if err := os.RemoveAll(d.dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.dir, 0o700); err != nil {
+		return err
+	}
+	d.ready = true
+	return nil
+}
+
+//load 读取记录的值，文件为空时返回空值
+func (d *diskTier) load(e *diskEntry) (ByteView, error) {
+	v := ByteView{e: e.e, tombstone: e.tombstone, meta: e.meta, compressed: e.compressed}
+	if e.file == "" {
+		return v, nil
+	}
+	b, err := os.ReadFile(e.file)
+	v.b = b
+	return v, err
+}
+
+//take 读取并删除记录，用于把记录移回内存，不触发回调。文件读取失败的记录被淘汰，视为未命中
+func (d *diskTier) take(key string) (ByteView, bool) {
+	ele, ok := d.index[key]
+	if !ok {
+		return ByteView{}, false
+	}
+	e := ele.Value.(*diskEntry)
+	v, err := d.load(e)
+	d.unlink(ele)
+	if err != nil {
+		d.onEvicted(key, v)
+		return ByteView{}, false
+	}
+	return v, true
+}
+
+//peek 读取记录，不影响淘汰顺序
+func (d *diskTier) peek(key string) (ByteView, bool) {
+	ele, ok := d.index[key]
+	if !ok {
+		return ByteView{}, false
+	}
+	v, err := d.load(ele.Value.(*diskEntry))
+	return v, err == nil
+}
+
+//remove 删除记录并触发回调
+func (d *diskTier) remove(key string) {
+	if ele, ok := d.index[key]; ok {
+		d.removeElement(ele)
+	}
+}
+
+//discard 删除记录，不触发回调
+func (d *diskTier) discard(key string) {
+	if ele, ok := d.index[key]; ok {
+		d.unlink(ele)
+	}
+}
+
+func (d *diskTier) removeOldest() {
+	if ele := d.ll.Back(); ele != nil {
+		d.removeElement(ele)
+	}
+}
+
+func (d *diskTier) removeElement(ele *list.Element) {
+	e := ele.Value.(*diskEntry)
+	v := ByteView{e: e.e, tombstone: e.tombstone, meta: e.meta, compressed: e.compressed}
+	if d.needValue {
+		v, _ = d.load(e)
+	}
+	d.unlink(ele)
+	d.onEvicted(e.key, v)
+}
+
+//unlink 从索引中删除记录并删除文件
+func (d *diskTier) unlink(ele *list.Element) {
+	e := ele.Value.(*diskEntry)
+	d.ll.Remove(ele)
+	delete(d.index, e.key)
+	d.nbytes -= e.size
+	if e.file != "" {
+		os.Remove(e.file)
+	}
+}
+
+func (d *diskTier) len() int {
+	return d.ll.Len()
+}
+
+//rangeEntries 从新到旧遍历磁盘中的记录，读取失败的记录被跳过
+func (d *diskTier) rangeEntries(fn func(key string, value ByteView) bool) {
+	for ele := d.ll.Front(); ele != nil; ele = ele.Next() {
+		e := ele.Value.(*diskEntry)
+		v, err := d.load(e)
+		if err != nil {
+			continue
+		}
+		if !fn(e.key, v) {
+			return
+		}
+	}
+}
+
+//removeExpired 删除在 now 时刻已经过期的记录并触发回调，返回剩余记录中最早的过期时间
+func (d *diskTier) removeExpired(now time.Time) time.Time {
+	var next time.Time
+	for ele := d.ll.Front(); ele != nil; {
+		nxt := ele.Next()
+		if e := ele.Value.(*diskEntry); (ByteView{e: e.e}).expired(now) {
+			d.removeElement(ele)
+		} else {
+			next = earliestExpiry(next, e.e)
+		}
+		ele = nxt
+	}
+	return next
+}
+
+//clear 丢弃所有记录并删除 dir，不触发回调
+func (d *diskTier) clear() {
+	d.ll.Init()
+	d.index = make(map[string]*list.Element)
+	d.nbytes = 0
+	if d.ready {
+		os.RemoveAll(d.dir)
+		d.ready = false
+	}
+}
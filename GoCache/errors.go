@@ -0,0 +1,28 @@
+package GoCache
+
+import "errors"
+
+//可以通过 errors.Is 判断的错误
+var (
+	//ErrKeyRequired 表示传入的 key 为空
+	ErrKeyRequired = errors.New("gocache: key is required")
+	//ErrInvalidKey 表示 key 没有通过校验，例如过长或含有换行、NUL，见 WithMaxKeyLength 和 WithKeyValidator
+	ErrInvalidKey = errors.New("gocache: invalid key")
+	//ErrGroupNotFound 表示 group 不存在，在 nil *Group 上调用 Get 等方法时返回
+	ErrGroupNotFound = errors.New("gocache: group not found")
+	//ErrPeerUnavailable 表示与远程节点交互失败
+	ErrPeerUnavailable = errors.New("gocache: peer unavailable")
+	//ErrNotFound 表示 key 在数据源中确实不存在。getter 返回 ErrNotFound（或用 %w 包装它的错误）时，
+	//如果开启了 WithNegativeTTL，结果会被缓存一段时间；其他错误视为临时错误，不会被缓存。
+	ErrNotFound = errors.New("gocache: key not found")
+	//ErrCircuitOpen 表示访问远程节点的熔断器处于打开状态，请求没有发出，见 WithCircuitBreaker
+	ErrCircuitOpen = errors.New("gocache: peer circuit open")
+	//ErrPoolClosed 表示 HTTPPool 已经调用过 Shutdown
+	ErrPoolClosed = errors.New("gocache: http pool is shut down")
+	//ErrValueTooLarge 表示值超过了 WithMaxValueSize 设置的上限，没有写入缓存
+	ErrValueTooLarge = errors.New("gocache: value too large")
+	//ErrLoadOverloaded 表示同时调用 getter 的数量已经达到上限，等待超时，见 WithMaxConcurrentLoads
+	ErrLoadOverloaded = errors.New("gocache: too many concurrent loads")
+	//ErrPinLimit 表示固定的记录将超过所在分片容量的 90%，key 没有被固定，见 Group.Pin
+	ErrPinLimit = errors.New("gocache: pinned entries exceed the cache budget")
+)
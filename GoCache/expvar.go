@@ -0,0 +1,54 @@
+package GoCache
+
+import (
+	"expvar"
+	"sync"
+)
+
+var (
+	expvarOnce sync.Once
+	expvarVars *expvar.Map
+)
+
+//WithExpvar 把 group 的统计信息发布到标准库 expvar 的 gocache 变量中，以 group 名称为 key，
+//导入 net/http 并使用 http.DefaultServeMux 时可以通过 /debug/vars 查看，不需要 Prometheus 等外部依赖：
+//	"gocache": {"scores": {"hits": 1, "misses": 2, ...}}
+//每次读取时从 Stats() 获取最新的值。DestroyGroup 后 group 从 gocache 中删除。默认不发布
+func WithExpvar() Option {
+	return func(g *Group) {
+		g.expvar = true
+	}
+}
+
+//gocacheVars 返回名为 gocache 的 expvar.Map，第一次调用时创建
+func gocacheVars() *expvar.Map {
+	expvarOnce.Do(func() {
+		expvarVars = expvar.NewMap("gocache")
+	})
+	return expvarVars
+}
+
+func publishExpvar(g *Group) {
+	gocacheVars().Set(g.name, expvar.Func(func() interface{} {
+		s := g.Stats()
+		return map[string]int64{
+			"hits":                 s.Hits,
+			"hot_hits":             s.HotHits,
+			"misses":               s.Misses,
+			"local_loads":          s.LocalLoads,
+			"peer_loads":           s.PeerLoads,
+			"peer_errors":          s.PeerErrors,
+			"loader_dedups":        s.LoaderDedups,
+			"peer_circuit_open":    s.PeerCircuitOpen,
+			"write_behind_dropped": s.WriteBehindDropped,
+			"write_behind_errors":  s.WriteBehindErrors,
+			"entries":              int64(g.Len()),
+			"bytes":                g.UsedBytes(),
+			"max_bytes":            g.MaxBytes(),
+		}
+	}))
+}
+
+func unpublishExpvar(g *Group) {
+	gocacheVars().Delete(g.name)
+}
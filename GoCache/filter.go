@@ -0,0 +1,25 @@
+package GoCache
+
+//BloomFilter 是应用提供的成员过滤器，例如根据数据源中所有 key 构建的布隆过滤器。
+//MayContain 返回 false 表示 key 一定不在数据源中，返回 true 表示 key 可能存在。
+//布隆过滤器没有假阴性，因此存在的 key 总会继续调用 getter；MayContain 会被并发调用，需要并发安全
+type BloomFilter interface {
+	MayContain(key string) bool
+}
+
+//SetMembershipFilter 设置成员过滤器，缓存未命中、调用 getter 之前先询问 bf，bf 判定不存在的 key 直接返回 ErrNotFound，
+//不访问数据源，也不写入负缓存。只作用于本节点的 getter，由其他节点负责的 key 由其所在节点的过滤器判断。
+//可以随时替换，例如数据源的 key 集合变化后重建过滤器，bf 为 nil 时取消过滤
+func (g *Group) SetMembershipFilter(bf BloomFilter) {
+	g.filterMu.Lock()
+	defer g.filterMu.Unlock()
+	g.filter = bf
+}
+
+//mayContain 判断 key 是否可能存在于数据源，没有设置过滤器时总是返回 true
+func (g *Group) mayContain(key string) bool {
+	g.filterMu.RLock()
+	bf := g.filter
+	g.filterMu.RUnlock()
+	return bf == nil || bf.MayContain(key)
+}
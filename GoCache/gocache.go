@@ -3,9 +3,15 @@ package GoCache
 import (
 	pb "GoCache/gocachepb"
 	"GoCache/singleflight"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 /*
@@ -37,13 +43,121 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+//TTLGetter 是 Getter 的变体，回调函数在返回源数据的同时返回该数据的有效期 ttl，ttl 为 0 表示永不过期。
+//实现了 TTLGetter 的 getter 传入 NewGroup 后，缓存未命中时会优先调用 GetWithTTL。
+type TTLGetter interface {
+	GetWithTTL(key string) ([]byte, time.Duration, error)
+}
+
+//定义函数类型 TTLGetterFunc，同时实现 Getter 和 TTLGetter 接口，可以直接作为 NewGroup 的 getter 参数。
+type TTLGetterFunc func(key string) ([]byte, time.Duration, error)
+
+//GetWithTTL实现TTLGetter接口功能
+func (f TTLGetterFunc) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	return f(key)
+}
+
+//Get实现Getter接口功能，丢弃 ttl
+func (f TTLGetterFunc) Get(key string) ([]byte, error) {
+	bytes, _, err := f(key)
+	return bytes, err
+}
+
+//ContextGetter 是 Getter 的变体，回调函数可以通过 ctx 感知调用方的取消和超时。
+//实现了 ContextGetter 的 getter 传入 NewGroup 后，缓存未命中时会优先调用 GetContext。
+type ContextGetter interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+//定义函数类型 ContextGetterFunc，同时实现 Getter 和 ContextGetter 接口。
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+//GetContext实现ContextGetter接口功能
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+//Get实现Getter接口功能，使用 context.Background()
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+//ByteViewGetter 是 Getter 的变体，回调函数直接返回 ByteView，缓存未命中时 getter 实现了 ByteViewGetter 会优先调用 GetByteView。
+//返回的 ByteView 是只读的，可以不经复制直接写入缓存，适合回调函数本身就新分配了数据（用 WrapByteView 包装）
+//或者数据来自另一个 Group 的场景；普通的 Getter 返回的数据可能被调用者继续修改，写入缓存前需要复制一次。
+//返回值的元数据被保留，有效期使用 WithTTL 设置的默认值
+type ByteViewGetter interface {
+	GetByteView(ctx context.Context, key string) (ByteView, error)
+}
+
+//定义函数类型 ByteViewGetterFunc，同时实现 Getter 和 ByteViewGetter 接口。
+type ByteViewGetterFunc func(ctx context.Context, key string) (ByteView, error)
+
+//GetByteView实现ByteViewGetter接口功能
+func (f ByteViewGetterFunc) GetByteView(ctx context.Context, key string) (ByteView, error) {
+	return f(ctx, key)
+}
+
+//Get实现Getter接口功能，使用 context.Background()，返回值的拷贝
+func (f ByteViewGetterFunc) Get(key string) ([]byte, error) {
+	view, err := f(context.Background(), key)
+	return view.ByteSlice(), err
+}
+
 type Group struct {
 	name      string
 	getter    Getter
 	mainCache cache
-	peers     PeerPicker
+	//hotCache 保存从远程节点获取的热点值，避免热点 key 每次访问都请求远程节点
+	hotCache cache
+	//hotCache 是否启用，hotCache.cacheBytes 为 0 时表示不限制大小，因此单独记录
+	hotCacheEnabled bool
+	peersMu         sync.RWMutex
+	peers           PeerPicker
 	//使用Singleflight.Group确保每个密钥只获取一次
 	loader *singleflight.Group
+	stats  stats
+	//负缓存的有效期，为 0 表示不缓存不存在的 key
+	negativeTTL time.Duration
+	logger      leveledLogger
+	//距离过期不足 refreshWindow 时，Get 直接返回旧值并在后台刷新，为 0 表示不提前刷新
+	refreshWindow time.Duration
+	refreshing    sync.Map //正在后台刷新的 key
+	//默认有效期，用于 getter 没有给出 ttl 的值和 Set 写入的值，为 0 表示永不过期
+	ttl time.Duration
+	//后台清理已过期记录的间隔，为 0 表示不启动后台清理
+	cleanupInterval time.Duration
+	//从远程节点获取失败后的行为
+	peerPolicy PeerFailurePolicy
+	//负责 key 的节点失败后，最多再尝试哈希环上的几个远程节点
+	peerRetries int
+	//单个值的大小上限，为 0 表示不限制
+	maxValueSize int64
+	//mainCache 中记录的标签，见 SetWithTags
+	tags tagIndex
+	//远程节点超过 hedgeDelay 没有返回时向下一个节点发出对冲请求，为 0 表示不对冲
+	hedgeDelay time.Duration
+	//expvar 为 true 时把统计信息发布到 expvar，见 WithExpvar
+	expvar bool
+	//tracer 为 GetContext 等创建 span，默认为 nopTracer，见 WithTracer
+	tracer Tracer
+	//topKeys 不为 nil 时统计每个 key 的命中次数，见 WithTopKeys
+	topKeys *topKeys
+	//loadSem 限制同时调用 getter 的数量，为 nil 表示不限制，loadWait 是等待的最长时间，见 WithMaxConcurrentLoads
+	loadSem  chan struct{}
+	loadWait time.Duration
+	//bus 不为 nil 时 Invalidate 通过 bus 广播，而不是逐个通知远程节点，见 WithInvalidationBus
+	bus InvalidationBus
+	//maxKeyLength 和 keyValidator 用于校验 key，见 WithMaxKeyLength 和 WithKeyValidator
+	maxKeyLength int
+	keyValidator func(key string) error
+	//filter 不为 nil 时调用 getter 之前先判断 key 是否可能存在，见 SetMembershipFilter
+	filterMu sync.RWMutex
+	filter   BloomFilter
+	//noCopy 为 true 时写入和读取都不复制值，见 WithNoCopy
+	noCopy bool
+	//writeBehind 开启时 Set 异步写入远程节点，见 WithWriteBehind
+	writeBehind writeBehind
 }
 
 var (
@@ -55,44 +169,301 @@ var (
 //getter Getter，即缓存未命中时获取源数据的回调(callback)
 //mainCache cache，即一开始实现的并发缓存。
 //构建函数 NewGroup 用来实例化 Group，并且将 group 存储在全局变量 groups 中
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+//opts 为可选配置，与 NewGroupWithOptions 相同
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...Option) *Group {
+	return NewGroupWithOptions(name, getter, append([]Option{WithCacheBytes(cacheBytes)}, opts...)...)
+}
+
+//NewGroupWithOptions 使用函数选项(functional options)模式创建 Group，例如：
+//	NewGroupWithOptions("scores", getter, WithCacheBytes(2<<10), WithTTL(time.Minute))
+//没有设置 WithCacheBytes 时 mainCache 不限制大小。
+//同名的 group 已经存在时直接返回已有的 group，不会覆盖，其余参数被忽略
+func NewGroupWithOptions(name string, getter Getter, opts ...Option) *Group {
+	g := &Group{
+		name:   name,
+		getter: getter,
+		//hotCache 的容量在应用所有选项后再确定，-1 表示没有通过 WithHotCacheBytes 设置
+		hotCache:     cache{cacheBytes: -1},
+		loader:       &singleflight.Group{},
+		tracer:       nopTracer{},
+		maxKeyLength: DefaultMaxKeyLength,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.hotCache.cacheBytes < 0 {
+		//与 groupcache 相同，hotCache 默认使用 mainCache 1/8 的容量
+		g.hotCache.cacheBytes = g.mainCache.cacheBytes / 8
+		g.hotCacheEnabled = g.hotCache.cacheBytes > 0
+	}
+	g.mainCache.onRemove = g.tags.remove
+	g.mainCache.init()
+	g.hotCache.init()
 	if getter == nil {
-		fmt.Println("nil Getter")
+		g.logger.logf(LevelError, "[GoCache] nil Getter")
 		return nil
 	}
 	mu.Lock()
-	defer mu.Unlock()
-	g := &Group{
-		name:      name,
-		getter:    getter,
-		mainCache: cache{cacheBytes: cacheBytes},
-		loader:    &singleflight.Group{},
+	if old, ok := groups[name]; ok {
+		mu.Unlock()
+		return old
 	}
 	groups[name] = g
+	if g.cleanupInterval > 0 {
+		g.mainCache.startCleanup(g.cleanupInterval)
+	}
+	g.startWriteBehind()
+	if g.expvar {
+		publishExpvar(g)
+	}
+	mu.Unlock()
+	//订阅可能需要访问网络，在释放全局锁之后进行
+	if g.bus != nil {
+		g.subscribe()
+	}
 	return g
+}
+
+//NewGroupWithCleanup 与 NewGroup 相同，并额外启动一个后台协程，每隔 interval 清理一次已过期的记录
+//不再使用时需要调用 StopCleanup 停止后台协程
+func NewGroupWithCleanup(name string, cacheBytes int64, getter Getter, interval time.Duration) *Group {
+	return NewGroup(name, cacheBytes, getter, WithCleanupInterval(interval))
+}
 
+//StopCleanup 停止 NewGroupWithCleanup 启动的后台清理协程，没有启动时什么也不做
+func (g *Group) StopCleanup() {
+	g.mainCache.stopCleanup()
 }
 
 //GetGroup 用来特定名称的 Group，这里使用了只读锁 RLock()，因为不涉及任何冲突变量的写操作
 func GetGroup(name string) *Group {
-	mu.Lock()
+	mu.RLock()
+	defer mu.RUnlock()
 	g := groups[name]
-	mu.RLocker()
 	return g
 }
 
+//groupCount 返回当前已创建的 group 数量
+func groupCount() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(groups)
+}
+
+//Groups 返回当前已创建的所有 Group，按名称排序，可以用于导出监控指标
+func Groups() []*Group {
+	mu.RLock()
+	list := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+//DestroyGroup 从全局变量 groups 中删除名称为 name 的 Group，停止其后台清理协程并释放缓存
+//返回是否确实删除了一个 group
+func DestroyGroup(name string) bool {
+	mu.Lock()
+	g, ok := groups[name]
+	delete(groups, name)
+	mu.Unlock()
+	if !ok {
+		return false
+	}
+	g.mainCache.stopCleanup()
+	g.stopWriteBehind()
+	g.mainCache.clear()
+	g.hotCache.clear()
+	if g.expvar {
+		unpublishExpvar(g)
+	}
+	return true
+}
+
+//Name 返回 Group 的名称
+func (g *Group) Name() string {
+	return g.name
+}
+
+//MaxBytes 返回 mainCache 的容量，0 表示不限制
+func (g *Group) MaxBytes() int64 {
+	return g.mainCache.maxBytes()
+}
+
+//Resize 在运行时修改 mainCache 的容量，0 表示不限制，可以与读写并发调用。
+//缩小时立即按淘汰策略淘汰记录直到 UsedBytes <= cacheBytes，被淘汰的记录会以 EvictCapacity 触发 OnEvicted 回调。
+//分片数在创建时确定，容量从很大缩小到很小时每个分片分到的容量也会很小；hotCache 的容量不随之改变
+func (g *Group) Resize(cacheBytes int64) {
+	g.mainCache.resize(cacheBytes)
+}
+
+//Len 返回 mainCache 中的记录数，不包括 hotCache
+func (g *Group) Len() int {
+	return g.mainCache.len()
+}
+
+//UsedBytes 返回 mainCache 当前已使用的内存，不包括 hotCache
+func (g *Group) UsedBytes() int64 {
+	return g.mainCache.bytes()
+}
+
+//Range 遍历 mainCache 中未过期的记录，fn 返回 false 时停止遍历，不更新淘汰顺序，也不计入 Stats。
+//不包括 hotCache 中属于其他节点的记录和负缓存的墓碑记录，可以用于导出本节点的热数据，在重启后用 Set 重新写入。
+//遍历按分片进行，每个分片在持有锁时复制记录，fn 在锁外调用，fn 中可以访问 Group
+func (g *Group) Range(fn func(key string, value ByteView) bool) {
+	if g == nil {
+		return
+	}
+	g.mainCache.rangeEntries(fn)
+}
+
+//Peek 查看 key 是否在本地缓存(mainCache 或 hotCache)中，不加载数据、不更新淘汰顺序，也不计入 Stats。
+//过期的记录和负缓存的墓碑记录都视为不存在，适合监控程序采样已缓存的 key
+func (g *Group) Peek(key string) (ByteView, bool) {
+	if g == nil {
+		return ByteView{}, false
+	}
+	v, ok := g.mainCache.peek(key)
+	if !ok && g.hotCacheEnabled {
+		v, ok = g.hotCache.peek(key)
+	}
+	if !ok || v.tombstone {
+		return ByteView{}, false
+	}
+	return v, true
+}
+
 //Group 的 Get 方法
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+//GetContext 与 Get 相同，ctx 被取消或超时后，正在进行的 load（包括远程节点请求和 getter 回调）会尽快返回
+//g 为 nil（例如 GetGroup 没有找到对应的 group）时返回 ErrGroupNotFound
+func (g *Group) GetContext(ctx context.Context, key string) (_ ByteView, err error) {
+	if g == nil {
+		return ByteView{}, ErrGroupNotFound
+	}
 	//流程 ⑴ :从 mainCache 中查找缓存，如果存在则返回缓存值。
-	if key == "" {
-		return ByteView{}, fmt.Errorf("key is required")
+	if err := g.validateKey(key); err != nil {
+		return ByteView{}, err
 	}
+	ctx, span := g.startSpan(ctx, "gocache.Get", key)
+	defer func() { span.End(err) }()
 	//流程 ⑶ ：缓存不存在，则调用 load 方法
-	if v, ok := g.mainCache.get(key); ok {
-		log.Println("[GoCache] hit")
+	v, ok := g.lookupCache(key)
+	span.SetAttribute(AttrHit, ok)
+	if ok {
+		g.logger.logf(LevelDebug, "[GoCache] hit")
+		if v.tombstone {
+			return ByteView{}, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		g.maybeRefresh(key, v)
 		return v, nil
 	}
-	return g.load(key)
+	g.stats.add(&g.stats.misses)
+	value, err := g.load(ctx, key)
+	if err != nil && ctx.Err() == nil && !errors.Is(err, ErrNotFound) {
+		if v, ok := g.mainCache.getStale(key); ok && !v.tombstone {
+			g.logger.logf(LevelError, "[GoCache] Serving stale %s: %v", key, err)
+			v.stale = true
+			return v, nil
+		}
+	}
+	return value, err
+}
+
+//GetStream 与 Get 相同，但把值写入 w 而不是返回 ByteView。
+//负责 key 的远程节点实现了 StreamPeerGetter（例如 grpcpool）时，值从远程节点分块直接写入 w，
+//本节点既不缓存也不在内存中保存完整的值，适合几 MB 以上的大对象。
+//远程节点在写入任何数据之前失败时按 Get 的流程重新加载；已经写入部分数据后失败时返回错误，w 中的数据不完整
+func (g *Group) GetStream(key string, w io.Writer) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	if err := g.validateKey(key); err != nil {
+		return err
+	}
+	if v, ok := g.lookupCache(key); ok {
+		if v.tombstone {
+			return fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		g.maybeRefresh(key, v)
+		_, err := v.WriteTo(w)
+		return err
+	}
+	g.stats.add(&g.stats.misses)
+	ctx := context.Background()
+	if peers := g.getPeers(); peers != nil {
+		if peer, ok := peers.PickPeer(key); ok {
+			if streamer, ok := peer.(StreamPeerGetter); ok {
+				cw := &countingWriter{w: w}
+				err := streamer.GetStream(ctx, &pb.Request{Group: g.name, Key: key}, cw)
+				if err == nil {
+					g.stats.add(&g.stats.peerLoads)
+					return nil
+				}
+				g.countPeerError(err)
+				if cw.n > 0 {
+					return fmt.Errorf("%w: %v", ErrPeerUnavailable, err)
+				}
+			}
+		}
+	}
+	v, err := g.load(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = v.WriteTo(w)
+	return err
+}
+
+//countingWriter 记录已经写入 w 的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+//maybeRefresh 在 v 即将过期时启动后台刷新，每个 key 同时只有一个后台刷新。
+//刷新通过 g.loader 进行，失败时保留旧值，直到真正过期。
+func (g *Group) maybeRefresh(key string, v ByteView) {
+	if g.refreshWindow <= 0 || v.e.IsZero() || time.Until(v.e) > g.refreshWindow {
+		return
+	}
+	if _, loaded := g.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer g.refreshing.Delete(key)
+		if _, err := g.load(context.Background(), key); err != nil {
+			g.logger.logf(LevelError, "[GoCache] Failed to refresh %s: %v", key, err)
+		}
+	}()
+}
+
+//lookupCache 依次查找 mainCache 和 hotCache，并记录命中次数
+func (g *Group) lookupCache(key string) (ByteView, bool) {
+	if v, ok := g.mainCache.get(key); ok {
+		g.stats.add(&g.stats.hits)
+		g.countHit(key)
+		return v, true
+	}
+	if g.hotCacheEnabled {
+		if v, ok := g.hotCache.get(key); ok {
+			g.stats.add(&g.stats.hotHits)
+			g.countHit(key)
+			return v, true
+		}
+	}
+	return ByteView{}, false
 }
 
 ////load 调用 getLocally（分布式场景下会调用 getFromPeer 从其他节点获取）
@@ -100,62 +471,668 @@ func (g *Group) Get(key string) (ByteView, error) {
 //	return g.getLocally(key)
 //}
 
+//KeyErrors 记录批量操作中每个失败的 key 及其错误
+type KeyErrors map[string]error
+
+func (e KeyErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	msgs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", key, e[key]))
+	}
+	return fmt.Sprintf("%d keys failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+//GetMulti 批量获取多个 key。未命中的 key 按 PickPeer 选出的节点分组，每个远程节点只发送一次批量请求，
+//本地负责的 key 调用 getLocally 获取，远程节点失败时与 Get 相同按 WithPeerRetries 和 PeerFailurePolicy 处理。
+//部分 key 失败时仍返回其余 key 的结果，失败的 key 通过 KeyErrors 返回。
+func (g *Group) GetMulti(keys []string) (map[string]ByteView, error) {
+	if g == nil {
+		return nil, ErrGroupNotFound
+	}
+	ctx := context.Background()
+	values := make(map[string]ByteView, len(keys))
+	errs := make(KeyErrors)
+	seen := make(map[string]bool, len(keys))
+	var local []string
+	remote := make(map[PeerGetter][]string)
+	peers := g.getPeers()
+	for _, key := range keys {
+		if err := g.validateKey(key); err != nil {
+			errs[key] = err
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if v, ok := g.lookupCache(key); ok {
+			if v.tombstone {
+				errs[key] = fmt.Errorf("%s: %w", key, ErrNotFound)
+				continue
+			}
+			values[key] = v
+			continue
+		}
+		g.stats.add(&g.stats.misses)
+		if peers != nil {
+			if peer, ok := peers.PickPeer(key); ok {
+				remote[peer] = append(remote[peer], key)
+				continue
+			}
+		}
+		local = append(local, key)
+	}
+
+	//并发地向每个远程节点发送一次批量请求
+	type result struct {
+		peer PeerGetter
+		keys []string
+		res  *pb.MultiResponse
+		err  error
+	}
+	results := make(chan result, len(remote))
+	for peer, ks := range remote {
+		go func(peer PeerGetter, ks []string) {
+			res, err := g.getMultiFromPeer(ctx, peer, ks)
+			results <- result{peer: peer, keys: ks, res: res, err: err}
+		}(peer, ks)
+	}
+	for range remote {
+		r := <-results
+		if r.err != nil {
+			g.logger.logf(LevelError, "[GoCache] Failed to get multi from peer %v", r.err)
+			//与 load 相同：按 WithPeerRetries 逐个 key 尝试哈希环上之后的节点，仍然失败时按 PeerFailurePolicy 回退到本地或返回错误
+			for _, key := range r.keys {
+				err := r.err
+				if retries := g.retries(); retries > 0 {
+					var v ByteView
+					if v, err = g.getFromNextPeers(ctx, peers, key, []PeerGetter{r.peer}, retries); err == nil {
+						values[key] = v
+						continue
+					}
+				}
+				if g.peerPolicy == PeerFailFast || g.peerPolicy == PeerTryNext {
+					errs[key] = err
+				} else {
+					local = append(local, key)
+				}
+			}
+			continue
+		}
+		for _, key := range r.keys {
+			if v, ok := r.res.GetValues()[key]; ok {
+				values[key] = ByteView{b: v, meta: r.res.GetMeta()[key].GetValues()}
+				g.maybePopulateHotCache(key, values[key])
+			} else if msg, ok := r.res.GetErrors()[key]; ok {
+				errs[key] = errors.New(msg)
+			} else {
+				errs[key] = fmt.Errorf("missing from peer response")
+			}
+		}
+	}
+
+	for _, key := range local {
+		key := key
+		viewi, err := g.loader.Do(key, func() (interface{}, error) {
+			return g.getLocally(ctx, key)
+		})
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		values[key] = viewi.(ByteView)
+	}
+	if len(errs) > 0 {
+		return values, errs
+	}
+	return values, nil
+}
+
 //getLocally 调用用户回调函数 g.getter.Get() 获取源数据，并且将源数据添加到缓存 mainCache 中（通过 populateCache 方法）
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key)
+//如果 getter 实现了 ByteViewGetter，则改为调用 GetByteView，返回的值不经复制直接写入缓存；
+//如果 getter 实现了 ContextGetter，则改为调用 GetContext，把 ctx 传给用户回调；
+//如果 getter 实现了 TTLGetter，则改为调用 GetWithTTL，并按返回的 ttl 设置过期时间，ttl 为 0 时使用 WithTTL 设置的默认值
+func (g *Group) getLocally(ctx context.Context, key string) (ByteView, error) {
+	ctx, span := g.startSpan(ctx, "gocache.getLocally", key)
+	value, err := g.loadLocally(ctx, key)
+	span.End(err)
+	return value, err
+}
+
+func (g *Group) loadLocally(ctx context.Context, key string) (ByteView, error) {
+	var (
+		bytes []byte
+		owned ByteView
+		isBV  bool
+		ttl   time.Duration
+		err   error
+	)
+	if !g.mayContain(key) {
+		return ByteView{}, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if err = g.acquireLoad(ctx); err != nil {
+		return ByteView{}, err
+	}
+	g.stats.add(&g.stats.localLoads)
+	if bg, ok := g.getter.(ByteViewGetter); ok {
+		owned, err = bg.GetByteView(ctx, key)
+		isBV = true
+	} else if cg, ok := g.getter.(ContextGetter); ok {
+		bytes, err = cg.GetContext(ctx, key)
+	} else if tg, ok := g.getter.(TTLGetter); ok {
+		bytes, ttl, err = tg.GetWithTTL(key)
+	} else {
+		bytes, err = g.getter.Get(key)
+	}
+	g.releaseLoad()
 	if err != nil {
+		//只缓存确定不存在的结果，临时错误下次仍然访问数据源
+		if g.negativeTTL > 0 && errors.Is(err, ErrNotFound) {
+			g.populateCache(key, ByteView{e: expireAt(g.negativeTTL), tombstone: true})
+		}
 		return ByteView{}, err
 	}
-	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	if ttl <= 0 {
+		ttl = g.ttl
+	}
+	var value ByteView
+	if isBV {
+		//ByteView 是只读的，不需要复制；只保留数据和元数据，过期时间、墓碑等状态由本 Group 决定
+		value = ByteView{b: owned.b, e: expireAt(ttl), meta: owned.meta, shared: g.noCopy}
+	} else {
+		value = ByteView{b: g.ownBytes(bytes), e: expireAt(ttl), shared: g.noCopy}
+	}
+	//超过 maxValueSize 的值仍然返回给调用者，只是不缓存
+	if err := g.populateCache(key, value); err != nil {
+		g.logger.logf(LevelDebug, "[GoCache] not caching %s: %v", key, err)
+	}
 	return value, nil
 }
 
-//将源数据添加到缓存 mainCache 中
-func (g *Group) populateCache(key string, value ByteView) {
+//SetWithTTL 将 key 对应的值直接写入本地缓存 mainCache，ttl 为 0 表示永不过期。
+//值超过 WithMaxValueSize 设置的上限时返回 ErrValueTooLarge
+func (g *Group) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return g.populateCache(key, ByteView{b: g.ownBytes(value), e: expireAt(ttl), shared: g.noCopy})
+}
+
+//Touch 在 key 已经缓存且未过期时把过期时间重置为 now+ttl 并返回 true，否则返回 false，ttl <= 0 表示永不过期。
+//不会调用 getter，也不复制值，适合实现滑动过期的会话：每次使用会话时调用 Touch 续期。
+//只修改本节点 mainCache 中的记录，不转发给其他节点；墓碑记录（见 WithNegativeTTL）不会被续期。key 没有通过 validateKey 时返回 false
+func (g *Group) Touch(key string, ttl time.Duration) bool {
+	if g.validateKey(key) != nil {
+		return false
+	}
+	return g.mainCache.extend(key, ttl)
+}
+
+//将源数据添加到缓存 mainCache 中，值超过 maxValueSize 时不缓存，返回 ErrValueTooLarge
+func (g *Group) populateCache(key string, value ByteView) error {
+	if g.tooLarge(value) {
+		return fmt.Errorf("%s: %w", key, ErrValueTooLarge)
+	}
 	g.mainCache.add(key, value)
+	return nil
+}
+
+//tooLarge 判断 value 是否超过 maxValueSize
+func (g *Group) tooLarge(value ByteView) bool {
+	return g.maxValueSize > 0 && int64(value.Len()) > g.maxValueSize
+}
+
+//SetLocal 只把 key 对应的值写入本节点的 mainCache，不转发给其他节点，使用 WithTTL 设置的默认有效期。
+//用于节点间通信的服务端处理其他节点转发来的 Set 请求，值超过 WithMaxValueSize 设置的上限时返回 ErrValueTooLarge。
+//tags 为 SetWithTags 设置的标签
+func (g *Group) SetLocal(key string, value []byte, tags ...string) error {
+	return g.SetLocalWithMeta(key, value, nil, tags...)
+}
+
+//SetLocalWithMeta 与 SetLocal 相同，并保存 SetWithMeta 设置的元数据 meta。
+//与 Set 相同先检查 key，其他节点写入的 key 同样受 WithMaxKeyLength 和 WithKeyValidator 约束
+func (g *Group) SetLocalWithMeta(key string, value []byte, meta map[string]string, tags ...string) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	if err := g.validateKey(key); err != nil {
+		return err
+	}
+	if err := g.populateTagged(key, value, tags, meta); err != nil {
+		return err
+	}
+	g.loader.Forget(key)
+	return nil
+}
+
+//populateTagged 记录 key 的标签后写入 mainCache，先更新标签，写入时立即被淘汰的记录也能通过 onRemove 删除标签
+func (g *Group) populateTagged(key string, value []byte, tags []string, meta map[string]string) error {
+	g.tags.set(key, tags)
+	if err := g.populateCache(key, ByteView{b: g.ownBytes(value), e: expireAt(g.ttl), meta: cloneMeta(meta), shared: g.noCopy}); err != nil {
+		g.tags.remove(key)
+		return err
+	}
+	return nil
+}
+
+//GetOrStore 与 sync.Map.LoadOrStore 相同：key 在本节点 mainCache 中有未过期的值时返回该值和 loaded=true，
+//否则写入 value 并返回它和 loaded=false，查找和写入在同一次持有分片锁期间完成，并发调用时只有一个 value 会被写入。
+//用于缓存调用者自己计算的值，不调用 getter，也不转发给其他节点；写入的值使用 WithTTL 设置的默认有效期，没有标签。
+//key 没有通过 validateKey 或值超过 WithMaxValueSize 设置的上限时不写入，返回 value 和 false
+func (g *Group) GetOrStore(key string, value []byte) (actual ByteView, loaded bool) {
+	view := ByteView{b: g.ownBytes(value), e: expireAt(g.ttl), shared: g.noCopy}
+	if g.validateKey(key) != nil || g.tooLarge(view) {
+		return view, false
+	}
+	if actual, loaded = g.mainCache.getOrAdd(key, view); !loaded {
+		g.tags.remove(key)
+		g.loader.Forget(key)
+	}
+	return actual, loaded
+}
+
+//DeleteLocal 只从本节点的 mainCache 和 hotCache 中删除 key，不转发给其他节点，
+//用于节点间通信的服务端处理其他节点转发来的 Delete 请求，以及 Invalidate 广播的删除
+func (g *Group) DeleteLocal(key string) {
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+	g.loader.Forget(key)
+}
+
+//Set 将 key 对应的值写入本地缓存 mainCache，如果注册了 peers，还会写入负责该 key 的远程节点，
+//这样值会缓存在权威节点上，而不只是收到请求的节点上。key 已存在时覆盖原有的值。
+//写入的值使用 WithTTL 设置的默认有效期。值超过 WithMaxValueSize 设置的上限时返回 ErrValueTooLarge，
+//本地和远程节点都不会写入。开启 WithWriteBehind 时不等待远程节点写入完成
+func (g *Group) Set(key string, value []byte) error {
+	return g.set(key, value, nil, nil)
+}
+
+//SetWithMeta 与 Set 相同，并给值附加元数据 meta，例如 MetaContentType 和 MetaETag，
+//元数据与值一起写入远程节点、保存到快照中，通过 Get 返回的 ByteView.Meta 读取。
+//再次写入 key 时元数据被替换；元数据不计入容量
+func (g *Group) SetWithMeta(key string, value []byte, meta map[string]string) error {
+	return g.set(key, value, nil, meta)
+}
+
+func (g *Group) set(key string, value []byte, tags []string, meta map[string]string) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	if err := g.validateKey(key); err != nil {
+		return err
+	}
+	if err := g.populateTagged(key, value, tags, meta); err != nil {
+		return err
+	}
+	g.hotCache.remove(key)
+	//写入之前开始的 load 可能返回旧值，之后的 Get 不再与它合并
+	g.loader.Forget(key)
+	if peers := g.getPeers(); peers != nil {
+		if peer, ok := peers.PickPeer(key); ok {
+			req := &pb.SetRequest{
+				Group: g.name,
+				Key:   key,
+				Value: value,
+				Tags:  tags,
+				Meta:  meta,
+			}
+			if g.writeBehind.enabled {
+				//Set 返回后调用方可能修改 value，异步发送前需要复制
+				req.Value = cloneBytes(value)
+				req.Tags = append([]string(nil), tags...)
+				req.Meta = cloneMeta(meta)
+				if g.enqueueWrite(peer, req) {
+					return nil
+				}
+			}
+			if err := peer.Set(req, &pb.SetResponse{}); err != nil {
+				return fmt.Errorf("set to peer: %w: %v", ErrPeerUnavailable, err)
+			}
+		}
+	}
+	return nil
+}
+
+//Clear 清空本节点上 mainCache 和 hotCache 中的所有记录，UsedBytes 归零，可以与 Get 并发调用。
+//设置了 WithOnEvicted 时，每条被清空的记录以 EvictDeleted 触发回调
+func (g *Group) Clear() {
+	if g == nil {
+		return
+	}
+	g.mainCache.clear()
+	g.hotCache.clear()
+}
+
+//ClearCluster 清空本节点的缓存，并通知所有远程节点清空同名 group 的缓存。
+//各节点并行清空，部分节点失败时其余节点仍会被清空，返回的错误包含失败的节点数
+func (g *Group) ClearCluster() error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	g.Clear()
+	return g.broadcast("clear", func(peer PeerGetter) error {
+		return peer.Clear(&pb.Request{Group: g.name})
+	})
 }
 
+//broadcast 并行地对所有远程节点调用 fn，部分节点失败时其余节点不受影响，
+//返回的错误包含失败的节点数和第一个错误，op 用于错误信息
+func (g *Group) broadcast(op string, fn func(peer PeerGetter) error) error {
+	peers := g.getPeers()
+	if peers == nil {
+		return nil
+	}
+	getters := peers.Peers()
+	errs := make([]error, len(getters))
+	var wg sync.WaitGroup
+	for i, peer := range getters {
+		wg.Add(1)
+		go func(i int, peer PeerGetter) {
+			defer wg.Done()
+			errs[i] = fn(peer)
+		}(i, peer)
+	}
+	wg.Wait()
+	var failed int
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s %d of %d peers: %w: %v", op, failed, len(getters), ErrPeerUnavailable, first)
+	}
+	return nil
+}
+
+//Invalidate 在数据源中的值变化后使 key 在整个集群中失效：删除本节点的记录，并通知所有远程节点删除，
+//而不只是负责该 key 的节点，这样其他节点 hotCache 中的副本也会被删除。
+//通知是尽力而为的，部分节点失败时其余节点仍会删除，返回的错误包含失败的节点数。
+//开启 WithInvalidationBus 时不再逐个通知远程节点，改为把 key 发布到 bus
+func (g *Group) Invalidate(key string) error {
+	return g.InvalidateMany([]string{key})
+}
+
+//InvalidateMany 与 Invalidate 相同，一次使多个 key 失效，每个远程节点依次删除所有 key，一个 key 失败时继续删除其余的 key
+func (g *Group) InvalidateMany(keys []string) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	for _, key := range keys {
+		if err := g.validateKey(key); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		g.DeleteLocal(key)
+	}
+	if g.bus != nil {
+		return g.publish(keys)
+	}
+	return g.broadcast("invalidate on", func(peer PeerGetter) error {
+		var first error
+		for _, key := range keys {
+			if err := peer.Delete(&pb.Request{Group: g.name, Key: key}); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+}
+
+//Delete 从本地缓存 mainCache 中删除 key，如果注册了 peers，还会通知负责该 key 的远程节点删除。
+//删除不存在的 key 不是错误，可以重复调用。
+func (g *Group) Delete(key string) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	if err := g.validateKey(key); err != nil {
+		return err
+	}
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+	g.loader.Forget(key)
+	//与 getFromPeer 相同，通过一致性哈希只通知负责该 key 的节点
+	if peers := g.getPeers(); peers != nil {
+		if peer, ok := peers.PickPeer(key); ok {
+			req := &pb.Request{
+				Group: g.name,
+				Key:   key,
+			}
+			if err := peer.Delete(req); err != nil {
+				return fmt.Errorf("delete from peer: %w: %v", ErrPeerUnavailable, err)
+			}
+		}
+	}
+	return nil
+}
+
+//RegisterPeers 用于第一次注册 PeerPicker，已经注册过时不做任何事，需要替换时使用 SetPeers
 func (g *Group) RegisterPeers(peers PeerPicker) {
-	if g.peers != nil {
+	if g.getPeers() != nil {
 		//panic("RegisterPeerPicker called more than once")
-		fmt.Println("RegisterPeerPicker called more than once")
+		g.logger.logf(LevelError, "[GoCache] RegisterPeerPicker called more than once")
 		return
 	}
+	g.SetPeers(peers)
+}
+
+//SetPeers 在加锁的情况下替换 PeerPicker，用于节点加入或离开集群后重建哈希环。
+//正在进行的 load 要么使用旧的 PeerPicker，要么使用新的，不会看到中间状态。
+func (g *Group) SetPeers(peers PeerPicker) {
+	g.peersMu.Lock()
+	defer g.peersMu.Unlock()
 	g.peers = peers
 }
 
-func (g *Group) load(key string) (value ByteView, err error) {
+//getPeers 返回当前的 PeerPicker，调用方应只读取一次并在本次操作中一直使用它
+func (g *Group) getPeers() PeerPicker {
+	g.peersMu.RLock()
+	defer g.peersMu.RUnlock()
+	return g.peers
+}
+
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	ctx, span := g.startSpan(ctx, "gocache.load", key)
+	defer func() { span.End(err) }()
 	//无论并发调用者数量如何，每个密钥只能获取一次（本地或远程）
 	//使用 g.loader.Do 包裹起来即可，这样确保了并发场景下针对相同的 key，load 过程只会调用一次。
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
-		if g.peers != nil {
-			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil {
+	//使用 DoChan 而不是 Do，ctx 结束时立即返回，正在进行的 load 继续执行，结果交给其他等待的调用者
+	ch := g.loader.DoChan(key, func() (interface{}, error) {
+		if peers := g.getPeers(); peers != nil {
+			if peer, ok := peers.PickPeer(key); ok {
+				value, tried, err := g.getFromPeerHedged(ctx, peers, peer, key)
+				if err == nil {
+					g.maybePopulateHotCache(key, value)
 					return value, nil
 				}
-				log.Println("[GeeCache] Failed to get from peer", err)
+				g.logger.logf(LevelError, "[GoCache] Failed to get from peer %v", err)
+				//调用方已经放弃，不再回退到本地 getter
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				if retries := g.retries(); retries > 0 {
+					if value, err = g.getFromNextPeers(ctx, peers, key, tried, retries); err == nil {
+						return value, nil
+					}
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+				}
+				if g.peerPolicy == PeerFailFast || g.peerPolicy == PeerTryNext {
+					return nil, err
+				}
 			}
 		}
-		return g.getLocally(key)
+		return g.getLocally(ctx, key)
 	})
-	if err == nil {
-		return viewi.(ByteView), nil
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return ByteView{}, res.Err
+		}
+		return res.Val.(ByteView), nil
+	case <-ctx.Done():
+		return ByteView{}, ctx.Err()
 	}
-	return
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+//maybePopulateHotCache 以 1/10 的概率把从远程节点获取的值放入 hotCache，
+//只有被频繁访问的 key 才大概率进入 hotCache
+func (g *Group) maybePopulateHotCache(key string, value ByteView) {
+	if g.hotCacheEnabled && !g.tooLarge(value) && rand.Intn(10) == 0 {
+		g.hotCache.add(key, value)
+	}
+}
+
+//retries 返回负责 key 的节点失败后还要尝试的远程节点数，PeerTryNext 至少尝试 1 个
+func (g *Group) retries() int {
+	if g.peerPolicy == PeerTryNext && g.peerRetries < 1 {
+		return 1
+	}
+	return g.peerRetries
+}
+
+//getFromNextPeers 在负责 key 的节点失败后，依次尝试哈希环上之后的节点，最多尝试 retries 个远程节点（包括对冲请求访问过的节点），
+//tried 中已经尝试过的节点会被跳过
+func (g *Group) getFromNextPeers(ctx context.Context, peers PeerPicker, key string, tried []PeerGetter, retries int) (ByteView, error) {
+	err := fmt.Errorf("%w: no more peers to try", ErrPeerUnavailable)
+	seen := make(map[PeerGetter]bool, len(tried))
+	for _, peer := range tried {
+		seen[peer] = true
+	}
+	for _, peer := range peers.PickPeers(key, retries+1) {
+		if seen[peer] || len(seen) > retries {
+			continue
+		}
+		seen[peer] = true
+		var value ByteView
+		if value, err = g.getFromPeer(ctx, peer, key); err == nil {
+			g.maybePopulateHotCache(key, value)
+			return value, nil
+		}
+		g.logger.logf(LevelError, "[GoCache] Failed to get from next peer %v", err)
+		if ctx.Err() != nil {
+			return ByteView{}, ctx.Err()
+		}
+	}
+	return ByteView{}, err
+}
+
+//countPeerError 记录一次远程请求失败，区分熔断器打开导致的失败
+func (g *Group) countPeerError(err error) {
+	g.stats.add(&g.stats.peerErrors)
+	if errors.Is(err, ErrCircuitOpen) {
+		g.stats.add(&g.stats.circuitOpen)
+	}
+}
+
+func (g *Group) getMultiFromPeer(ctx context.Context, peer PeerGetter, keys []string) (*pb.MultiResponse, error) {
+	req := &pb.MultiRequest{
+		Group: g.name,
+		Keys:  keys,
+	}
+	res := &pb.MultiResponse{}
+	if err := peer.GetMulti(ctx, req, res); err != nil {
+		g.countPeerError(err)
+		return nil, fmt.Errorf("%w: %v", ErrPeerUnavailable, err)
+	}
+	g.stats.add(&g.stats.peerLoads)
+	return res, nil
+}
+
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
+	value, err := g.fetchFromPeer(ctx, peer, key)
+	g.countPeerResult(err)
+	return value, err
+}
+
+//fetchFromPeer 从远程节点获取 key，不计入 Stats
+func (g *Group) fetchFromPeer(ctx context.Context, peer PeerGetter, key string) (_ ByteView, err error) {
+	ctx, span := g.startSpan(ctx, "gocache.getFromPeer", key)
+	if addr, ok := peerAddr(peer); ok {
+		span.SetAttribute(AttrPeer, addr)
+	}
+	defer func() { span.End(err) }()
 	//bytes, err := peer.Get(g.name, key)
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
-	if err != nil {
-		return ByteView{}, err
+	if err := peer.Get(ctx, req, res); err != nil {
+		return ByteView{}, fmt.Errorf("%w: %v", ErrPeerUnavailable, err)
 	}
 	//return ByteView{b: bytes}, nil
-	return ByteView{b: res.Value}, nil
+	return ByteView{b: res.Value, meta: res.Meta, shared: g.noCopy}, nil
+}
+
+//countPeerResult 按一次远程获取的结果更新 Stats
+func (g *Group) countPeerResult(err error) {
+	if err != nil {
+		g.countPeerError(err)
+		return
+	}
+	g.stats.add(&g.stats.peerLoads)
+}
+
+//getFromPeerHedged 从负责 key 的节点 peer 获取 key，开启 WithHedging 时，hedgeDelay 后还没有返回则向哈希环上的下一个节点
+//发出对冲请求，使用先成功的结果并取消另一个请求。返回尝试过的节点，无论是否对冲，Stats 中只计入一次结果
+func (g *Group) getFromPeerHedged(ctx context.Context, peers PeerPicker, peer PeerGetter, key string) (ByteView, []PeerGetter, error) {
+	tried := []PeerGetter{peer}
+	if g.hedgeDelay <= 0 {
+		value, err := g.getFromPeer(ctx, peer, key)
+		return value, tried, err
+	}
+	type result struct {
+		value ByteView
+		err   error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	//返回时取消仍在进行的请求，results 有足够的缓冲，被取消的协程不会阻塞
+	defer cancel()
+	results := make(chan result, 2)
+	fetch := func(peer PeerGetter) {
+		value, err := g.fetchFromPeer(ctx, peer, key)
+		results <- result{value, err}
+	}
+	go fetch(peer)
+	timer := time.NewTimer(g.hedgeDelay)
+	defer timer.Stop()
+	pending := 1
+	var err error
+	for {
+		select {
+		case <-timer.C:
+			for _, next := range peers.PickPeers(key, 2) {
+				if next != peer {
+					tried = append(tried, next)
+					pending++
+					go fetch(next)
+					break
+				}
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				g.countPeerResult(nil)
+				return res.value, tried, nil
+			}
+			err = res.err
+			//第一个请求在对冲之前失败时不再对冲，交给 load 按 PeerFailurePolicy 处理
+			if pending == 0 {
+				g.countPeerResult(err)
+				return ByteView{}, tried, err
+			}
+		}
+	}
 }
@@ -1,12 +1,31 @@
 package GoCache
 
 import (
+	"GoCache/LRU_Cache"
+	"GoCache/consistenthash"
+	pb "GoCache/gocachepb"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+//twoEntries 是恰好能放下两条 key 为 2 字节、value 为 4 字节的记录的容量
+var twoEntries = 2 * LRU_Cache.EntryBytes("k1", ByteView{b: []byte("1234")})
+
 var db = map[string]string{
 	"Tom":  "630",
 	"Jack": "589",
@@ -66,3 +85,2293 @@ func TestGetGroup(t *testing.T) {
 		t.Fatalf("expect nil, but %s got", group.name)
 	}
 }
+
+func TestDestroyGroup(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	g := NewGroupWithCleanup("destroy", 2<<10, getter, time.Millisecond)
+	if NewGroup("destroy", 2<<10, getter) != g {
+		t.Fatal("NewGroup should return the existing group instead of overwriting it")
+	}
+	g.Get("Tom")
+
+	if !DestroyGroup("destroy") || GetGroup("destroy") != nil {
+		t.Fatal("failed to destroy group")
+	}
+	if DestroyGroup("destroy") {
+		t.Fatal("destroy missing group should return false")
+	}
+	if g.mainCache.stop != nil {
+		t.Fatal("destroyed group should stop cleanup")
+	}
+	for _, s := range g.mainCache.shards {
+		if s.store != nil {
+			t.Fatal("destroyed group should free its cache")
+		}
+	}
+	if NewGroup("destroy", 2<<10, getter) == g {
+		t.Fatal("expect a new group after destroy")
+	}
+}
+
+func TestGetGroupConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		name := fmt.Sprintf("concurrent-%d", i%10)
+		go func() {
+			defer wg.Done()
+			NewGroup(name, 2<<10, GetterFunc(
+				func(key string) (bytes []byte, err error) { return }))
+		}()
+		go func() {
+			defer wg.Done()
+			GetGroup(name)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetGroup/NewGroup deadlocked")
+	}
+}
+
+func TestGetWithTTL(t *testing.T) {
+	loads := 0
+	g := NewGroup("ttl", 2<<10, TTLGetterFunc(
+		func(key string) ([]byte, time.Duration, error) {
+			loads++
+			return []byte(key), 20 * time.Millisecond, nil
+		}))
+
+	if view, err := g.Get("Tom"); err != nil || view.String() != "Tom" || view.Expire().IsZero() {
+		t.Fatal("failed to get value of Tom")
+	}
+	if _, err := g.Get("Tom"); err != nil || loads != 1 {
+		t.Fatalf("cache Tom miss before expiration, loads %d", loads)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := g.Get("Tom"); err != nil || loads != 2 {
+		t.Fatalf("expired Tom should be loaded again, loads %d", loads)
+	}
+
+	g.SetWithTTL("Jack", []byte("589"), 0)
+	if view, err := g.Get("Jack"); err != nil || view.String() != "589" || !view.Expire().IsZero() || loads != 2 {
+		t.Fatal("failed to get value of Jack set without ttl")
+	}
+}
+
+func TestExpiredNotCounted(t *testing.T) {
+	c := &cache{cacheBytes: twoEntries}
+	c.init()
+	c.add("a1", ByteView{b: []byte("1234")})
+	c.add("b1", ByteView{b: []byte("1234"), e: time.Now().Add(10 * time.Millisecond)})
+	time.Sleep(20 * time.Millisecond)
+	//b1 已过期，应当先清理 b1 而不是淘汰仍然有效的 a1
+	c.add("c1", ByteView{b: []byte("1234")})
+	if _, ok := c.get("a1"); !ok {
+		t.Fatal("valid entry a1 should not be evicted while expired entry exists")
+	}
+	if _, ok := c.get("b1"); ok {
+		t.Fatal("expired entry b1 should be a miss")
+	}
+}
+
+func TestNextExpiry(t *testing.T) {
+	c := &cache{cacheBytes: twoEntries}
+	c.init()
+	later := time.Now().Add(time.Hour)
+	c.add("a1", ByteView{b: []byte("1234"), e: later})
+	c.add("b1", ByteView{b: []byte("1234"), e: time.Now().Add(10 * time.Millisecond)})
+	time.Sleep(20 * time.Millisecond)
+	c.add("c1", ByteView{b: []byte("1234")})
+	s := c.shardFor("a1")
+	if _, ok := c.peek("a1"); !ok || !s.nextExpiry.Equal(later) {
+		t.Fatalf("nextExpiry should move to the remaining entry after the scan, got %v", s.nextExpiry)
+	}
+	//a1 还没有过期，写满时不需要扫描
+	if s.mayHaveExpired(time.Now()) {
+		t.Fatal("no entry can have expired before nextExpiry")
+	}
+}
+
+func TestOverwriteNotCountedAsAccess(t *testing.T) {
+	c := &cache{cacheBytes: twoEntries, policy: PolicyLFU}
+	c.init()
+	c.add("a1", ByteView{b: []byte("1234")})
+	c.get("a1")
+	c.get("a1")
+	c.add("b1", ByteView{b: []byte("1234")})
+	c.add("b1", ByteView{b: []byte("5678")})
+	//覆盖 b1 只算一次写入，b1 的访问次数少于 a1，应当被淘汰
+	c.add("c1", ByteView{b: []byte("1234")})
+	if _, ok := c.peek("a1"); !ok {
+		t.Fatal("frequently accessed a1 should not be evicted")
+	}
+	if _, ok := c.peek("b1"); ok {
+		t.Fatal("overwritten b1 should be evicted")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	g := NewGroupWithCleanup("cleanup", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }), 10*time.Millisecond)
+	defer g.StopCleanup()
+
+	g.SetWithTTL("Tom", []byte("630"), 5*time.Millisecond)
+	g.SetWithTTL("Jack", []byte("589"), 0)
+	time.Sleep(50 * time.Millisecond)
+
+	var n, ttls int
+	for _, s := range g.mainCache.shards {
+		s.mu.Lock()
+		if s.store != nil {
+			n, ttls = n+s.store.Len(), ttls+s.ttls
+		}
+		s.mu.Unlock()
+	}
+	if n != 1 || ttls != 0 {
+		t.Fatalf("expired entry should be purged in background, got %d entries and %d ttls", n, ttls)
+	}
+}
+
+//fakePeer 记录收到的请求，用于测试与远程节点的交互
+type fakePeer struct {
+	deleted []string
+	set     map[string]string
+	multi   int
+	cleared int
+	tags    []string            //收到的 InvalidateTag 请求
+	tagged  map[string][]string //InvalidateTag 返回的 key
+	meta    map[string]map[string]string
+}
+
+func (p *fakePeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	out.Value = []byte("peer:" + in.GetKey())
+	return nil
+}
+
+func (p *fakePeer) GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	p.multi++
+	out.Values = make(map[string][]byte)
+	for _, key := range in.GetKeys() {
+		out.Values[key] = []byte("peer:" + key)
+	}
+	return nil
+}
+
+func (p *fakePeer) Set(in *pb.SetRequest, out *pb.SetResponse) error {
+	if p.set == nil {
+		p.set = make(map[string]string)
+	}
+	p.set[in.GetKey()] = string(in.GetValue())
+	if in.GetMeta() != nil {
+		if p.meta == nil {
+			p.meta = make(map[string]map[string]string)
+		}
+		p.meta[in.GetKey()] = in.GetMeta()
+	}
+	return nil
+}
+
+func (p *fakePeer) Delete(in *pb.Request) error {
+	p.deleted = append(p.deleted, in.GetKey())
+	return nil
+}
+
+func (p *fakePeer) Clear(in *pb.Request) error {
+	p.cleared++
+	return nil
+}
+
+func (p *fakePeer) InvalidateTag(in *pb.TagRequest, out *pb.TagResponse) error {
+	p.tags = append(p.tags, in.GetTag())
+	out.Keys = p.tagged[in.GetTag()]
+	return nil
+}
+
+//fakePicker 把所有 key 都交给同一个 peer，next 为哈希环上之后的节点
+type fakePicker struct {
+	peer PeerGetter
+	next []PeerGetter
+}
+
+func (p *fakePicker) PickPeer(key string) (PeerGetter, bool) {
+	return p.peer, true
+}
+
+func (p *fakePicker) Peers() []PeerGetter {
+	return append([]PeerGetter{p.peer}, p.next...)
+}
+
+func (p *fakePicker) PickPeers(key string, n int) []PeerGetter {
+	peers := append([]PeerGetter{p.peer}, p.next...)
+	if len(peers) > n {
+		peers = peers[:n]
+	}
+	return peers
+}
+
+func TestDelete(t *testing.T) {
+	loads := 0
+	g := NewGroup("delete", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}))
+
+	g.Get("Tom")
+	if err := g.Delete("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Get("Tom"); loads != 2 {
+		t.Fatalf("deleted key Tom should be loaded again, loads %d", loads)
+	}
+	if err := g.Delete("unknown"); err != nil {
+		t.Fatalf("delete missing key should not fail, got %v", err)
+	}
+
+	peer := &fakePeer{}
+	g.RegisterPeers(&fakePicker{peer: peer})
+	if err := g.Delete("Tom"); err != nil || !reflect.DeepEqual(peer.deleted, []string{"Tom"}) {
+		t.Fatalf("delete should be sent to owning peer, got %v", peer.deleted)
+	}
+}
+
+func TestSet(t *testing.T) {
+	loads := 0
+	g := NewGroup("set", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}))
+
+	g.Set("Tom", []byte("630"))
+	g.Set("Tom", []byte("631"))
+	if view, err := g.Get("Tom"); err != nil || view.String() != "631" || loads != 0 {
+		t.Fatalf("Set should overwrite cached value, got %s", view)
+	}
+
+	peer := &fakePeer{}
+	g.RegisterPeers(&fakePicker{peer: peer})
+	if err := g.Set("Jack", []byte("589")); err != nil || peer.set["Jack"] != "589" {
+		t.Fatalf("set should be sent to owning peer, got %v", peer.set)
+	}
+}
+
+func TestStats(t *testing.T) {
+	g := NewGroup("stats", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("Jack")
+
+	expect := Stats{Hits: 1, Misses: 2, LocalLoads: 2}
+	if s := g.Stats(); s != expect {
+		t.Fatalf("expect stats %+v, but %+v got", expect, s)
+	}
+
+	g.RegisterPeers(&fakePicker{peer: &fakePeer{}})
+	if view, err := g.Get("Sam"); err != nil || view.String() != "peer:Sam" || g.Stats().PeerLoads != 1 {
+		t.Fatalf("failed to count peer load, got %+v", g.Stats())
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	g := NewGroup("context", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return []byte(key), nil
+			}
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := g.GetContext(ctx, "Tom"); err != context.DeadlineExceeded {
+		t.Fatalf("expect %v, but %v got", context.DeadlineExceeded, err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("GetContext should return promptly after deadline")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.GetContext(ctx, "Tom"); err != context.Canceled {
+		t.Fatalf("expect %v, but %v got", context.Canceled, err)
+	}
+}
+
+func TestByteViewGetter(t *testing.T) {
+	src := NewGroup("bvsource", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte("src:" + key), nil
+		}))
+	defer DestroyGroup("bvsource")
+	src.SetWithMeta("Jack", []byte("589"), map[string]string{MetaContentType: "text/plain"})
+
+	bufs := make(map[string][]byte)
+	g := NewGroup("byteview", 2<<10, ByteViewGetterFunc(
+		func(ctx context.Context, key string) (ByteView, error) {
+			if key == "Jack" {
+				return src.GetContext(ctx, key)
+			}
+			b := []byte("owned:" + key)
+			bufs[key] = b
+			return WrapByteView(b), nil
+		}))
+	defer DestroyGroup("byteview")
+
+	view, err := g.Get("Tom")
+	if err != nil || view.String() != "owned:Tom" {
+		t.Fatalf("failed to get Tom, got %q, %v", view.String(), err)
+	}
+	if &view.b[0] != &bufs["Tom"][0] {
+		t.Fatal("value returned by ByteViewGetter should not be copied")
+	}
+	if cached, ok := g.mainCache.get("Tom"); !ok || &cached.b[0] != &bufs["Tom"][0] {
+		t.Fatal("value returned by ByteViewGetter should be cached without copying")
+	}
+
+	view, err = g.Get("Jack")
+	if err != nil || view.String() != "589" || view.Meta(MetaContentType) != "text/plain" {
+		t.Fatalf("failed to get Jack from another group, got %q, %v, %v", view.String(), view.Metadata(), err)
+	}
+
+	if b, err := ByteViewGetterFunc(func(ctx context.Context, key string) (ByteView, error) {
+		return NewByteView([]byte(key)), nil
+	}).Get("Sam"); err != nil || string(b) != "Sam" {
+		t.Fatalf("ByteViewGetterFunc.Get got %q, %v", b, err)
+	}
+}
+
+func TestGetMulti(t *testing.T) {
+	g := NewGroup("multi", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+
+	g.Get("Tom")
+	values, err := g.GetMulti([]string{"Tom", "Jack", "unknown"})
+	if values["Tom"].String() != "630" || values["Jack"].String() != "589" {
+		t.Fatalf("failed to get multi values, got %v", values)
+	}
+	errs, ok := err.(KeyErrors)
+	if !ok || len(errs) != 1 || errs["unknown"] == nil {
+		t.Fatalf("expect error only for unknown, but %v got", err)
+	}
+
+	peer := &fakePeer{}
+	g.RegisterPeers(&fakePicker{peer: peer})
+	values, err = g.GetMulti([]string{"Tom", "Sam", "Bob"})
+	if err != nil || values["Tom"].String() != "630" || values["Sam"].String() != "peer:Sam" || values["Bob"].String() != "peer:Bob" {
+		t.Fatalf("failed to get multi values from peer, got %v %v", values, err)
+	}
+	if peer.multi != 1 {
+		t.Fatalf("expect 1 batched peer request, but %d got", peer.multi)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	loads := 0
+	transient := true
+	g := NewGroup("negative", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			if key == "flaky" && transient {
+				return nil, fmt.Errorf("connection refused")
+			}
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}), WithNegativeTTL(20*time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		if _, err := g.Get("unknown"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expect ErrNotFound, but %v got", err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("not found result should be cached, loads %d", loads)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if g.Get("unknown"); loads != 2 {
+		t.Fatalf("negative cache should expire, loads %d", loads)
+	}
+
+	g.Get("flaky")
+	g.Get("flaky")
+	if loads != 4 {
+		t.Fatalf("transient errors must not be cached, loads %d", loads)
+	}
+}
+
+//recordLogger 记录所有日志，用于测试
+type recordLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprintf(format, v...))
+}
+
+func TestLogger(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	l := &recordLogger{}
+	g := NewGroup("logger", 2<<10, getter, WithLogger(l))
+	g.Get("Tom")
+	g.Get("Tom")
+	if !reflect.DeepEqual(l.logs, []string{"[GoCache] hit"}) {
+		t.Fatalf("expect hit logged to custom logger, but %v got", l.logs)
+	}
+
+	l = &recordLogger{}
+	g = NewGroup("logger-silent", 2<<10, getter, WithLogger(l), WithLogLevel(LevelError))
+	g.Get("Tom")
+	g.Get("Tom")
+	if len(l.logs) != 0 {
+		t.Fatalf("hit should be silenced at LevelError, but %v got", l.logs)
+	}
+}
+
+func TestSetPeers(t *testing.T) {
+	g := NewGroup("setpeers", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	old, peer := &fakePeer{}, &fakePeer{}
+	g.RegisterPeers(&fakePicker{peer: old})
+	g.RegisterPeers(&fakePicker{peer: peer})
+	g.Delete("Tom")
+	if len(old.deleted) != 1 || len(peer.deleted) != 0 {
+		t.Fatal("RegisterPeers should not replace the registered PeerPicker")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			g.SetPeers(&fakePicker{peer: &fakePeer{}})
+		}()
+		go func(i int) {
+			defer wg.Done()
+			g.Get(fmt.Sprintf("key%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	g.SetPeers(&fakePicker{peer: peer})
+	g.Delete("Tom")
+	if len(peer.deleted) != 1 {
+		t.Fatal("SetPeers should replace the registered PeerPicker")
+	}
+}
+
+func TestHotCache(t *testing.T) {
+	g := NewGroup("hot", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }), WithHotCacheBytes(2<<10))
+	g.RegisterPeers(&fakePicker{peer: &fakePeer{}})
+
+	//热点 key 访问足够多次后一定会进入 hotCache
+	for i := 0; i < 200 && g.Stats().HotHits == 0; i++ {
+		if view, err := g.Get("Tom"); err != nil || view.String() != "peer:Tom" {
+			t.Fatalf("failed to get value of Tom from peer, got %s %v", view, err)
+		}
+	}
+	if g.Stats().HotHits == 0 {
+		t.Fatal("hot key should be served from hotCache")
+	}
+	g.Delete("Tom")
+	if _, ok := g.hotCache.get("Tom"); ok {
+		t.Fatal("Delete should remove key from hotCache")
+	}
+
+	disabled := NewGroup("hot-disabled", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }), WithHotCacheBytes(0))
+	disabled.RegisterPeers(&fakePicker{peer: &fakePeer{}})
+	for i := 0; i < 100; i++ {
+		disabled.Get("Tom")
+	}
+	if s := disabled.Stats(); s.HotHits != 0 || s.PeerLoads != 100 {
+		t.Fatalf("hotCache should be disabled, got %+v", s)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	var mu sync.Mutex
+	loads := 0
+	release := make(chan struct{})
+	g := NewGroup("refresh", 2<<10, TTLGetterFunc(
+		func(key string) ([]byte, time.Duration, error) {
+			mu.Lock()
+			loads++
+			n := loads
+			mu.Unlock()
+			if n > 1 {
+				<-release
+			}
+			return []byte(fmt.Sprintf("%s%d", key, n)), 100 * time.Millisecond, nil
+		}), WithRefreshWindow(80*time.Millisecond))
+	defer DestroyGroup("refresh")
+
+	g.Get("Tom")
+	time.Sleep(30 * time.Millisecond)
+	//进入刷新窗口，后台刷新完成前多次读取仍然返回旧值，且只有一次后台刷新
+	for i := 0; i < 10; i++ {
+		if view, err := g.Get("Tom"); err != nil || view.String() != "Tom1" {
+			t.Fatalf("expect stale value Tom1, but %s got", view)
+		}
+	}
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	n := loads
+	mu.Unlock()
+	if n != 2 {
+		t.Fatalf("expect exactly one background refresh, loads %d", n)
+	}
+	if view, _ := g.Get("Tom"); view.String() != "Tom2" {
+		t.Fatalf("expect refreshed value Tom2, but %s got", view)
+	}
+}
+
+func TestTypedGroup(t *testing.T) {
+	type score struct {
+		Name  string
+		Score int
+	}
+	g := NewTypedGroup[score](NewGroup("typed", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(fmt.Sprintf(`{"Name":%q,"Score":%s}`, key, db[key])), nil
+		})), nil)
+
+	if v, err := g.Get("Tom"); err != nil || v != (score{"Tom", 630}) {
+		t.Fatalf("failed to get typed value of Tom, got %+v %v", v, err)
+	}
+	if err := g.Set("Bob", score{"Bob", 100}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := g.Get("Bob"); err != nil || v != (score{"Bob", 100}) {
+		t.Fatalf("failed to get typed value of Bob, got %+v %v", v, err)
+	}
+}
+
+func TestNewGroupWithOptions(t *testing.T) {
+	loads := 0
+	g := NewGroupWithOptions("options", GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}),
+		WithCacheBytes(2<<10),
+		WithTTL(20*time.Millisecond),
+		WithHotCacheBytes(64),
+		WithCleanupInterval(time.Millisecond),
+		WithLogLevel(LevelSilent),
+	)
+	defer g.StopCleanup()
+
+	if g.mainCache.cacheBytes != 2<<10 || g.hotCache.cacheBytes != 64 || !g.hotCacheEnabled {
+		t.Fatal("failed to apply cache size options")
+	}
+	if view, _ := g.Get("Tom"); view.Expire().IsZero() {
+		t.Fatal("value loaded by Getter should use the default ttl")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if g.Get("Tom"); loads != 2 {
+		t.Fatalf("value should expire after default ttl, loads %d", loads)
+	}
+
+	if g := NewGroup("options-default", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return nil, nil })); g.hotCache.cacheBytes != (2<<10)/8 {
+		t.Fatalf("hotCache should default to 1/8 of cacheBytes, got %d", g.hotCache.cacheBytes)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	var g *Group = GetGroup("no-such-group")
+	if _, err := g.Get("Tom"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expect ErrGroupNotFound, but %v got", err)
+	}
+	if err := g.Delete("Tom"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("expect ErrGroupNotFound, but %v got", err)
+	}
+
+	g = NewGroup("sentinel", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	if _, err := g.Get(""); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("expect ErrKeyRequired, but %v got", err)
+	}
+	if err := g.Set("", nil); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("expect ErrKeyRequired, but %v got", err)
+	}
+	if _, err := g.getFromPeer(context.Background(), failingPeer{}, "Tom"); !errors.Is(err, ErrPeerUnavailable) {
+		t.Fatalf("expect ErrPeerUnavailable, but %v got", err)
+	}
+}
+
+//failingPeer 的所有请求都失败
+type failingPeer struct{}
+
+func (failingPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	return fmt.Errorf("connection refused")
+}
+
+func (failingPeer) GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	return fmt.Errorf("connection refused")
+}
+
+func (failingPeer) Set(in *pb.SetRequest, out *pb.SetResponse) error {
+	return fmt.Errorf("connection refused")
+}
+
+func (failingPeer) Delete(in *pb.Request) error {
+	return fmt.Errorf("connection refused")
+}
+
+func (failingPeer) Clear(in *pb.Request) error {
+	return fmt.Errorf("connection refused")
+}
+
+func (failingPeer) InvalidateTag(in *pb.TagRequest, out *pb.TagResponse) error {
+	return fmt.Errorf("connection refused")
+}
+
+func TestPeerFailurePolicy(t *testing.T) {
+	loads := 0
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	})
+
+	g := NewGroup("policy-local", 2<<10, getter)
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}})
+	if view, err := g.Get("Tom"); err != nil || view.String() != "Tom" || loads != 1 {
+		t.Fatal("PeerFallbackLocal should load locally after peer failure")
+	}
+
+	g = NewGroup("policy-failfast", 2<<10, getter, WithPeerFailurePolicy(PeerFailFast))
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}})
+	if _, err := g.Get("Tom"); !errors.Is(err, ErrPeerUnavailable) || loads != 1 {
+		t.Fatalf("PeerFailFast should return ErrPeerUnavailable without calling getter, got %v", err)
+	}
+
+	g = NewGroup("policy-trynext", 2<<10, getter, WithPeerFailurePolicy(PeerTryNext))
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}, next: []PeerGetter{&fakePeer{}}})
+	if view, err := g.Get("Tom"); err != nil || view.String() != "peer:Tom" || loads != 1 {
+		t.Fatalf("PeerTryNext should get from next peer, got %s %v", view, err)
+	}
+	g.SetPeers(&fakePicker{peer: failingPeer{}})
+	if _, err := g.Get("Jack"); !errors.Is(err, ErrPeerUnavailable) || loads != 1 {
+		t.Fatalf("PeerTryNext should fail when no more peers, got %v", err)
+	}
+}
+
+func TestGetMultiPeerFailurePolicy(t *testing.T) {
+	loads := 0
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(key), nil
+	})
+	keys := []string{"Tom", "Jack"}
+
+	g := NewGroup("multi-policy-local", 2<<10, getter)
+	defer DestroyGroup("multi-policy-local")
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}})
+	if values, err := g.GetMulti(keys); err != nil || values["Tom"].String() != "Tom" || loads != 2 {
+		t.Fatalf("PeerFallbackLocal should load locally after peer failure, got %v %v", values, err)
+	}
+
+	g = NewGroup("multi-policy-failfast", 2<<10, getter, WithPeerFailurePolicy(PeerFailFast))
+	defer DestroyGroup("multi-policy-failfast")
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}})
+	values, err := g.GetMulti(keys)
+	errs, _ := err.(KeyErrors)
+	if len(values) != 0 || len(errs) != 2 || !errors.Is(errs["Tom"], ErrPeerUnavailable) || loads != 2 {
+		t.Fatalf("PeerFailFast should return ErrPeerUnavailable without calling getter, got %v %v", values, err)
+	}
+
+	g = NewGroup("multi-policy-trynext", 2<<10, getter, WithPeerFailurePolicy(PeerTryNext))
+	defer DestroyGroup("multi-policy-trynext")
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}, next: []PeerGetter{&fakePeer{}}})
+	if values, err := g.GetMulti(keys); err != nil || values["Jack"].String() != "peer:Jack" || loads != 2 {
+		t.Fatalf("PeerTryNext should get from next peer, got %v %v", values, err)
+	}
+	g.SetPeers(&fakePicker{peer: failingPeer{}})
+	values, err = g.GetMulti([]string{"Sam"})
+	if errs, _ := err.(KeyErrors); len(values) != 0 || !errors.Is(errs["Sam"], ErrPeerUnavailable) || loads != 2 {
+		t.Fatalf("PeerTryNext should fail when no more peers, got %v %v", values, err)
+	}
+}
+
+func TestPeerRetries(t *testing.T) {
+	loads := 0
+	g := NewGroup("retries", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return []byte(key), nil
+		}), WithPeerRetries(2))
+	g.RegisterPeers(&fakePicker{peer: failingPeer{}, next: []PeerGetter{failingPeer{}, &fakePeer{}}})
+
+	//failingPeer{} 相等，第二个 failingPeer{} 被视为已经尝试过的节点而跳过
+	if view, err := g.Get("Tom"); err != nil || view.String() != "peer:Tom" || loads != 0 {
+		t.Fatalf("should retry next peer on the ring, got %s %v", view, err)
+	}
+	if s := g.Stats(); s.PeerErrors != 1 || s.PeerLoads != 1 {
+		t.Fatalf("tried peers should be skipped, got %+v", s)
+	}
+
+	g.SetPeers(&fakePicker{peer: failingPeer{}, next: []PeerGetter{&failingPeerB{}, &failingPeerB{}, &fakePeer{}}})
+	if view, err := g.Get("Jack"); err != nil || view.String() != "Jack" || loads != 1 {
+		t.Fatalf("should fall back to local after retries exhausted, got %s %v", view, err)
+	}
+}
+
+//failingPeerB 与 failingPeer 相同，但每个实例都是不同的节点
+type failingPeerB struct {
+	failingPeer
+}
+
+func TestGroupAccessors(t *testing.T) {
+	g := NewGroup("accessors", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("630"), nil }))
+	if g.Name() != "accessors" || g.MaxBytes() != 2<<10 || g.UsedBytes() != 0 {
+		t.Fatal("failed to read group name and size")
+	}
+	g.Get("Tom")
+	if size := LRU_Cache.EntryBytes("Tom", ByteView{b: []byte("630")}); g.UsedBytes() != size {
+		t.Fatalf("expect %d used bytes, but %d got", size, g.UsedBytes())
+	}
+}
+
+func TestOnEvicted(t *testing.T) {
+	var g *Group
+	evicted := make(map[string]EvictReason)
+	g = NewGroup("evicted", twoEntries, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("1234"), nil }),
+		WithHotCacheBytes(0),
+		WithOnEvicted(func(key string, value ByteView, reason EvictReason) {
+			evicted[key] = reason
+			//回调中访问缓存不会死锁
+			g.UsedBytes()
+		}))
+
+	g.Get("k1")
+	g.Get("k2")
+	g.Get("k3")
+	g.SetWithTTL("k4", []byte("1234"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	g.Get("k4")
+	g.Delete("k3")
+
+	expect := map[string]EvictReason{"k1": EvictCapacity, "k2": EvictCapacity, "k4": EvictExpired, "k3": EvictDeleted}
+	if !reflect.DeepEqual(evicted, expect) {
+		t.Fatalf("expect evicted %v, but %v got", expect, evicted)
+	}
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte("1234"), nil })
+	for _, tt := range []struct {
+		policy EvictionPolicy
+		cached string //k1 被反复访问，写满后 LRU 保留最近写入的 k2，LFU 保留访问次数多的 k1
+	}{
+		{PolicyLRU, "k2"},
+		{PolicyLFU, "k1"},
+		{PolicyTinyLFU, "k1"},
+		{PolicyFIFO, "k2"},   //忽略访问，淘汰最早写入的 k1
+		{PolicyRandom, "k3"}, //刚写入的记录不会被淘汰
+		{PolicyARC, "k1"},    //访问过多次的 k1 在 T2 中，从 T1 淘汰 k2
+		{Policy2Q, "k2"},     //k1 的访问都发生在 in 队列中，仍然按写入顺序被淘汰
+	} {
+		name := fmt.Sprintf("policy-%d", tt.policy)
+		g := NewGroup(name, twoEntries, getter, WithHotCacheBytes(0), WithEvictionPolicy(tt.policy))
+		g.Get("k1")
+		g.Get("k1")
+		g.Get("k1")
+		g.Get("k2")
+		g.Get("k3")
+		if _, ok := g.mainCache.get(tt.cached); !ok {
+			t.Fatalf("%s: %s should be cached", name, tt.cached)
+		}
+		DestroyGroup(name)
+	}
+}
+
+func TestWithTwoQueue(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte("1234"), nil })
+	g := NewGroup("two-queue", twoEntries, getter, WithHotCacheBytes(0), WithTwoQueue(0.5, 1))
+	defer DestroyGroup("two-queue")
+	g.Get("k1")
+	g.Get("k2")
+	g.Get("k3")
+	//k1 被挤出 in 后留在 out 中，再次加载时进入 main，不会被接下来的 k4 挤出
+	g.Get("k1")
+	g.Get("k4")
+	if _, ok := g.mainCache.shardFor("k1").store.(*LRU_Cache.TwoQueue); !ok {
+		t.Fatal("WithTwoQueue should select the 2Q policy")
+	}
+	if _, ok := g.mainCache.get("k1"); !ok {
+		t.Fatal("k1 should be promoted to the main queue")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte("session:" + key), nil })
+	for _, policy := range []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyTinyLFU, PolicyFIFO, PolicyRandom, PolicyARC, Policy2Q} {
+		name := fmt.Sprintf("touch-%d", policy)
+		g := NewGroup(name, 2<<10, getter, WithEvictionPolicy(policy))
+		if g.Touch("Tom", time.Minute) {
+			t.Fatalf("%s: Touch should fail for missing key", name)
+		}
+		g.SetWithTTL("Tom", []byte("630"), 50*time.Millisecond)
+		if !g.Touch("Tom", time.Hour) {
+			t.Fatalf("%s: Touch should succeed for cached key", name)
+		}
+		time.Sleep(100 * time.Millisecond)
+		view, ok := g.mainCache.get("Tom")
+		if !ok || view.String() != "630" || time.Until(view.Expire()) < 50*time.Minute {
+			t.Fatalf("%s: Touch should extend expiry, got %q %v", name, view.String(), view.Expire())
+		}
+		if !g.Touch("Tom", 0) {
+			t.Fatal("Touch with 0 ttl should succeed")
+		}
+		if view, _ := g.mainCache.get("Tom"); !view.Expire().IsZero() || g.mainCache.shardFor("Tom").ttls != 0 {
+			t.Fatalf("%s: Touch with 0 ttl should remove expiry", name)
+		}
+
+		g.SetWithTTL("Sam", []byte("567"), time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		if g.Touch("Sam", time.Hour) {
+			t.Fatalf("%s: Touch should fail for expired key", name)
+		}
+		DestroyGroup(name)
+	}
+
+	g := NewGroup("touch-pinned", 2<<10, getter)
+	defer DestroyGroup("touch-pinned")
+	g.Pin("Jack")
+	g.SetWithTTL("Jack", []byte("589"), 50*time.Millisecond)
+	if !g.Touch("Jack", time.Hour) {
+		t.Fatal("Touch should succeed for pinned key")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if view, ok := g.mainCache.get("Jack"); !ok || view.String() != "589" {
+		t.Fatal("Touch should extend expiry of pinned key")
+	}
+}
+
+func TestGetOrStore(t *testing.T) {
+	g := NewGroup("getorstore", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return nil, ErrNotFound }))
+	defer DestroyGroup("getorstore")
+
+	var (
+		wg     sync.WaitGroup
+		stored int32
+		values = make([]string, 50)
+	)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual, loaded := g.GetOrStore("Tom", []byte(fmt.Sprint(i)))
+			if !loaded {
+				atomic.AddInt32(&stored, 1)
+			}
+			values[i] = actual.String()
+		}(i)
+	}
+	wg.Wait()
+	if stored != 1 {
+		t.Fatalf("expect exactly one store, got %d", stored)
+	}
+	for _, v := range values {
+		if v != values[0] {
+			t.Fatalf("all callers should see the same value, got %q and %q", values[0], v)
+		}
+	}
+	if view, err := g.Get("Tom"); err != nil || view.String() != values[0] {
+		t.Fatalf("stored value should be cached, got %q, %v", view.String(), err)
+	}
+
+	g.SetWithTTL("Sam", []byte("567"), time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if actual, loaded := g.GetOrStore("Sam", []byte("568")); loaded || actual.String() != "568" {
+		t.Fatalf("expired value should be replaced, got %q, %v", actual.String(), loaded)
+	}
+
+	c := NewGroup("getorstore-compressed", 2<<10, g.getter, WithValueCompression(1, nil))
+	defer DestroyGroup("getorstore-compressed")
+	c.GetOrStore("Jack", []byte("589589589589"))
+	if actual, loaded := c.GetOrStore("Jack", []byte("x")); !loaded || actual.String() != "589589589589" {
+		t.Fatalf("compressed value should be returned decompressed, got %q, %v", actual.String(), loaded)
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	g := NewGroup("jitter", 0, getter, WithTTL(time.Hour), WithTTLJitter(0.5))
+	defer DestroyGroup("jitter")
+	plain := NewGroup("jitter-off", 0, getter, WithTTL(time.Hour))
+	defer DestroyGroup("jitter-off")
+
+	var min, max time.Duration
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i)
+		g.Get(key)
+		plain.Get(key)
+		view, _ := g.mainCache.get(key)
+		d := time.Until(view.Expire())
+		if d < 30*time.Minute-time.Second || d > 90*time.Minute {
+			t.Fatalf("jittered ttl %v out of bounds", d)
+		}
+		if i == 0 || d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+		if view, _ := plain.mainCache.get(key); time.Until(view.Expire()) < time.Hour-time.Second {
+			t.Fatal("ttl should not be jittered by default")
+		}
+	}
+	if max-min < 10*time.Minute {
+		t.Fatalf("expect expiries to spread out, got [%v, %v]", min, max)
+	}
+}
+
+type keySet map[string]bool
+
+func (s keySet) MayContain(key string) bool { return s[key] }
+
+func TestMembershipFilter(t *testing.T) {
+	var calls int32
+	g := NewGroup("filter", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, ErrNotFound
+		}), WithNegativeTTL(time.Minute))
+	defer DestroyGroup("filter")
+	g.SetMembershipFilter(keySet{"Tom": true, "Unknown": true})
+
+	if view, err := g.Get("Tom"); err != nil || view.String() != db["Tom"] {
+		t.Fatalf("failed to get Tom, got %q, %v", view.String(), err)
+	}
+	if _, err := g.Get("Jack"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expect ErrNotFound for filtered key, got %v", err)
+	}
+	if _, ok := g.mainCache.peek("Jack"); ok || calls != 1 {
+		t.Fatalf("filtered key should not call getter or be cached, got %d calls", calls)
+	}
+	//过滤器的假阳性仍然调用 getter
+	if _, err := g.Get("Unknown"); !errors.Is(err, ErrNotFound) || calls != 2 {
+		t.Fatalf("expect getter to be called for possible key, got %d calls, %v", calls, err)
+	}
+
+	g.SetMembershipFilter(nil)
+	if view, err := g.Get("Jack"); err != nil || view.String() != db["Jack"] || calls != 3 {
+		t.Fatalf("failed to get Jack without filter, got %q, %v", view.String(), err)
+	}
+}
+
+func TestShards(t *testing.T) {
+	for _, tt := range []struct {
+		cacheBytes int64
+		nshards    int
+		expect     int
+	}{
+		{0, 0, DefaultShards},
+		{64 << 20, 0, DefaultShards},
+		{64 << 20, 16, 16},
+		{4 * minShardBytes, 0, 4}, //容量较小时减少分片数
+		{12, 8, 1},
+	} {
+		c := &cache{cacheBytes: tt.cacheBytes, nshards: tt.nshards}
+		c.init()
+		var total int64
+		for _, s := range c.shards {
+			total += s.cacheBytes
+		}
+		if len(c.shards) != tt.expect || total != tt.cacheBytes {
+			t.Fatalf("cache{%d, %d}: expect %d shards, got %d shards with %d bytes",
+				tt.cacheBytes, tt.nshards, tt.expect, len(c.shards), total)
+		}
+	}
+}
+
+//BenchmarkCacheParallel 比较单个分片与默认分片数在大量协程并发读写时的吞吐量
+//go test -bench CacheParallel -cpu 8
+func BenchmarkCacheParallel(b *testing.B) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	value := ByteView{b: []byte("value")}
+	for _, nshards := range []int{1, DefaultShards} {
+		b.Run(fmt.Sprintf("shards-%d", nshards), func(b *testing.B) {
+			c := &cache{cacheBytes: 64 << 20, nshards: nshards}
+			c.init()
+			for _, key := range keys {
+				c.add(key, value)
+			}
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(p *testing.PB) {
+				i := rand.Intn(len(keys))
+				for p.Next() {
+					key := keys[i%len(keys)]
+					if i%10 == 0 {
+						c.add(key, value)
+					} else {
+						c.get(key)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestPeek(t *testing.T) {
+	g := NewGroup("peek", twoEntries, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("1234"), nil }), WithHotCacheBytes(0))
+	defer DestroyGroup("peek")
+
+	if _, ok := g.Peek("k1"); ok {
+		t.Fatal("Peek should not load missing key")
+	}
+	g.Get("k1")
+	g.Get("k2")
+	before := g.Stats()
+	//Peek 不会把 k1 变成最近访问，写入 k3 时仍然淘汰 k1
+	if v, ok := g.Peek("k1"); !ok || v.String() != "1234" {
+		t.Fatal("failed to peek k1")
+	}
+	if g.Stats() != before {
+		t.Fatal("Peek should not change stats")
+	}
+	g.Get("k3")
+	if _, ok := g.Peek("k1"); ok {
+		t.Fatal("Peek should not update recency")
+	}
+
+	g.SetWithTTL("k2", []byte("1234"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := g.Peek("k2"); ok {
+		t.Fatal("expired key should be absent")
+	}
+}
+
+func TestResize(t *testing.T) {
+	var evicted []string
+	size := LRU_Cache.EntryBytes("k00", ByteView{b: []byte("1234")})
+	g := NewGroup("resize", 100*size, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("1234"), nil }),
+		WithOnEvicted(func(key string, value ByteView, reason EvictReason) {
+			if reason == EvictCapacity {
+				evicted = append(evicted, key)
+			}
+		}))
+	defer DestroyGroup("resize")
+
+	for i := 0; i < 100; i++ {
+		g.Get(fmt.Sprintf("k%02d", i))
+	}
+	if g.Len() != 100 || g.UsedBytes() != 100*size {
+		t.Fatalf("expect 100 entries, got %d entries and %d bytes", g.Len(), g.UsedBytes())
+	}
+
+	g.Resize(40 * size)
+	if g.MaxBytes() != 40*size || g.UsedBytes() > g.MaxBytes() {
+		t.Fatalf("UsedBytes %d should not exceed MaxBytes %d", g.UsedBytes(), g.MaxBytes())
+	}
+	if g.Len() != 40 || len(evicted) != 60 {
+		t.Fatalf("expect 40 entries and 60 OnEvicted calls, got %d and %d", g.Len(), len(evicted))
+	}
+
+	//扩容后不再淘汰
+	g.Resize(0)
+	g.Get("new")
+	if g.Len() != 41 || len(evicted) != 60 {
+		t.Fatalf("unexpected Len %d after growing", g.Len())
+	}
+}
+
+func TestClear(t *testing.T) {
+	var cleared int
+	g := NewGroup("clear", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }),
+		WithOnEvicted(func(key string, value ByteView, reason EvictReason) {
+			if reason == EvictDeleted {
+				cleared++
+			}
+		}))
+	defer DestroyGroup("clear")
+	g.Get("Tom")
+	g.Get("Jack")
+
+	g.Clear()
+	if g.Len() != 0 || g.UsedBytes() != 0 || cleared != 2 {
+		t.Fatalf("expect empty cache and 2 OnEvicted calls, got %d entries, %d bytes, %d calls", g.Len(), g.UsedBytes(), cleared)
+	}
+	if v, err := g.Get("Tom"); err != nil || v.String() != "Tom" {
+		t.Fatal("group should still work after Clear")
+	}
+
+	peer := &fakePeer{}
+	g.SetPeers(&fakePicker{peer: peer, next: []PeerGetter{failingPeer{}}})
+	err := g.ClearCluster()
+	if !errors.Is(err, ErrPeerUnavailable) || peer.cleared != 1 || g.Len() != 0 {
+		t.Fatalf("ClearCluster should clear local cache and every reachable peer, got %v", err)
+	}
+}
+
+func TestRange(t *testing.T) {
+	g := NewGroup("range", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("range")
+	g.Get("Tom")
+	g.Get("Jack")
+	g.SetWithTTL("Sam", []byte("567"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	got := make(map[string]string)
+	g.Range(func(key string, value ByteView) bool {
+		got[key] = value.String()
+		//fn 在锁外调用，可以访问 Group
+		g.Peek(key)
+		return true
+	})
+	if expect := map[string]string{"Tom": "Tom", "Jack": "Jack"}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, but %v got", expect, got)
+	}
+
+	n := 0
+	g.Range(func(key string, value ByteView) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("Range should stop when fn returns false, called %d times", n)
+	}
+}
+
+func TestPoolRing(t *testing.T) {
+	p := NewHTTPPool("http://a", WithPoolRing(func() consistenthash.Ring {
+		return consistenthash.NewRendezvous(nil)
+	}))
+	p.Set("http://a", "http://b", "http://c")
+	if _, ok := p.peers.(*consistenthash.Rendezvous); !ok {
+		t.Fatalf("expect Rendezvous ring, got %T", p.peers)
+	}
+	//本节点被跳过，只剩两个远程节点
+	if peers := p.PickPeers("Tom", 3); len(peers) != 2 {
+		t.Fatalf("expect 2 remote peers, got %d", len(peers))
+	}
+}
+
+//streamPeer 通过 GetStream 分两块返回 "stream:<key>"，fail 为 "before" 或 "after" 时在写入之前或写入第一块之后失败
+type streamPeer struct {
+	fakePeer
+	fail string
+}
+
+func (p *streamPeer) GetStream(ctx context.Context, in *pb.Request, w io.Writer) error {
+	if p.fail == "before" {
+		return errors.New("stream failed")
+	}
+	w.Write([]byte("stream:"))
+	if p.fail == "after" {
+		return errors.New("stream failed")
+	}
+	w.Write([]byte(in.GetKey()))
+	return nil
+}
+
+func TestGetStream(t *testing.T) {
+	loads := 0
+	g := NewGroup("stream", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("local:" + key), nil
+	}))
+	defer DestroyGroup("stream")
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		buf.Reset()
+		if err := g.GetStream("k", &buf); err != nil || buf.String() != "local:k" {
+			t.Fatalf("GetStream = %q, %v", buf.String(), err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("second GetStream should hit the cache, loaded %d times", loads)
+	}
+
+	peer := &streamPeer{}
+	g.RegisterPeers(&fakePicker{peer: peer})
+	buf.Reset()
+	if err := g.GetStream("remote", &buf); err != nil || buf.String() != "stream:remote" {
+		t.Fatalf("GetStream from peer = %q, %v", buf.String(), err)
+	}
+	if _, ok := g.Peek("remote"); ok {
+		t.Fatal("streamed values should not be cached locally")
+	}
+	if s := g.Stats(); s.PeerLoads != 1 {
+		t.Fatalf("PeerLoads = %d, want 1", s.PeerLoads)
+	}
+
+	//写入之前失败时按 Get 的流程重新加载
+	peer.fail = "before"
+	buf.Reset()
+	if err := g.GetStream("fallback", &buf); err != nil || buf.String() != "peer:fallback" {
+		t.Fatalf("GetStream should fall back to Get, got %q, %v", buf.String(), err)
+	}
+
+	//写入部分数据后失败时返回错误
+	peer.fail = "after"
+	buf.Reset()
+	if err := g.GetStream("partial", &buf); !errors.Is(err, ErrPeerUnavailable) {
+		t.Fatalf("partial stream should fail with ErrPeerUnavailable, got %v", err)
+	}
+
+	if err := g.GetStream("", &buf); !errors.Is(err, ErrKeyRequired) {
+		t.Fatalf("empty key = %v, want ErrKeyRequired", err)
+	}
+}
+
+func TestGetContextAbandonsSharedLoad(t *testing.T) {
+	release := make(chan struct{})
+	var loads int32
+	g := NewGroup("abandon", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("v:" + key), nil
+	}))
+	defer DestroyGroup("abandon")
+
+	done := make(chan string)
+	go func() {
+		v, _ := g.Get("k")
+		done <- v.String()
+	}()
+	//等待第一个调用者开始加载
+	for atomic.LoadInt32(&loads) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := g.GetContext(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Fatalf("GetContext should return once ctx is done, got %v after %v", err, time.Since(start))
+	}
+
+	close(release)
+	if v := <-done; v != "v:k" || atomic.LoadInt32(&loads) != 1 {
+		t.Fatalf("shared load should finish for the other caller, got %q after %d loads", v, loads)
+	}
+}
+
+func TestByteViewReader(t *testing.T) {
+	v := ByteView{b: []byte("hello")}
+	got, err := io.ReadAll(v.Reader())
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("Reader = %q, %v", got, err)
+	}
+	var buf bytes.Buffer
+	if n, err := v.WriteTo(&buf); err != nil || n != 5 || buf.String() != "hello" {
+		t.Fatalf("WriteTo = %d, %v, %q", n, err, buf.String())
+	}
+	//io.Copy 使用 bytes.Reader 的 WriteTo
+	buf.Reset()
+	if n, err := io.Copy(&buf, v.Reader()); err != nil || n != 5 || buf.String() != "hello" {
+		t.Fatalf("io.Copy = %d, %v, %q", n, err, buf.String())
+	}
+}
+
+func TestNewByteView(t *testing.T) {
+	b := []byte("hello")
+	v := NewByteView(b)
+	b[0] = 'j'
+	if v.String() != "hello" {
+		t.Fatalf("NewByteView should copy its input, got %q", v.String())
+	}
+	if !v.Equal(NewByteView([]byte("hello"))) || v.Equal(NewByteView(b)) {
+		t.Fatal("Equal should compare the bytes")
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	var calls int32
+	g := NewGroup("maxValueSize", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		return []byte(key), nil
+	}), WithMaxValueSize(4))
+	defer DestroyGroup("maxValueSize")
+
+	if err := g.Set("k", []byte("12345")); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Set = %v, want ErrValueTooLarge", err)
+	}
+	if err := g.SetWithTTL("k", []byte("12345"), time.Minute); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("SetWithTTL = %v, want ErrValueTooLarge", err)
+	}
+	if err := g.Set("k", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+
+	//getter 返回的大值仍然交给调用者，但不缓存
+	for i := 0; i < 2; i++ {
+		if v, err := g.Get("large"); err != nil || v.String() != "large" {
+			t.Fatalf("Get = %q, %v", v.String(), err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("getter called %d times, oversized values should not be cached", calls)
+	}
+	if _, ok := g.Peek("large"); ok {
+		t.Fatal("oversized value should not be cached")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	src := NewGroup("snapshotSrc", 2<<10, getter)
+	defer DestroyGroup("snapshotSrc")
+	src.Set("Tom", []byte("630"))
+	src.SetWithTTL("Jack", []byte("589"), time.Minute)
+	src.SetWithTTL("Sam", []byte("567"), 20*time.Millisecond)
+	var buf bytes.Buffer
+	if err := src.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	//Sam 在恢复前已经过期
+	time.Sleep(30 * time.Millisecond)
+	dst := NewGroup("snapshotDst", 2<<10, getter)
+	defer DestroyGroup("snapshotDst")
+	if err := dst.LoadSnapshot(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	dst.Range(func(key string, value ByteView) bool {
+		got[key] = value.String()
+		return true
+	})
+	if expect := map[string]string{"Tom": "630", "Jack": "589"}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, but %v got", expect, got)
+	}
+	if v, _ := dst.Peek("Jack"); time.Until(v.Expire()) <= 0 || time.Until(v.Expire()) > time.Minute {
+		t.Fatalf("Jack should keep its remaining ttl, expires at %v", v.Expire())
+	}
+	if v, _ := dst.Peek("Tom"); !v.Expire().IsZero() {
+		t.Fatalf("Tom should never expire, expires at %v", v.Expire())
+	}
+
+	if err := dst.LoadSnapshot(bytes.NewReader(data[:len(data)-1])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated snapshot: %v, want io.ErrUnexpectedEOF", err)
+	}
+	if err := dst.LoadSnapshot(strings.NewReader("not a snapshot")); err == nil {
+		t.Fatal("expect an error for invalid snapshot")
+	}
+}
+
+func TestDiskTier(t *testing.T) {
+	var calls int32
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		return []byte(key + "-value"), nil
+	})
+	dir := t.TempDir()
+	size := LRU_Cache.EntryBytes("k00", ByteView{b: []byte("k00-value")})
+	//内存中只能放下 2 条记录，磁盘中能放下 3 条
+	g := NewGroup("diskTier", 2*size, getter, WithDiskTier(dir, 3*int64(len("k00")+len("k00-value"))))
+	defer DestroyGroup("diskTier")
+
+	for _, key := range []string{"k00", "k01", "k02", "k03", "k04"} {
+		g.Get(key)
+	}
+	if g.UsedBytes() > 2*size || g.Len() != 5 {
+		t.Fatalf("UsedBytes = %d, Len = %d, want at most %d bytes in memory and 5 entries", g.UsedBytes(), g.Len(), 2*size)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if len(files) != 3 {
+		t.Fatalf("expect 3 entries on disk, got %v", files)
+	}
+
+	//k00 被挤出内存后保存在磁盘中，命中时不再调用 getter
+	if v, err := g.Get("k00"); err != nil || v.String() != "k00-value" || calls != 5 {
+		t.Fatalf("Get from disk = %q, %v after %d getter calls", v.String(), err, calls)
+	}
+
+	//磁盘写满后按 LRU 淘汰，k01 已经被淘汰
+	for _, key := range []string{"k05", "k06"} {
+		g.Get(key)
+	}
+	atomic.StoreInt32(&calls, 0)
+	g.Get("k01")
+	if calls != 1 {
+		t.Fatalf("k01 should be evicted from disk, getter called %d times", calls)
+	}
+
+	g.Clear()
+	if files, _ := filepath.Glob(filepath.Join(dir, "*", "*")); len(files) != 0 || g.Len() != 0 {
+		t.Fatalf("Clear should remove disk entries, got %v", files)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	g := NewGroup("invalidate", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("invalidate")
+	a, b := &fakePeer{}, &fakePeer{}
+	g.RegisterPeers(&fakePicker{peer: a, next: []PeerGetter{b, failingPeer{}}})
+	g.populateCache("Tom", ByteView{b: []byte("630")})
+	g.hotCache.add("Jack", ByteView{b: []byte("589")})
+
+	err := g.InvalidateMany([]string{"Tom", "Jack"})
+	if !errors.Is(err, ErrPeerUnavailable) || !strings.Contains(err.Error(), "1 of 3") {
+		t.Fatalf("expect 1 of 3 peers to fail, got %v", err)
+	}
+	if _, ok := g.Peek("Tom"); ok {
+		t.Fatal("Tom should be deleted locally")
+	}
+	if _, ok := g.Peek("Jack"); ok {
+		t.Fatal("Jack should be deleted from hotCache")
+	}
+	//每个能连接的节点都收到了所有 key，而不只是负责 key 的节点
+	for _, peer := range []*fakePeer{a, b} {
+		if !reflect.DeepEqual(peer.deleted, []string{"Tom", "Jack"}) {
+			t.Fatalf("peer deleted %v, want every key", peer.deleted)
+		}
+	}
+	if err := g.Invalidate(""); err != ErrKeyRequired {
+		t.Fatalf("expect ErrKeyRequired, got %v", err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	g := NewGroup("tags", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("tags")
+	g.SetWithTags("user:1:profile", []byte("p"), "user:1")
+	g.SetWithTags("user:1:feed", []byte("f"), "user:1", "feeds")
+	g.SetWithTags("user:2:feed", []byte("f"), "user:2", "feeds")
+	g.Set("other", []byte("o"))
+	if keys := g.tags.keysOf("feeds"); len(keys) != 2 {
+		t.Fatalf("feeds = %v, want 2 keys", keys)
+	}
+
+	//删除或重新写入后标签随之更新
+	g.Delete("user:2:feed")
+	g.Set("user:1:profile", []byte("p2"))
+	if keys := g.tags.keysOf("feeds"); !reflect.DeepEqual(keys, []string{"user:1:feed"}) {
+		t.Fatalf("feeds = %v after delete", keys)
+	}
+	if keys := g.tags.keysOf("user:1"); !reflect.DeepEqual(keys, []string{"user:1:feed"}) {
+		t.Fatalf("user:1 = %v after overwrite", keys)
+	}
+
+	peer := &fakePeer{tagged: map[string][]string{"user:1": {"remote"}}}
+	g.RegisterPeers(&fakePicker{peer: peer})
+	g.SetWithTags("user:1:settings", []byte("s"), "user:1")
+	if err := g.InvalidateByTag("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"user:1:feed", "user:1:settings"} {
+		if _, ok := g.Peek(key); ok {
+			t.Fatalf("%s should be invalidated", key)
+		}
+	}
+	if _, ok := g.Peek("other"); !ok {
+		t.Fatal("untagged key should stay")
+	}
+	//远程节点删除的 key 再广播给所有节点，清除 hotCache 中的副本
+	sort.Strings(peer.deleted)
+	if !reflect.DeepEqual(peer.tags, []string{"user:1"}) || !reflect.DeepEqual(peer.deleted, []string{"remote", "user:1:feed", "user:1:settings"}) {
+		t.Fatalf("peer got tags %v and deletes %v", peer.tags, peer.deleted)
+	}
+	if len(g.tags.keys) != 0 {
+		t.Fatalf("tag index should be empty, got %v", g.tags.keys)
+	}
+}
+
+//slowPeer 的 Get 一直阻塞到 ctx 结束，exited 在返回时关闭
+type slowPeer struct {
+	failingPeer
+	exited chan struct{}
+}
+
+func (p *slowPeer) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	defer close(p.exited)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHedging(t *testing.T) {
+	g := NewGroup("hedging", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("getter should not be called")
+	}), WithHedging(10*time.Millisecond), WithHotCacheBytes(0))
+	defer DestroyGroup("hedging")
+	slow := &slowPeer{exited: make(chan struct{})}
+	g.RegisterPeers(&fakePicker{peer: slow, next: []PeerGetter{&fakePeer{}}})
+
+	start := time.Now()
+	v, err := g.Get("Tom")
+	if err != nil || v.String() != "peer:Tom" {
+		t.Fatalf("Get = %q, %v", v.String(), err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("hedged request returned after %v", elapsed)
+	}
+	//输掉的请求被取消，协程退出
+	select {
+	case <-slow.exited:
+	case <-time.After(time.Second):
+		t.Fatal("slow request was not cancelled")
+	}
+	if s := g.Stats(); s.PeerLoads != 1 || s.PeerErrors != 0 {
+		t.Fatalf("hedged load should be counted once, got %+v", s)
+	}
+}
+
+func TestSetWithMeta(t *testing.T) {
+	peer := &fakePeer{}
+	g := NewGroup("meta", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("meta")
+	meta := map[string]string{MetaContentType: "text/plain", MetaETag: `"v1"`}
+	if err := g.SetWithMeta("Tom", []byte("630"), meta); err != nil {
+		t.Fatal(err)
+	}
+	//写入后修改 meta 不影响缓存中的元数据
+	meta[MetaETag] = `"v2"`
+	v, err := g.Get("Tom")
+	if err != nil || v.Meta(MetaContentType) != "text/plain" || v.Meta(MetaETag) != `"v1"` {
+		t.Fatalf("Get = %v, %v", v.Metadata(), err)
+	}
+	v.Metadata()[MetaETag] = "changed"
+	if v.Meta(MetaETag) != `"v1"` {
+		t.Fatal("Metadata should return a copy")
+	}
+	//再次写入时元数据被替换
+	g.Set("Tom", []byte("631"))
+	if v, _ := g.Get("Tom"); v.Metadata() != nil {
+		t.Fatalf("Set should clear metadata, got %v", v.Metadata())
+	}
+
+	g.SetWithMeta("Jack", []byte("589"), map[string]string{"x": "1"})
+	var buf bytes.Buffer
+	if err := g.SaveSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewGroup("metaDst", 2<<10, GetterFunc(func(key string) ([]byte, error) { return nil, ErrNotFound }))
+	defer DestroyGroup("metaDst")
+	if err := dst.LoadSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := dst.Peek("Jack"); !ok || !reflect.DeepEqual(v.Metadata(), map[string]string{"x": "1"}) {
+		t.Fatalf("snapshot should keep metadata, got %v", v.Metadata())
+	}
+	//旧格式的快照没有元数据
+	if err := dst.LoadSnapshot(strings.NewReader("GCSNAP01\x00\x03ka\x01x\x00\x00")); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := dst.Peek("ka"); !ok || v.String() != "x" || v.Metadata() != nil {
+		t.Fatalf("v1 snapshot: %q %v", v.String(), ok)
+	}
+
+	//元数据随 Set 请求写入远程节点
+	g.RegisterPeers(&fakePicker{peer: peer})
+	if err := g.SetWithMeta("Sam", []byte("567"), map[string]string{"x": "2"}); err != nil || peer.meta["Sam"]["x"] != "2" {
+		t.Fatalf("peer meta = %v, %v", peer.meta, err)
+	}
+}
+
+func TestExpvar(t *testing.T) {
+	g := NewGroup("expvar", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }), WithExpvar())
+	g.Get("a")
+	g.Get("a")
+	var got map[string]int64
+	if err := json.Unmarshal([]byte(expvar.Get("gocache").(*expvar.Map).Get("expvar").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["hits"] != 1 || got["misses"] != 1 || got["local_loads"] != 1 || got["entries"] != 1 || got["bytes"] != g.UsedBytes() {
+		t.Fatalf("expvar = %v", got)
+	}
+	DestroyGroup("expvar")
+	if v := expvar.Get("gocache").(*expvar.Map).Get("expvar"); v != nil {
+		t.Fatalf("destroyed group should be unpublished, got %v", v)
+	}
+	//没有设置 WithExpvar 的 group 不发布
+	NewGroup("noExpvar", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("noExpvar")
+	if v := expvar.Get("gocache").(*expvar.Map).Get("noExpvar"); v != nil {
+		t.Fatalf("noExpvar should not be published, got %v", v)
+	}
+}
+
+func TestTopKeys(t *testing.T) {
+	g := NewGroup("topKeys", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }), WithTopKeys(2, 0))
+	defer DestroyGroup("topKeys")
+	for key, n := range map[string]int{"a": 5, "b": 3, "c": 1, "d": 2} {
+		//第一次 Get 未命中，不计入
+		for i := 0; i <= n; i++ {
+			g.Get(key)
+		}
+	}
+	if got, expect := g.TopKeys(10), []KeyStat{{"a", 5}, {"b", 3}}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, but %v got", expect, got)
+	}
+	if got := g.TopKeys(1); len(got) != 1 || got[0].Key != "a" {
+		t.Fatalf("TopKeys(1) = %v", got)
+	}
+	g.ResetTopKeys()
+	if got := g.TopKeys(10); len(got) != 0 {
+		t.Fatalf("TopKeys after reset = %v", got)
+	}
+	g.Get("c")
+	if got := g.TopKeys(10); !reflect.DeepEqual(got, []KeyStat{{"c", 1}}) {
+		t.Fatalf("TopKeys = %v", got)
+	}
+
+	plain := NewGroup("noTopKeys", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("noTopKeys")
+	plain.Get("a")
+	if got := plain.TopKeys(10); got != nil {
+		t.Fatalf("TopKeys without WithTopKeys = %v", got)
+	}
+}
+
+func TestPin(t *testing.T) {
+	var evicted []string
+	g := NewGroup("pin", 10*twoEntries, GetterFunc(func(key string) ([]byte, error) { return []byte("1234"), nil }),
+		WithOnEvicted(func(key string, value ByteView, reason EvictReason) {
+			evicted = append(evicted, key+":"+reason.String())
+		}))
+	defer DestroyGroup("pin")
+	if err := g.Pin("c0"); err != nil {
+		t.Fatal(err)
+	}
+	g.Set("c0", []byte("1234"))
+	g.Set("c1", []byte("1234"))
+	//已经缓存的 key 同样可以固定
+	if err := g.Pin("c1"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		g.Set(fmt.Sprintf("k%d", i), []byte("1234"))
+	}
+	for _, key := range []string{"c0", "c1"} {
+		if _, ok := g.Peek(key); !ok {
+			t.Fatalf("pinned %s should not be evicted", key)
+		}
+	}
+	if used := g.UsedBytes(); used > 10*twoEntries {
+		t.Fatalf("UsedBytes = %d, over capacity %d", used, 10*twoEntries)
+	}
+
+	//固定的记录不会被后台清理删除，过期后重新加载
+	g.SetWithTTL("c0", []byte("old"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	g.mainCache.cleanup()
+	if v, err := g.Get("c0"); err != nil || v.String() != "1234" {
+		t.Fatalf("expired pinned key = %q, %v", v.String(), err)
+	}
+	if _, ok := g.Peek("c0"); !ok {
+		t.Fatal("reloaded c0 should stay pinned")
+	}
+
+	evicted = nil
+	g.Delete("c1")
+	if _, ok := g.Peek("c1"); ok || !reflect.DeepEqual(evicted, []string{"c1:deleted"}) {
+		t.Fatalf("Delete should remove pinned c1, evicted %v", evicted)
+	}
+	g.Unpin("c0")
+	for i := 100; i < 200; i++ {
+		g.Set(fmt.Sprintf("k%d", i), []byte("1234"))
+	}
+	if _, ok := g.Peek("c0"); ok {
+		t.Fatal("unpinned c0 should be evicted")
+	}
+
+	//固定的记录不能超过容量的 90%
+	g.Set("big", make([]byte, 10*twoEntries*9/10))
+	if err := g.Pin("big"); !errors.Is(err, ErrPinLimit) {
+		t.Fatalf("Pin big = %v, want ErrPinLimit", err)
+	}
+}
+
+func TestStaleOnError(t *testing.T) {
+	var fail int32
+	g := NewGroup("stale", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "gone" && atomic.LoadInt32(&fail) == 1 {
+			return nil, ErrNotFound
+		}
+		if atomic.LoadInt32(&fail) == 1 {
+			return nil, errors.New("db down")
+		}
+		return []byte("v:" + key), nil
+	}), WithTTL(10*time.Millisecond), WithStaleOnError(time.Minute))
+	defer DestroyGroup("stale")
+	g.Get("Tom")
+	g.Get("gone")
+	time.Sleep(20 * time.Millisecond)
+	g.mainCache.cleanup()
+
+	atomic.StoreInt32(&fail, 1)
+	v, err := g.Get("Tom")
+	if err != nil || v.String() != "v:Tom" || !v.Stale() {
+		t.Fatalf("Get = %q, %v, stale %v", v.String(), err, v.Stale())
+	}
+	//数据源确认不存在时不返回旧值
+	if _, err := g.Get("gone"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get gone = %v, want ErrNotFound", err)
+	}
+	if _, err := g.Get("never"); err == nil {
+		t.Fatal("expect an error for key without stale value")
+	}
+
+	atomic.StoreInt32(&fail, 0)
+	if v, err := g.Get("Tom"); err != nil || v.Stale() {
+		t.Fatalf("Get after recovery = %q, %v, stale %v", v.String(), err, v.Stale())
+	}
+}
+
+func TestGlobalMemoryLimit(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	a := NewGroup("globalA", 0, getter)
+	defer DestroyGroup("globalA")
+	b := NewGroup("globalB", 0, getter)
+	defer DestroyGroup("globalB")
+	entry := LRU_Cache.EntryBytes("a00", ByteView{b: []byte("1234")})
+	//其他测试遗留的 group 同样计入总用量
+	limit := GlobalMemoryUsage() + 20*entry
+	SetGlobalMemoryLimit(limit)
+	defer SetGlobalMemoryLimit(0)
+
+	for i := 0; i < 15; i++ {
+		a.Set(fmt.Sprintf("a%02d", i), []byte("1234"))
+	}
+	time.Sleep(time.Millisecond)
+	for i := 0; i < 15; i++ {
+		b.Set(fmt.Sprintf("b%02d", i), []byte("1234"))
+	}
+	if used := GlobalMemoryUsage(); used > limit {
+		t.Fatalf("GlobalMemoryUsage = %d, over limit %d", used, limit)
+	}
+	//globalA 和其他测试遗留的 group 更久没有被访问，globalB 中的记录都被保留
+	if b.Len() != 15 {
+		t.Fatalf("globalA has %d entries, globalB has %d", a.Len(), b.Len())
+	}
+
+	DestroyGroup("globalA")
+	DestroyGroup("globalB")
+	if used := GlobalMemoryUsage(); used > limit-20*entry {
+		t.Fatalf("destroyed groups should release their bytes, used %d", used)
+	}
+}
+
+func TestGlobalMemoryLimitWithDiskTier(t *testing.T) {
+	value := bytes.Repeat([]byte("v"), 1<<10)
+	g := NewGroup("global-disk", 64<<10, GetterFunc(func(key string) ([]byte, error) { return value, nil }),
+		WithShards(1), WithHotCacheBytes(0), WithDiskTier(t.TempDir(), 0))
+	defer DestroyGroup("global-disk")
+	for i := 0; i < 200; i++ {
+		g.Get(fmt.Sprintf("k%03d", i))
+	}
+	if g.Len() != 200 {
+		t.Fatalf("expect 200 entries in memory and on disk, got %d", g.Len())
+	}
+	used := g.UsedBytes()
+	limit := GlobalMemoryUsage() - 5000
+	SetGlobalMemoryLimit(limit)
+	defer SetGlobalMemoryLimit(0)
+	//全局上限只需要释放内存，记录被移入磁盘，磁盘中原有的记录不会被淘汰
+	if GlobalMemoryUsage() > limit || g.UsedBytes() >= used {
+		t.Fatalf("GlobalMemoryUsage = %d over limit %d, UsedBytes %d -> %d", GlobalMemoryUsage(), limit, used, g.UsedBytes())
+	}
+	if g.Len() != 200 {
+		t.Fatalf("global limit should spill memory entries to disk, %d entries left", g.Len())
+	}
+}
+
+func TestValueCompressionWithDiskTier(t *testing.T) {
+	var calls int32
+	value := func(key string) []byte { return []byte(strings.Repeat(key+":0123456789,", 40)) }
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		return value(key), nil
+	})
+	//内存中只能放下一条压缩后的记录，其余记录以压缩的形式进入磁盘层
+	g := NewGroup("compress-disk", 200, getter, WithHotCacheBytes(0), WithValueCompression(100, nil), WithDiskTier(t.TempDir(), 0))
+	defer DestroyGroup("compress-disk")
+	keys := []string{"k0", "k1", "k2", "k3"}
+	for _, key := range keys {
+		g.Get(key)
+	}
+	for _, key := range keys {
+		v, err := g.Get(key)
+		if err != nil || !bytes.Equal(v.ByteSlice(), value(key)) {
+			t.Fatalf("%s after a disk round-trip = %d bytes, %v, want %d bytes", key, v.Len(), err, len(value(key)))
+		}
+	}
+	if calls != int32(len(keys)) {
+		t.Fatalf("expect values to be served from the disk tier, got %d getter calls", calls)
+	}
+}
+
+func TestValueCompression(t *testing.T) {
+	value := strings.Repeat(`{"name":"Tom","score":630},`, 100)
+	var evicted []string
+	for _, c := range []Compressor{nil, GzipCompressor{}} {
+		evicted = nil
+		g := NewGroup("compress", 2<<20, GetterFunc(func(key string) ([]byte, error) { return []byte(value), nil }),
+			WithValueCompression(1024, c), WithOnEvicted(func(key string, v ByteView, reason EvictReason) {
+				evicted = append(evicted, v.String())
+			}))
+		if v, err := g.Get("Tom"); err != nil || v.String() != value {
+			t.Fatalf("%T: Get = %v", c, err)
+		}
+		if v, err := g.Get("Tom"); err != nil || v.String() != value {
+			t.Fatalf("%T: cached Get = %v", c, err)
+		}
+		//容量按压缩后的大小计算
+		if used := g.UsedBytes(); used >= LRU_Cache.EntryBytes("Tom", ByteView{b: []byte(value)}) {
+			t.Fatalf("%T: UsedBytes = %d should use the compressed size", c, used)
+		}
+		//小于 threshold 的值不压缩
+		g.Set("small", []byte("tiny"))
+		if v, _ := g.Peek("small"); v.String() != "tiny" {
+			t.Fatalf("%T: Peek small = %q", c, v.String())
+		}
+		g.Range(func(key string, v ByteView) bool {
+			if key == "Tom" && v.String() != value {
+				t.Fatalf("%T: Range should return the decompressed value", c)
+			}
+			return true
+		})
+		g.Delete("Tom")
+		if len(evicted) != 1 || evicted[0] != value {
+			t.Fatalf("%T: OnEvicted should receive the decompressed value", c)
+		}
+		DestroyGroup("compress")
+	}
+}
+
+//BenchmarkValueCompression 比较压缩前后读取的吞吐和内存占用，bytes/entry 为每条记录计入容量的字节数
+//go test -bench ValueCompression -benchmem
+func BenchmarkValueCompression(b *testing.B) {
+	var sb strings.Builder
+	for i := 0; sb.Len() < 4096; i++ {
+		fmt.Fprintf(&sb, `{"id":%d,"name":"user%d","tags":["a","b","c"],"active":true},`, i, i)
+	}
+	value := []byte(sb.String())
+	getter := GetterFunc(func(key string) ([]byte, error) { return value, nil })
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"none", nil},
+		{"snappy", []Option{WithValueCompression(1024, SnappyCompressor{})}},
+		{"gzip", []Option{WithValueCompression(1024, GzipCompressor{})}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			g := NewGroup("benchCompress", 0, getter, append(bc.opts, WithLogLevel(LevelSilent))...)
+			defer DestroyGroup("benchCompress")
+			const keys = 1000
+			for i := 0; i < keys; i++ {
+				g.Get(fmt.Sprintf("key%d", i))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.Get(fmt.Sprintf("key%d", i%keys))
+			}
+			b.ReportMetric(float64(g.UsedBytes())/keys, "bytes/entry")
+		})
+	}
+}
+
+func TestNoCopy(t *testing.T) {
+	value := []byte("630")
+	g := NewGroup("nocopy", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return value, nil }), WithNoCopy(true))
+	defer DestroyGroup("nocopy")
+
+	view, err := g.Get("Tom")
+	if err != nil || &view.ByteSlice()[0] != &value[0] {
+		t.Fatal("WithNoCopy should share the getter's slice")
+	}
+	g.Set("Sam", value)
+	if view, _ := g.Get("Sam"); &view.ByteSlice()[0] != &value[0] {
+		t.Fatal("WithNoCopy should share the slice passed to Set")
+	}
+
+	copied := NewGroup("copy", 2<<10, g.getter)
+	defer DestroyGroup("copy")
+	if view, _ := copied.Get("Tom"); &view.ByteSlice()[0] == &value[0] || &view.ByteSlice()[0] == &view.b[0] {
+		t.Fatal("values should be copied by default")
+	}
+}
+
+//BenchmarkNoCopy 比较开启 WithNoCopy 前后写入和读取的分配次数
+//go test -bench NoCopy -benchmem
+func BenchmarkNoCopy(b *testing.B) {
+	value := bytes.Repeat([]byte("x"), 1024)
+	getter := GetterFunc(func(key string) ([]byte, error) { return value, nil })
+	const n = 1000
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	for _, noCopy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("nocopy=%v/Set", noCopy), func(b *testing.B) {
+			g := NewGroup("benchNoCopy", 0, getter, WithNoCopy(noCopy), WithLogLevel(LevelSilent))
+			defer DestroyGroup("benchNoCopy")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.Set(keys[i%n], value)
+			}
+		})
+		b.Run(fmt.Sprintf("nocopy=%v/GetByteSlice", noCopy), func(b *testing.B) {
+			g := NewGroup("benchNoCopy", 0, getter, WithNoCopy(noCopy), WithLogLevel(LevelSilent))
+			defer DestroyGroup("benchNoCopy")
+			for _, key := range keys {
+				g.Get(key)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				view, _ := g.Get(keys[i%n])
+				_ = view.ByteSlice()
+			}
+		})
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	var loaded []string
+	var mu sync.Mutex
+	g := NewGroup("namespace", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			mu.Lock()
+			loaded = append(loaded, key)
+			mu.Unlock()
+			if strings.HasSuffix(key, "missing") {
+				return nil, ErrNotFound
+			}
+			return []byte("loaded:" + key), nil
+		}))
+	defer DestroyGroup("namespace")
+	users, orders := g.WithPrefix("user:"), g.WithPrefix("order:")
+
+	users.Set("42", []byte("Tom"))
+	orders.Set("42", []byte("book"))
+	if view, err := users.Get("42"); err != nil || view.String() != "Tom" {
+		t.Fatalf("users.Get(42) = %q, %v", view.String(), err)
+	}
+	if view, err := g.Get("order:42"); err != nil || view.String() != "book" {
+		t.Fatalf("namespaced key should be stored with prefix, got %q, %v", view.String(), err)
+	}
+	if view, err := users.Get("7"); err != nil || view.String() != "loaded:user:7" || loaded[0] != "user:7" {
+		t.Fatalf("getter should receive prefixed key, got %q, %v", view.String(), loaded)
+	}
+
+	values, err := users.WithPrefix("vip:").GetMulti([]string{"1", "missing"})
+	var keyErrs KeyErrors
+	if values["1"].String() != "loaded:user:vip:1" || !errors.As(err, &keyErrs) || !errors.Is(keyErrs["missing"], ErrNotFound) {
+		t.Fatalf("GetMulti should strip prefix, got %v, %v", values, err)
+	}
+
+	var keys []string
+	orders.Range(func(key string, value ByteView) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if !reflect.DeepEqual(keys, []string{"42"}) {
+		t.Fatalf("orders.Range got %v", keys)
+	}
+
+	users.Delete("42")
+	if _, ok := users.Peek("42"); ok {
+		t.Fatal("users:42 should be deleted")
+	}
+	if _, ok := orders.Peek("42"); !ok {
+		t.Fatal("order:42 should not be affected")
+	}
+}
+
+func TestKeyValidation(t *testing.T) {
+	var calls int32
+	g := NewGroup("validate", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			atomic.AddInt32(&calls, 1)
+			return []byte(key), nil
+		}), WithMaxKeyLength(8), WithKeyValidator(func(key string) error {
+		if strings.HasPrefix(key, "_") {
+			return errors.New("reserved prefix")
+		}
+		return nil
+	}))
+	defer DestroyGroup("validate")
+
+	for _, key := range []string{"toolongkey", "a\nb", "a\x00b", "_tom"} {
+		if _, err := g.Get(key); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("Get(%q): expect ErrInvalidKey, got %v", key, err)
+		}
+		if err := g.Set(key, []byte("v")); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("Set(%q): expect ErrInvalidKey, got %v", key, err)
+		}
+		if err := g.Delete(key); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("Delete(%q): expect ErrInvalidKey, got %v", key, err)
+		}
+		if _, loaded := g.GetOrStore(key, []byte("v")); loaded {
+			t.Fatalf("GetOrStore(%q): expect not loaded", key)
+		}
+		if _, ok := g.mainCache.peek(key); ok {
+			t.Fatalf("GetOrStore(%q) should not store an invalid key", key)
+		}
+		if g.Touch(key, time.Minute) {
+			t.Fatalf("Touch(%q): expect false for invalid key", key)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("getter should not be called for invalid keys, got %d calls", calls)
+	}
+	if _, err := g.Get(""); err != ErrKeyRequired {
+		t.Fatalf("expect ErrKeyRequired for empty key, got %v", err)
+	}
+	if view, err := g.Get("tom"); err != nil || view.String() != "tom" {
+		t.Fatalf("valid key should pass, got %q, %v", view.String(), err)
+	}
+
+	d := NewGroup("validate-default", 2<<10, g.getter)
+	defer DestroyGroup("validate-default")
+	if _, err := d.Get(strings.Repeat("k", DefaultMaxKeyLength+1)); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expect default max key length, got %v", err)
+	}
+	if _, err := d.Get(strings.Repeat("k", DefaultMaxKeyLength)); err != nil {
+		t.Fatalf("key of max length should pass, got %v", err)
+	}
+}
+
+//memoryBus 是进程内的 InvalidationBus，Publish 同步调用所有回调
+type memoryBus struct {
+	mu   sync.Mutex
+	subs []func(key string)
+	err  error
+}
+
+func (b *memoryBus) Publish(key string) error {
+	b.mu.Lock()
+	subs, err := b.subs, b.err
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, fn := range subs {
+		fn(key)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(fn func(key string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, fn)
+	return nil
+}
+
+func TestInvalidationBus(t *testing.T) {
+	bus := &memoryBus{}
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	a := NewGroup("bus-a", 2<<10, getter, WithInvalidationBus(bus))
+	defer DestroyGroup("bus-a")
+	b := NewGroup("bus-b", 2<<10, getter, WithInvalidationBus(bus))
+	defer DestroyGroup("bus-b")
+	peer := &fakePeer{}
+	a.RegisterPeers(&fakePicker{peer: peer})
+
+	b.Get("Tom")
+	b.Get("Sam")
+	if err := a.InvalidateMany([]string{"Tom", "Sam"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Peek("Tom"); ok {
+		t.Fatal("Tom should be invalidated through the bus")
+	}
+	if _, ok := b.Peek("Sam"); ok {
+		t.Fatal("Sam should be invalidated through the bus")
+	}
+	if len(peer.deleted) != 0 {
+		t.Fatalf("peers should not be notified over HTTP, got %v", peer.deleted)
+	}
+
+	bus.err = errors.New("connection refused")
+	if err := a.Invalidate("Tom"); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expect publish error, got %v", err)
+	}
+}
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	var running, peak int32
+	block := make(chan struct{})
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-block
+		return []byte(key), nil
+	})
+	g := NewGroup("maxloads", 2<<10, getter, WithMaxConcurrentLoads(2))
+	defer DestroyGroup("maxloads")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if view, err := g.Get(fmt.Sprint(i)); err != nil || view.String() != fmt.Sprint(i) {
+				t.Errorf("Get(%d) = %q, %v", i, view.String(), err)
+			}
+		}(i)
+	}
+	for atomic.LoadInt32(&running) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetContext(ctx, "waiting"); err != context.DeadlineExceeded {
+		t.Fatalf("expect waiting load to respect ctx, got %v", err)
+	}
+
+	failFast := NewGroup("maxloads-fail", 2<<10, getter, WithMaxConcurrentLoads(1), WithLoadQueueTimeout(-1))
+	defer DestroyGroup("maxloads-fail")
+	go failFast.Get("first")
+	for atomic.LoadInt32(&running) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := failFast.Get("second"); !errors.Is(err, ErrLoadOverloaded) {
+		t.Fatalf("expect ErrLoadOverloaded, got %v", err)
+	}
+
+	close(block)
+	wg.Wait()
+	if peak > 3 {
+		t.Fatalf("expect at most 3 concurrent loads across both groups, got %d", peak)
+	}
+}
+
+//gatedPeer 的 Set 在 started 中报告收到的 key，等待 release 后返回 err
+type gatedPeer struct {
+	fakePeer
+	started chan string
+	release chan struct{}
+	err     error
+}
+
+func (p *gatedPeer) Set(in *pb.SetRequest, out *pb.SetResponse) error {
+	p.started <- in.GetKey()
+	<-p.release
+	return p.err
+}
+
+func TestWriteBehind(t *testing.T) {
+	peer := &gatedPeer{started: make(chan string, 10), release: make(chan struct{})}
+	g := NewGroup("writebehind", 2<<10, GetterFunc(func(key string) ([]byte, error) { return nil, errors.New("no getter") }),
+		WithWriteBehind(true), WithWriteBehindQueue(1, WriteBehindDrop), WithWriteBehindWorkers(1), WithLogLevel(LevelSilent))
+	defer DestroyGroup("writebehind")
+	g.RegisterPeers(&fakePicker{peer: peer})
+
+	value := []byte("1")
+	if err := g.Set("a", value); err != nil {
+		t.Fatal(err)
+	}
+	value[0] = 'x'
+	if view, ok := g.mainCache.peek("a"); !ok || view.String() != "1" {
+		t.Fatalf("Set should store locally before replicating, got %q", view.String())
+	}
+	//a 正在发送，b 留在队列中，c 因为队列已满被丢弃，Set 都不会等待远程节点
+	if key := <-peer.started; key != "a" {
+		t.Fatalf("expect a to be replicated first, got %s", key)
+	}
+	g.Set("b", []byte("2"))
+	g.Set("c", []byte("3"))
+	if s := g.Stats(); s.WriteBehindDropped != 1 {
+		t.Fatalf("expect 1 dropped write, got %+v", s)
+	}
+	peer.err = errors.New("peer down")
+	close(peer.release)
+	if key := <-peer.started; key != "b" {
+		t.Fatalf("expect b to be replicated, got %s", key)
+	}
+	for deadline := time.Now().Add(time.Second); g.Stats().WriteBehindErrors != 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect 2 failed writes, got %+v", g.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	blockPeer := &gatedPeer{started: make(chan string, 10), release: make(chan struct{})}
+	blocking := NewGroup("writebehind-block", 2<<10, GetterFunc(func(key string) ([]byte, error) { return nil, errors.New("no getter") }),
+		WithWriteBehind(true), WithWriteBehindQueue(1, WriteBehindBlock), WithWriteBehindWorkers(1))
+	defer DestroyGroup("writebehind-block")
+	blocking.RegisterPeers(&fakePicker{peer: blockPeer})
+	blocking.Set("a", []byte("1"))
+	<-blockPeer.started
+	blocking.Set("b", []byte("2"))
+	done := make(chan struct{})
+	go func() {
+		blocking.Set("c", []byte("3"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Set should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(blockPeer.release)
+	<-done
+	for _, want := range []string{"b", "c"} {
+		if key := <-blockPeer.started; key != want {
+			t.Fatalf("expect %s to be replicated, got %s", want, key)
+		}
+	}
+	if s := blocking.Stats(); s.WriteBehindDropped != 0 || s.WriteBehindErrors != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestWriteBehindAfterDestroy(t *testing.T) {
+	peer := &fakePeer{}
+	g := NewGroup("writebehind-destroyed", 2<<10, GetterFunc(func(key string) ([]byte, error) { return nil, errors.New("no getter") }),
+		WithWriteBehind(true), WithWriteBehindQueue(1, WriteBehindBlock), WithWriteBehindWorkers(1))
+	g.RegisterPeers(&fakePicker{peer: peer})
+	DestroyGroup("writebehind-destroyed")
+	//后台协程已经停止，Set 同步写入远程节点，不会阻塞在没人读取的队列上，也不会丢失
+	for _, key := range []string{"a", "b"} {
+		if err := g.Set(key, []byte("v:"+key)); err != nil {
+			t.Fatal(err)
+		}
+		if peer.set[key] != "v:"+key {
+			t.Fatalf("Set after DestroyGroup should write %s to the peer, got %v", key, peer.set)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.19.4
+// source: gocachepb.proto
+
+package __
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	GoCache_Get_FullMethodName           = "/geecachepb.GoCache/Get"
+	GoCache_GetMulti_FullMethodName      = "/geecachepb.GoCache/GetMulti"
+	GoCache_Set_FullMethodName           = "/geecachepb.GoCache/Set"
+	GoCache_Delete_FullMethodName        = "/geecachepb.GoCache/Delete"
+	GoCache_Clear_FullMethodName         = "/geecachepb.GoCache/Clear"
+	GoCache_GetStream_FullMethodName     = "/geecachepb.GoCache/GetStream"
+	GoCache_InvalidateTag_FullMethodName = "/geecachepb.GoCache/InvalidateTag"
+)
+
+// GoCacheClient is the client API for GoCache service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GoCacheClient interface {
+	Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error)
+	GetMulti(ctx context.Context, in *MultiRequest, opts ...grpc.CallOption) (*MultiResponse, error)
+	Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error)
+	Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error)
+	Clear(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error)
+	GetStream(ctx context.Context, in *Request, opts ...grpc.CallOption) (GoCache_GetStreamClient, error)
+	InvalidateTag(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*TagResponse, error)
+}
+
+type goCacheClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGoCacheClient(cc grpc.ClientConnInterface) GoCacheClient {
+	return &goCacheClient{cc}
+}
+
+func (c *goCacheClient) Get(ctx context.Context, in *Request, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, GoCache_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCacheClient) GetMulti(ctx context.Context, in *MultiRequest, opts ...grpc.CallOption) (*MultiResponse, error) {
+	out := new(MultiResponse)
+	err := c.cc.Invoke(ctx, GoCache_GetMulti_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCacheClient) Set(ctx context.Context, in *SetRequest, opts ...grpc.CallOption) (*SetResponse, error) {
+	out := new(SetResponse)
+	err := c.cc.Invoke(ctx, GoCache_Set_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCacheClient) Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, GoCache_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCacheClient) Clear(ctx context.Context, in *Request, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, GoCache_Clear_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCacheClient) GetStream(ctx context.Context, in *Request, opts ...grpc.CallOption) (GoCache_GetStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GoCache_ServiceDesc.Streams[0], GoCache_GetStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &goCacheGetStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GoCache_GetStreamClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type goCacheGetStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *goCacheGetStreamClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *goCacheClient) InvalidateTag(ctx context.Context, in *TagRequest, opts ...grpc.CallOption) (*TagResponse, error) {
+	out := new(TagResponse)
+	err := c.cc.Invoke(ctx, GoCache_InvalidateTag_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GoCacheServer is the server API for GoCache service.
+// All implementations must embed UnimplementedGoCacheServer
+// for forward compatibility
+type GoCacheServer interface {
+	Get(context.Context, *Request) (*Response, error)
+	GetMulti(context.Context, *MultiRequest) (*MultiResponse, error)
+	Set(context.Context, *SetRequest) (*SetResponse, error)
+	Delete(context.Context, *Request) (*DeleteResponse, error)
+	Clear(context.Context, *Request) (*DeleteResponse, error)
+	GetStream(*Request, GoCache_GetStreamServer) error
+	InvalidateTag(context.Context, *TagRequest) (*TagResponse, error)
+	mustEmbedUnimplementedGoCacheServer()
+}
+
+// UnimplementedGoCacheServer must be embedded to have forward compatible implementations.
+type UnimplementedGoCacheServer struct {
+}
+
+func (UnimplementedGoCacheServer) Get(context.Context, *Request) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedGoCacheServer) GetMulti(context.Context, *MultiRequest) (*MultiResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMulti not implemented")
+}
+func (UnimplementedGoCacheServer) Set(context.Context, *SetRequest) (*SetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedGoCacheServer) Delete(context.Context, *Request) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedGoCacheServer) Clear(context.Context, *Request) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clear not implemented")
+}
+func (UnimplementedGoCacheServer) GetStream(*Request, GoCache_GetStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetStream not implemented")
+}
+func (UnimplementedGoCacheServer) InvalidateTag(context.Context, *TagRequest) (*TagResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InvalidateTag not implemented")
+}
+func (UnimplementedGoCacheServer) mustEmbedUnimplementedGoCacheServer() {}
+
+// UnsafeGoCacheServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GoCacheServer will
+// result in compilation errors.
+type UnsafeGoCacheServer interface {
+	mustEmbedUnimplementedGoCacheServer()
+}
+
+func RegisterGoCacheServer(s grpc.ServiceRegistrar, srv GoCacheServer) {
+	s.RegisterService(&GoCache_ServiceDesc, srv)
+}
+
+func _GoCache_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCacheServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCache_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCacheServer).Get(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCache_GetMulti_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCacheServer).GetMulti(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCache_GetMulti_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCacheServer).GetMulti(ctx, req.(*MultiRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCache_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCacheServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCache_Set_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCacheServer).Set(ctx, req.(*SetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCache_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCacheServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCache_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCacheServer).Delete(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCache_Clear_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCacheServer).Clear(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCache_Clear_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCacheServer).Clear(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCache_GetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Request)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GoCacheServer).GetStream(m, &goCacheGetStreamServer{stream})
+}
+
+type GoCache_GetStreamServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type goCacheGetStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *goCacheGetStreamServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GoCache_InvalidateTag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCacheServer).InvalidateTag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCache_InvalidateTag_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCacheServer).InvalidateTag(ctx, req.(*TagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GoCache_ServiceDesc is the grpc.ServiceDesc for GoCache service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GoCache_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "geecachepb.GoCache",
+	HandlerType: (*GoCacheServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _GoCache_Get_Handler,
+		},
+		{
+			MethodName: "GetMulti",
+			Handler:    _GoCache_GetMulti_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _GoCache_Set_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _GoCache_Delete_Handler,
+		},
+		{
+			MethodName: "Clear",
+			Handler:    _GoCache_Clear_Handler,
+		},
+		{
+			MethodName: "InvalidateTag",
+			Handler:    _GoCache_InvalidateTag_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetStream",
+			Handler:       _GoCache_GetStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gocachepb.proto",
+}
@@ -0,0 +1,445 @@
+//Package grpcpool 提供基于 gRPC 的节点间通信，作用与 GoCache.HTTPPool 相同，
+//复用 gocachepb 中的消息，服务定义为 gocachepb.proto 中的 GoCache。
+//
+//	pool := grpcpool.NewPool("10.0.0.1:8001", grpcpool.WithTLS(cfg))
+//	pool.Set("10.0.0.1:8001", "10.0.0.2:8001", "10.0.0.3:8001")
+//	group.RegisterPeers(pool)
+//	lis, _ := net.Listen("tcp", ":8001")
+//	pool.NewServer().Serve(lis)
+//
+//节点地址是 gRPC 的 dial target，例如 host:port，本节点的地址需要与 Set 中的写法一致
+package grpcpool
+
+import (
+	"GoCache"
+	"GoCache/consistenthash"
+	pb "GoCache/gocachepb"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReplicas = 50
+	//defaultTimeout 是没有 ctx 的请求（Set、Delete、Clear）访问远程节点的默认超时时间
+	defaultTimeout = 2 * time.Second
+	//chunkSize 是 GetStream 每个分块的最大字节数
+	chunkSize = 64 << 10
+)
+
+//Pool 实现 GoCache.PeerPicker，并提供 gRPC 服务端。
+//每个远程节点对应一个 *grpc.ClientConn，节点列表变化时仍在列表中的节点复用原有连接
+type Pool struct {
+	self string
+	mu   sync.Mutex
+	//peers 用来根据具体的 key 选择节点，默认是一致性哈希
+	peers   consistenthash.Ring
+	newRing func() consistenthash.Ring
+	getters map[string]*grpcGetter
+	//creds 同时用于服务端和访问远程节点的客户端，默认不加密，见 WithTLS
+	creds    credentials.TransportCredentials
+	dialOpts []grpc.DialOption
+	timeout  time.Duration
+}
+
+//Option 用于在创建 Pool 时修改可选配置
+type Option func(p *Pool)
+
+//WithTLS 让节点间通信使用 TLS，cfg 的用法与 GoCache.WithTLS 相同：
+//cfg.Certificates 同时作为服务端和客户端证书，cfg.RootCAs 校验远程节点，需要双向 TLS 时设置 cfg.ClientCAs 和 cfg.ClientAuth
+func WithTLS(cfg *tls.Config) Option {
+	return func(p *Pool) {
+		p.creds = credentials.NewTLS(cfg)
+	}
+}
+
+//WithDialOptions 追加创建连接时使用的 grpc.DialOption，例如拦截器和 keepalive 参数
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(p *Pool) {
+		p.dialOpts = append(p.dialOpts, opts...)
+	}
+}
+
+//WithTimeout 设置没有 ctx 的请求（Set、Delete、Clear）的超时时间，默认为 2s，Get 和 GetMulti 受调用方 ctx 的限制
+func WithTimeout(d time.Duration) Option {
+	return func(p *Pool) {
+		p.timeout = d
+	}
+}
+
+//WithRing 设置选择节点的算法，与 GoCache.WithPoolRing 相同，默认使用虚拟节点倍数为 50 的一致性哈希
+func WithRing(newRing func() consistenthash.Ring) Option {
+	return func(p *Pool) {
+		p.newRing = newRing
+	}
+}
+
+//NewPool 创建 gRPC 节点池，self 是本节点的地址
+func NewPool(self string, opts ...Option) *Pool {
+	p := &Pool{
+		self:    self,
+		getters: make(map[string]*grpcGetter),
+		creds:   insecure.NewCredentials(),
+		timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+//Set 更新节点列表并重建哈希环，新节点创建连接，不再存在的节点的连接被关闭。
+//创建连接失败时关闭这次创建的连接并返回错误，原来的节点列表和连接保持不变
+func (p *Pool) Set(peers ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ring consistenthash.Ring
+	if p.newRing != nil {
+		ring = p.newRing()
+	} else {
+		ring = consistenthash.New(defaultReplicas, nil)
+	}
+	ring.Add(peers...)
+
+	getters := make(map[string]*grpcGetter, len(peers))
+	var dialed []*grpc.ClientConn
+	for _, peer := range peers {
+		if peer == p.self {
+			continue
+		}
+		if getter, ok := p.getters[peer]; ok {
+			getters[peer] = getter
+			continue
+		}
+		//grpc.Dial 不会阻塞等待连接建立，连接在第一次请求时建立，断开后自动重连
+		conn, err := grpc.Dial(peer, append([]grpc.DialOption{grpc.WithTransportCredentials(p.creds)}, p.dialOpts...)...)
+		if err != nil {
+			for _, conn := range dialed {
+				conn.Close()
+			}
+			return fmt.Errorf("dial %s: %v", peer, err)
+		}
+		dialed = append(dialed, conn)
+		getters[peer] = &grpcGetter{conn: conn, client: pb.NewGoCacheClient(conn), timeout: p.timeout}
+	}
+	for peer, getter := range p.getters {
+		if _, ok := getters[peer]; !ok {
+			getter.conn.Close()
+		}
+	}
+	p.peers = ring
+	p.getters = getters
+	return nil
+}
+
+//PickPeer 根据具体的 key 选择节点，负责 key 的是本节点时返回 false
+func (p *Pool) PickPeer(key string) (GoCache.PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil, false
+	}
+	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+		return p.getters[peer], true
+	}
+	return nil, false
+}
+
+//PickPeers 按哈希环的顺序返回最多 n 个远程节点，跳过本节点
+func (p *Pool) PickPeers(key string, n int) []GoCache.PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	var getters []GoCache.PeerGetter
+	//多取一个节点，本节点被跳过时仍能凑够 n 个
+	for _, peer := range p.peers.GetN(key, n+1) {
+		if peer != p.self && len(getters) < n {
+			getters = append(getters, p.getters[peer])
+		}
+	}
+	return getters
+}
+
+//Peers 返回除本节点以外所有节点
+func (p *Pool) Peers() []GoCache.PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	getters := make([]GoCache.PeerGetter, 0, len(p.getters))
+	for _, getter := range p.getters {
+		getters = append(getters, getter)
+	}
+	return getters
+}
+
+//Close 关闭所有远程节点的连接
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var first error
+	for _, getter := range p.getters {
+		if err := getter.conn.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	p.getters = make(map[string]*grpcGetter)
+	p.peers = nil
+	return first
+}
+
+var _ GoCache.PeerPicker = (*Pool)(nil)
+
+//NewServer 创建使用 Pool 的证书配置并注册了 GoCache 服务的 grpc.Server
+func (p *Pool) NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append([]grpc.ServerOption{grpc.Creds(p.creds)}, opts...)...)
+	Register(s)
+	return s
+}
+
+//Register 把 GoCache 服务注册到已有的 grpc.Server 上，例如已经配置了 mTLS 的服务
+func Register(s *grpc.Server) {
+	pb.RegisterGoCacheServer(s, &server{})
+}
+
+//server 实现 gocachepb.GoCacheServer，与 HTTPPool.ServeHTTP 相同，只处理本节点的缓存，不再转发给其他节点
+type server struct {
+	pb.UnimplementedGoCacheServer
+}
+
+func lookup(name string) (*GoCache.Group, error) {
+	group := GoCache.GetGroup(name)
+	if group == nil {
+		return nil, status.Errorf(codes.NotFound, "no such group: %s", name)
+	}
+	return group, nil
+}
+
+//toStatus 把 Group 返回的错误转换为 gRPC 状态码
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, GoCache.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, GoCache.ErrKeyRequired), errors.Is(err, GoCache.ErrInvalidKey), errors.Is(err, GoCache.ErrValueTooLarge):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (s *server) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	view, err := group.GetContext(ctx, in.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Response{Value: view.ByteSlice(), Meta: view.Metadata()}, nil
+}
+
+//GetMulti 单个 key 失败时记录在 MultiResponse.Errors 中，不影响其他 key
+func (s *server) GetMulti(ctx context.Context, in *pb.MultiRequest) (*pb.MultiResponse, error) {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.MultiResponse{
+		Values: make(map[string][]byte, len(in.GetKeys())),
+		Errors: make(map[string]string),
+		Meta:   make(map[string]*pb.Meta),
+	}
+	for _, key := range in.GetKeys() {
+		view, err := group.GetContext(ctx, key)
+		if err != nil {
+			res.Errors[key] = err.Error()
+			continue
+		}
+		res.Values[key] = view.ByteSlice()
+		if meta := view.Metadata(); meta != nil {
+			res.Meta[key] = &pb.Meta{Values: meta}
+		}
+	}
+	return res, nil
+}
+
+func (s *server) Set(ctx context.Context, in *pb.SetRequest) (*pb.SetResponse, error) {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	if err = group.SetLocalWithMeta(in.GetKey(), in.GetValue(), in.GetMeta(), in.GetTags()...); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SetResponse{}, nil
+}
+
+func (s *server) Delete(ctx context.Context, in *pb.Request) (*pb.DeleteResponse, error) {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.DeleteLocal(in.GetKey())
+	return &pb.DeleteResponse{}, nil
+}
+
+//GetStream 把缓存值按 chunkSize 分块发送，直接从缓存中的 ByteView 写出，不复制完整的值
+func (s *server) GetStream(in *pb.Request, stream pb.GoCache_GetStreamServer) error {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return err
+	}
+	view, err := group.GetContext(stream.Context(), in.GetKey())
+	if err != nil {
+		return toStatus(err)
+	}
+	_, err = view.WriteTo(chunkWriter{stream})
+	return err
+}
+
+//chunkWriter 把写入的数据拆分为不超过 chunkSize 的 pb.Chunk 发送
+type chunkWriter struct {
+	stream pb.GoCache_GetStreamServer
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		size := len(p)
+		if size > chunkSize {
+			size = chunkSize
+		}
+		//Send 返回前已经完成序列化，之后不再引用 p
+		if err := c.stream.Send(&pb.Chunk{Data: p[:size]}); err != nil {
+			return n, err
+		}
+		n += size
+		p = p[size:]
+	}
+	return n, nil
+}
+
+func (s *server) Clear(ctx context.Context, in *pb.Request) (*pb.DeleteResponse, error) {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	group.Clear()
+	return &pb.DeleteResponse{}, nil
+}
+
+func (s *server) InvalidateTag(ctx context.Context, in *pb.TagRequest) (*pb.TagResponse, error) {
+	group, err := lookup(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TagResponse{Keys: group.InvalidateTagLocal(in.GetTag())}, nil
+}
+
+//grpcGetter 实现 GoCache.PeerGetter，所有请求共用同一个连接
+type grpcGetter struct {
+	conn    *grpc.ClientConn
+	client  pb.GoCacheClient
+	timeout time.Duration
+}
+
+//Addr 返回节点地址，用于 span 的 AttrPeer 属性
+func (g *grpcGetter) Addr() string {
+	return g.conn.Target()
+}
+
+func (g *grpcGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	res, err := g.client.Get(ctx, in)
+	if err != nil {
+		return err
+	}
+	out.Value, out.Meta = res.GetValue(), res.GetMeta()
+	return nil
+}
+
+func (g *grpcGetter) GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	res, err := g.client.GetMulti(ctx, in)
+	if err != nil {
+		return err
+	}
+	out.Values, out.Errors, out.Meta = res.GetValues(), res.GetErrors(), res.GetMeta()
+	return nil
+}
+
+//GetStream 实现 GoCache.StreamPeerGetter，每收到一个分块就写入 w
+func (g *grpcGetter) GetStream(ctx context.Context, in *pb.Request, w io.Writer) error {
+	stream, err := g.client.GetStream(ctx, in)
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk.GetData()); err != nil {
+			return err
+		}
+	}
+}
+
+//context 返回没有 ctx 的请求使用的超时 context
+func (g *grpcGetter) context() (context.Context, context.CancelFunc) {
+	if g.timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), g.timeout)
+}
+
+func (g *grpcGetter) Set(in *pb.SetRequest, out *pb.SetResponse) error {
+	ctx, cancel := g.context()
+	defer cancel()
+	_, err := g.client.Set(ctx, in)
+	return err
+}
+
+func (g *grpcGetter) Delete(in *pb.Request) error {
+	ctx, cancel := g.context()
+	defer cancel()
+	_, err := g.client.Delete(ctx, in)
+	return err
+}
+
+func (g *grpcGetter) Clear(in *pb.Request) error {
+	ctx, cancel := g.context()
+	defer cancel()
+	_, err := g.client.Clear(ctx, in)
+	return err
+}
+
+func (g *grpcGetter) InvalidateTag(in *pb.TagRequest, out *pb.TagResponse) error {
+	ctx, cancel := g.context()
+	defer cancel()
+	res, err := g.client.InvalidateTag(ctx, in)
+	if err != nil {
+		return err
+	}
+	out.Keys = res.GetKeys()
+	return nil
+}
+
+var (
+	_ GoCache.PeerGetter       = (*grpcGetter)(nil)
+	_ GoCache.StreamPeerGetter = (*grpcGetter)(nil)
+)
@@ -0,0 +1,269 @@
+package grpcpool
+
+import (
+	"GoCache"
+	pb "GoCache/gocachepb"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+//startServer 在随机端口上启动使用 p 配置的 gRPC 服务，返回地址
+func startServer(t *testing.T, p *Pool) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.NewServer()
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestPool(t *testing.T) {
+	group := GoCache.NewGroup("grpc", 2<<10, GoCache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, GoCache.ErrNotFound
+		}
+		return []byte("v:" + key), nil
+	}))
+	defer GoCache.DestroyGroup("grpc")
+	addr := startServer(t, NewPool(""))
+
+	p := NewPool("self")
+	defer p.Close()
+	if err := p.Set("self", addr); err != nil {
+		t.Fatal(err)
+	}
+	getter := p.getters[addr]
+	if _, ok := p.getters["self"]; ok || len(p.Peers()) != 1 {
+		t.Fatal("self should not get a connection")
+	}
+
+	ctx := context.Background()
+	out := &pb.Response{}
+	if err := getter.Get(ctx, &pb.Request{Group: "grpc", Key: "Tom"}, out); err != nil || string(out.GetValue()) != "v:Tom" {
+		t.Fatalf("Get = %q, %v", out.GetValue(), err)
+	}
+	err := getter.Get(ctx, &pb.Request{Group: "grpc", Key: "missing"}, out)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("missing key should return NotFound, got %v", err)
+	}
+	if err := getter.Get(ctx, &pb.Request{Group: "nope", Key: "k"}, out); status.Code(err) != codes.NotFound {
+		t.Fatalf("unknown group should return NotFound, got %v", err)
+	}
+
+	multi := &pb.MultiResponse{}
+	if err := getter.GetMulti(ctx, &pb.MultiRequest{Group: "grpc", Keys: []string{"a", "missing"}}, multi); err != nil {
+		t.Fatal(err)
+	}
+	if string(multi.Values["a"]) != "v:a" || multi.Errors["missing"] == "" {
+		t.Fatalf("GetMulti = %v, %v", multi.Values, multi.Errors)
+	}
+
+	if err := getter.Set(&pb.SetRequest{Group: "grpc", Key: "k", Value: []byte("set")}, &pb.SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := group.Peek("k"); !ok || v.String() != "set" {
+		t.Fatalf("Set should write to the server's cache, got %q", v.String())
+	}
+	err = getter.Set(&pb.SetRequest{Group: "grpc", Key: "a\x00b", Value: []byte("set")}, &pb.SetResponse{})
+	if _, ok := group.Peek("a\x00b"); ok || status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid key should return InvalidArgument and not be stored, got %v", err)
+	}
+	if err := getter.Delete(&pb.Request{Group: "grpc", Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := group.Peek("k"); ok {
+		t.Fatal("Delete should remove the key from the server's cache")
+	}
+	if err := getter.Clear(&pb.Request{Group: "grpc"}); err != nil || group.Len() != 0 {
+		t.Fatalf("Clear = %v, %d entries left", err, group.Len())
+	}
+
+	//节点列表变化时复用仍然存在的节点的连接
+	p.Set("self", addr, "127.0.0.1:1")
+	if p.getters[addr] != getter {
+		t.Fatal("connection to an existing peer should be reused")
+	}
+	p.Set("self")
+	if len(p.getters) != 0 {
+		t.Fatal("removed peers should be closed")
+	}
+}
+
+func TestPickPeer(t *testing.T) {
+	p := NewPool("a")
+	defer p.Close()
+	p.Set("a", "b", "c")
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		getter, ok := p.PickPeer(key)
+		if !ok {
+			seen["a"] = true
+			continue
+		}
+		for peer, g := range p.getters {
+			if g == getter {
+				seen[peer] = true
+			}
+		}
+		if peers := p.PickPeers(key, 2); len(peers) != 2 || peers[0] != getter {
+			t.Fatalf("PickPeers should start with the owner, got %v", peers)
+		}
+	}
+	if !seen["a"] || !seen["b"] || !seen["c"] {
+		t.Fatalf("keys should spread over all peers, got %v", seen)
+	}
+}
+
+func TestSetDialError(t *testing.T) {
+	addr := startServer(t, NewPool(""))
+	//阻塞等待连接建立，让连接被拒绝的节点在 Set 中返回错误
+	p := NewPool("self", WithDialOptions(grpc.WithBlock(), grpc.FailOnNonTempDialError(true)))
+	defer p.Close()
+	if err := p.Set("self", addr); err != nil {
+		t.Fatal(err)
+	}
+	getter := p.getters[addr]
+	if err := p.Set("self", addr, "127.0.0.1:1"); err == nil {
+		t.Fatal("expect dial error for unreachable peer")
+	}
+	//失败时保留原来的哈希环和连接，PickPeer 不会返回没有连接的节点
+	if len(p.getters) != 1 || p.getters[addr] != getter {
+		t.Fatalf("failed Set should keep the old getters, got %v", p.getters)
+	}
+	for i := 0; i < 100; i++ {
+		if g, ok := p.PickPeer(strconv.Itoa(i)); ok && g != GoCache.PeerGetter(getter) {
+			t.Fatalf("key %d picked a peer without a connection", i)
+		}
+	}
+}
+
+func TestTLS(t *testing.T) {
+	GoCache.NewGroup("grpc-tls", 2<<10, GoCache.GetterFunc(func(key string) ([]byte, error) { return []byte("v:" + key), nil }))
+	defer GoCache.DestroyGroup("grpc-tls")
+	cfg := newTestTLSConfig(t)
+	addr := startServer(t, NewPool("", WithTLS(cfg)))
+
+	p := NewPool("self", WithTLS(cfg))
+	defer p.Close()
+	p.Set(addr)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out := &pb.Response{}
+	if err := p.getters[addr].Get(ctx, &pb.Request{Group: "grpc-tls", Key: "Tom"}, out); err != nil || string(out.GetValue()) != "v:Tom" {
+		t.Fatalf("mutual TLS request failed: %v", err)
+	}
+
+	//没有客户端证书时握手失败
+	anonymous := NewPool("anonymous", WithTLS(&tls.Config{RootCAs: cfg.RootCAs}))
+	defer anonymous.Close()
+	anonymous.Set(addr)
+	if err := anonymous.getters[addr].Get(ctx, &pb.Request{Group: "grpc-tls", Key: "Tom"}, out); err == nil {
+		t.Fatal("request without client certificate should fail")
+	}
+}
+
+//newTestTLSConfig 与 GoCache 包测试中的同名函数相同，生成自签名 CA 和同时用于服务端、客户端的证书
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gocache test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "gocache node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leafDER}, PrivateKey: key}},
+		RootCAs:      roots,
+		ClientCAs:    roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+func TestGetStream(t *testing.T) {
+	//超过 gRPC 默认 4MB 的单条消息上限，只能通过 GetStream 获取
+	value := bytes.Repeat([]byte("0123456789abcdef"), 5<<16)
+	GoCache.NewGroup("grpc-stream", 0, GoCache.GetterFunc(func(key string) ([]byte, error) { return value, nil }))
+	defer GoCache.DestroyGroup("grpc-stream")
+	addr := startServer(t, NewPool(""))
+
+	p := NewPool("self")
+	defer p.Close()
+	p.Set(addr)
+	getter := p.getters[addr]
+	var buf bytes.Buffer
+	w := &countingWriter{w: &buf}
+	if err := getter.GetStream(context.Background(), &pb.Request{Group: "grpc-stream", Key: "value"}, w); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), value) {
+		t.Fatalf("streamed %d bytes, want %d", buf.Len(), len(value))
+	}
+	if w.writes != len(value)/chunkSize {
+		t.Fatalf("value should arrive in %d chunks, got %d", len(value)/chunkSize, w.writes)
+	}
+	if err := getter.Get(context.Background(), &pb.Request{Group: "grpc-stream", Key: "value"}, &pb.Response{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("unary Get of a 5MB value = %v, want ResourceExhausted", err)
+	}
+}
+
+type countingWriter struct {
+	w      io.Writer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.w.Write(p)
+}
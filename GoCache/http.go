@@ -3,20 +3,43 @@ package GoCache
 import (
 	"GoCache/consistenthash"
 	pb "GoCache/gocachepb"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"google.golang.org/protobuf/proto"
+	"io"
 	"io/ioutil"
-	"log"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //为 HTTPPool 添加节点选择的功能
 const (
 	defultBasePath  = "/_gocache/"
 	defaultReplicas = 50
+	//defaultPeerTimeout 是访问远程节点的默认超时时间
+	defaultPeerTimeout = 2 * time.Second
+	//defaultCompressMinSize 是开启压缩后响应需要达到的最小字节数，更小的响应压缩收益不大
+	defaultCompressMinSize = 1024
+	//defaultMaxIdleConnsPerHost 是与每个远程节点保持的空闲连接数，http.DefaultTransport 只保持 2 个，并发较高时连接会被频繁重建
+	defaultMaxIdleConnsPerHost = 64
+	//defaultIdleConnTimeout 是空闲连接被关闭前保持的时间，与 http.DefaultTransport 相同
+	defaultIdleConnTimeout = 90 * time.Second
+	//healthPath 是健康检查接口相对 basePath 的路径
+	healthPath = "health"
+	//tagPath 是按标签删除接口相对 basePath 的前缀，请求为 DELETE <basePath>_tag/<group>/<tag>
+	tagPath = "_tag/"
 )
 
 //HTTPPool 只有 2 个参数，
@@ -28,28 +51,487 @@ const (
 type HTTPPool struct {
 	self     string
 	basePath string
-	mu       sync.Mutex
-	//新增成员变量 peers，类型是一致性哈希算法的 Map，用来根据具体的 key 选择节点
-	peers *consistenthash.Map
+	mu       sync.RWMutex
+	//新增成员变量 peers，默认是一致性哈希算法的 Map，用来根据具体的 key 选择节点
+	peers consistenthash.Ring
+	//newRing 在 Set 时创建新的 peers，nil 表示使用一致性哈希
+	newRing func() consistenthash.Ring
 	//新增成员变量 httpGetters，映射远程节点与对应的 httpGetter。
 	//每一个远程节点对应一个 httpGetter，因为 httpGetter 与远程节点的地址 baseURL 有关。
 	httpGetters map[string]*httpGetter
+	logger      leveledLogger
+	//tlsConfig 不为 nil 时，节点间通信使用 HTTPS，见 WithTLS
+	tlsConfig *tls.Config
+	//client 是所有 httpGetter 共用的 HTTP 客户端，见 WithHTTPClient
+	client *http.Client
+	//timeout 是访问远程节点的超时时间，见 WithTimeout
+	timeout time.Duration
+	//authToken 不为空时，节点间请求需要携带该令牌，见 WithAuthToken
+	authToken string
+	//compress 为 true 时节点间响应使用 gzip 压缩，见 WithCompression
+	compress        bool
+	compressMinSize int
+	//retry 是请求远程节点遇到暂时性错误时的重试策略，见 WithPeerRetry
+	retry retryPolicy
+	//breakerThreshold > 0 时为每个远程节点创建熔断器，见 WithCircuitBreaker
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	//healthInterval > 0 时每隔 healthInterval 探测一次远程节点，见 WithHealthCheck
+	healthInterval time.Duration
+	stopHealth     chan struct{}
+	started        time.Time
+	//ownClient 为 true 时 client 由 HTTPPool 创建，Shutdown 时关闭其空闲连接
+	ownClient bool
+	//创建 client 时 Transport 的连接池配置，见 WithMaxConnsPerHost、WithMaxIdleConnsPerHost 和 WithIdleConnTimeout
+	maxConnsPerHost     int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	//conns 记录 HTTPPool 创建的 client 与每个节点之间的连接数，见 PeerConns
+	conns *connTracker
+	//observer 不为 nil 时在每次访问远程节点后调用，见 WithPeerObserver
+	observer func(peer string, latency time.Duration, err error)
+	//codec 是访问远程节点使用的 Codec，为 nil 表示 ProtobufCodec，见 WithCodec
+	codec Codec
+	//admin 为 true 时提供只读的管理接口，见 WithAdmin
+	admin bool
+	//tracer 不为 nil 时在节点间请求的请求头中传递追踪上下文，见 WithPoolTracer
+	tracer Tracer
+	//latencyAware 为 true 时优先选择平均延迟低的节点，见 WithLatencyAwareRouting
+	latencyAware bool
+	//rateLimit 和 peerRateLimit 不为 nil 时限制收到的节点间请求，见 WithRateLimit 和 WithPeerRateLimit
+	rateLimit     *tokenBucket
+	peerRateLimit *peerLimiter
+	//serveMu 保护 closed 和 server，inflight 记录正在处理的请求，见 Shutdown
+	serveMu  sync.Mutex
+	closed   bool
+	server   *http.Server
+	inflight sync.WaitGroup
+}
+
+//retryPolicy 的零值表示不重试
+type retryPolicy struct {
+	maxRetries int
+	base       time.Duration
+}
+
+//PoolOption 用于在创建 HTTPPool 时修改可选配置
+type PoolOption func(p *HTTPPool)
+
+//WithPoolLogger 设置 HTTPPool 使用的 Logger，默认使用标准库的 log
+func WithPoolLogger(l Logger) PoolOption {
+	return func(p *HTTPPool) {
+		p.logger.logger = l
+	}
+}
+
+//WithPoolLogLevel 设置 HTTPPool 的日志级别，默认为 LevelDebug
+func WithPoolLogLevel(level LogLevel) PoolOption {
+	return func(p *HTTPPool) {
+		p.logger.level = level
+	}
+}
+
+//WithPoolRing 设置选择节点的算法，newRing 在每次 Set 时被调用以创建新的 Ring，例如：
+//	NewHTTPPool(self, WithPoolRing(func() consistenthash.Ring { return consistenthash.NewRendezvous(nil) }))
+//默认使用虚拟节点倍数为 50 的一致性哈希
+func WithPoolRing(newRing func() consistenthash.Ring) PoolOption {
+	return func(p *HTTPPool) {
+		p.newRing = newRing
+	}
+}
+
+//WithTLS 让节点间通信使用 HTTPS，cfg 同时用于服务端(ListenAndServe)和访问远程节点的客户端：
+//cfg.Certificates 是本节点的证书，服务端出示给对方，开启双向 TLS 时客户端也出示同一张证书，
+//因此证书需要同时允许 serverAuth 和 clientAuth；cfg.RootCAs 用于校验远程节点的证书；
+//需要双向 TLS 时设置 cfg.ClientCAs 和 cfg.ClientAuth = tls.RequireAndVerifyClientCert。
+//开启后 ServeHTTP 拒绝不是通过 TLS 收到的请求，节点地址需要使用 https:// 开头
+func WithTLS(cfg *tls.Config) PoolOption {
+	return func(p *HTTPPool) {
+		p.tlsConfig = cfg
+	}
+}
+
+//WithTimeout 设置访问远程节点的超时时间，同时用于建立连接、TLS 握手和整个请求（包括读取响应），默认为 2s。
+//d <= 0 表示不设置超时，只受调用方 ctx 的限制。
+//超时后请求返回错误，load 按 PeerFailurePolicy 回退到本地或尝试下一个节点，不会一直占用 singleflight
+func WithTimeout(d time.Duration) PoolOption {
+	return func(p *HTTPPool) {
+		p.timeout = d
+	}
+}
+
+//WithHTTPClient 设置访问远程节点使用的 HTTP 客户端，所有 httpGetter 共用该客户端，
+//可以用来复用自己的连接池、代理和监控。设置后 WithTimeout 和 WithTLS 的客户端部分以及连接池相关的配置不再生效，
+//PeerConns 返回 nil，需要在 c 中自行配置；WithTLS 仍然用于 ListenAndServe 和拒绝明文请求
+func WithHTTPClient(c *http.Client) PoolOption {
+	return func(p *HTTPPool) {
+		p.client = c
+	}
+}
+
+//WithPeerRetry 设置请求远程节点失败时的重试：连接失败、超时和 5xx 响应最多重试 maxRetries 次，
+//第 n 次重试前等待 base*2^n 并加上随机抖动；4xx 响应不重试。
+//重试受请求 ctx 的限制，剩余时间不够等待下一次重试时直接返回最后一次的错误。默认不重试
+func WithPeerRetry(maxRetries int, base time.Duration) PoolOption {
+	return func(p *HTTPPool) {
+		p.retry = retryPolicy{maxRetries: maxRetries, base: base}
+	}
+}
+
+//WithCircuitBreaker 为每个远程节点开启熔断器：连续 threshold 次请求失败（网络错误、超时或 5xx，重试计为一次）后，
+//在 cooldown 时间内访问该节点的请求直接返回 ErrCircuitOpen，load 立即回退或尝试下一个节点，不再等待超时；
+//冷却期结束后放行一个探测请求，成功（2xx）则恢复，失败则再次打开。
+//超时指 WithTimeout 的超时；调用方 ctx 取消或超时、以及 4xx 的请求既不算成功也不算失败。threshold <= 0 表示关闭，默认关闭
+func WithCircuitBreaker(threshold int, cooldown time.Duration) PoolOption {
+	return func(p *HTTPPool) {
+		p.breakerThreshold = threshold
+		p.breakerCooldown = cooldown
+	}
+}
+
+//WithHealthCheck 每隔 interval 请求一次所有远程节点的健康检查接口 <basePath>health，
+//没有返回 200 的节点被标记为下线，PickPeer 和 PickPeers 跳过下线的节点，
+//原本属于该节点的 key 交给哈希环上的下一个节点；节点恢复后在下一次探测时重新上线。默认关闭
+func WithHealthCheck(interval time.Duration) PoolOption {
+	return func(p *HTTPPool) {
+		p.healthInterval = interval
+	}
+}
+
+//WithPeerObserver 设置访问远程节点后的回调，用于统计每个节点的请求延迟和错误，例如 metrics.Collector.ObservePeer。
+//每次 Get、GetMulti、Set、Delete 和 Clear 调用一次，latency 包括重试的时间；
+//err 为请求错误或非 2xx 响应，熔断器打开时 err 为 ErrCircuitOpen。fn 会被并发调用，需要是并发安全的
+func WithPeerObserver(fn func(peer string, latency time.Duration, err error)) PoolOption {
+	return func(p *HTTPPool) {
+		p.observer = fn
+	}
+}
+
+//WithCodec 设置访问远程节点时请求体和响应的编码，默认为 ProtobufCodec。
+//无论是否设置，ServeHTTP 都按请求的 Content-Type 解码请求体、按 Accept 选择响应的编码，
+//支持 ProtobufCodec、ProtoJSONCodec 和 c，因此集群中的节点可以使用不同的 Codec
+func WithCodec(c Codec) PoolOption {
+	return func(p *HTTPPool) {
+		p.codec = c
+	}
+}
+
+//WithAuthToken 设置节点间共享的令牌：访问远程节点时在 Authorization 头中携带 "Bearer <token>"，
+//ServeHTTP 对令牌不正确的请求返回 401。集群中所有节点需要使用相同的令牌，建议同时开启 WithTLS 避免令牌被窃听
+func WithAuthToken(token string) PoolOption {
+	return func(p *HTTPPool) {
+		p.authToken = token
+	}
+}
+
+//WithCompression 开启后，访问远程节点时声明 Accept-Encoding: gzip 并自动解压响应，
+//ServeHTTP 对声明支持 gzip 的请求压缩不小于 WithCompressionMinSize（默认 1KB）的响应。适合值较大的场景
+func WithCompression(enabled bool) PoolOption {
+	return func(p *HTTPPool) {
+		p.compress = enabled
+	}
+}
+
+//WithCompressionMinSize 设置开启压缩后响应需要达到的最小字节数，更小的响应不压缩
+func WithCompressionMinSize(n int) PoolOption {
+	return func(p *HTTPPool) {
+		p.compressMinSize = n
+	}
 }
 
 //baseURL 表示将要访问的远程节点的地址，例如 http://example.com/_gocache/
 type httpGetter struct {
-	baseURL string
+	baseURL  string
+	client   *http.Client
+	token    string
+	compress bool
+	retry    retryPolicy
+	breaker  *circuitBreaker //为 nil 表示不熔断
+	down     int32           //健康检查失败时为 1，通过 sync/atomic 读写
+	peer     string          //节点地址，传给 observer
+	observer func(peer string, latency time.Duration, err error)
+	codec    Codec
+	tracer   Tracer //为 nil 表示不传递追踪上下文
+	//rtt 是成功请求耗时的 EWMA（纳秒，通过 sync/atomic 读写），用于 PeerInfos 和 WithLatencyAwareRouting
+	rtt int64
+	//errMu 保护最近一次失败的错误和时间，见 PeerInfos
+	errMu     sync.Mutex
+	lastErr   error
+	lastErrAt time.Time
+}
+
+//Addr 返回节点地址，用于 span 的 AttrPeer 属性
+func (h *httpGetter) Addr() string {
+	return h.peer
+}
+
+func (h *httpGetter) isDown() bool {
+	return atomic.LoadInt32(&h.down) == 1
+}
+
+//do 发送请求，记录耗时或错误并通知 observer
+func (h *httpGetter) do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := h.guard(req)
+	elapsed := time.Since(start)
+	observed := err
+	if err == nil && (res.StatusCode < 200 || res.StatusCode > 299) {
+		observed = fmt.Errorf("server returned: %v", res.Status)
+	}
+	if observed == nil {
+		h.observeLatency(elapsed)
+	} else {
+		h.setLastError(observed)
+	}
+	if h.observer != nil {
+		h.observer(h.peer, elapsed, observed)
+	}
+	return res, err
+}
+
+//guard 经过熔断器发送请求，熔断器打开时直接返回 ErrCircuitOpen
+func (h *httpGetter) guard(req *http.Request) (*http.Response, error) {
+	if h.breaker == nil {
+		return h.send(req)
+	}
+	if !h.breaker.allow() {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, h.baseURL)
+	}
+	res, err := h.send(req)
+	switch {
+	case req.Context().Err() != nil:
+		//调用方取消或超时不能说明节点是否正常，既不算成功也不算失败
+		h.breaker.release()
+	case err != nil || res.StatusCode >= http.StatusInternalServerError:
+		h.breaker.record(false)
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		h.breaker.record(true)
+	default:
+		//4xx（包括 429）是请求本身的问题或对方在限流，同样不改变熔断器的状态
+		h.breaker.release()
+	}
+	return res, err
+}
+
+//send 为请求加上认证信息后发送，遇到暂时性错误时按 h.retry 重试
+func (h *httpGetter) send(req *http.Request) (*http.Response, error) {
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	req.Header.Set("Accept", h.codec.ContentType())
+	if h.tracer != nil {
+		h.tracer.Inject(req.Context(), req.Header)
+	}
+	//手动设置 Accept-Encoding 后 http.Transport 不会自动解压，由 readBody 处理
+	if h.compress {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		res, err := h.client.Do(req)
+		if attempt >= h.retry.maxRetries || !retryable(ctx, res, err) {
+			return res, err
+		}
+		wait := h.retry.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		//请求体已经被读取，重试前需要重新获取
+		req = req.Clone(ctx)
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+//retryable 判断请求是否值得重试：连接失败、超时等网络错误和 5xx 响应是暂时性的，ctx 结束和 4xx 响应不是
+func retryable(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return res.StatusCode >= http.StatusInternalServerError
+}
+
+//backoff 返回第 attempt 次重试前的等待时间，在 [d/2, d) 之间随机，d = base*2^attempt
+func (r retryPolicy) backoff(attempt int) time.Duration {
+	d := r.base << uint(attempt)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+//readBody 读取响应体，响应使用 gzip 压缩时自动解压
+func readBody(res *http.Response) ([]byte, error) {
+	if res.Header.Get("Content-Encoding") != "gzip" {
+		return ioutil.ReadAll(res.Body)
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
 }
 
 //NewHTTPPool初始化对等方的HTTP池
-func NewHTTPPool(self string) *HTTPPool {
+func NewHTTPPool(self string, opts ...PoolOption) *HTTPPool {
 	defaultBasePath := defultBasePath
-	return &HTTPPool{
-		self:     self,
-		basePath: defaultBasePath,
+	p := &HTTPPool{
+		self:                self,
+		basePath:            defaultBasePath,
+		timeout:             defaultPeerTimeout,
+		compressMinSize:     defaultCompressMinSize,
+		maxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		idleConnTimeout:     defaultIdleConnTimeout,
+		started:             time.Now(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.client == nil {
+		p.client = p.newClient()
+		p.ownClient = true
+	}
+	if p.healthInterval > 0 {
+		p.stopHealth = make(chan struct{})
+		go p.healthLoop()
+	}
+	return p
+}
+
+//healthLoop 每隔 healthInterval 探测一次所有远程节点，直到 stopHealth 被关闭
+func (p *HTTPPool) healthLoop() {
+	ticker := time.NewTicker(p.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkPeers()
+		case <-p.stopHealth:
+			return
+		}
 	}
 }
 
+//checkPeers 并发探测所有远程节点并更新它们的上下线状态
+func (p *HTTPPool) checkPeers() {
+	p.mu.RLock()
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		if peer != p.self {
+			getters[peer] = getter
+		}
+	}
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for peer, getter := range getters {
+		wg.Add(1)
+		go func(peer string, getter *httpGetter) {
+			defer wg.Done()
+			var down int32
+			err := getter.probe(p.healthInterval)
+			if err != nil {
+				down = 1
+				getter.setLastError(err)
+			}
+			if old := atomic.SwapInt32(&getter.down, down); old != down {
+				if down == 1 {
+					p.logf(LevelError, "peer %s is down: %v", peer, err)
+				} else {
+					p.logf(LevelInfo, "peer %s is up", peer)
+				}
+			}
+		}(peer, getter)
+	}
+	wg.Wait()
+}
+
+//probe 请求远程节点的健康检查接口，不经过重试和熔断器，超时时间不超过探测间隔
+func (h *httpGetter) probe(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
+
+//newClient 根据配置创建访问远程节点的 HTTP 客户端，其余配置与 http.DefaultTransport 相同，
+//建立的连接计入 p.conns
+func (p *HTTPPool) newClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = p.maxConnsPerHost
+	transport.MaxIdleConnsPerHost = p.maxIdleConnsPerHost
+	transport.IdleConnTimeout = p.idleConnTimeout
+	if p.timeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: p.timeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = p.timeout
+	}
+	p.conns = newConnTracker()
+	transport.DialContext = p.conns.wrap(dialUnix(transport.DialContext))
+	if p.tlsConfig != nil {
+		transport.TLSClientConfig = p.tlsConfig.Clone()
+	}
+	return &http.Client{Transport: transport, Timeout: p.timeout}
+}
+
+//ListenAndServe 在 addr 上启动节点间通信的 HTTP 服务，设置了 WithTLS 时使用 HTTPS。
+//addr 为 unix:///path/to.sock 时在 Unix domain socket 上监听，启动前删除已经存在的 socket 文件，停止后 socket 文件被删除。
+//调用 Shutdown 后返回 http.ErrServerClosed
+func (p *HTTPPool) ListenAndServe(addr string) error {
+	server := &http.Server{Addr: addr, Handler: p, TLSConfig: p.tlsConfig}
+	p.serveMu.Lock()
+	if p.closed {
+		p.serveMu.Unlock()
+		return ErrPoolClosed
+	}
+	p.server = server
+	p.serveMu.Unlock()
+	if path, ok := socketPath(addr); ok {
+		ln, err := listenUnix(path)
+		if err != nil {
+			return err
+		}
+		if p.tlsConfig == nil {
+			return server.Serve(ln)
+		}
+		return server.ServeTLS(ln, "", "")
+	}
+	if p.tlsConfig == nil {
+		return server.ListenAndServe()
+	}
+	//证书已经在 TLSConfig 中，不需要再传入证书文件
+	return server.ListenAndServeTLS("", "")
+}
+
 /*
 分布式缓存需要实现节点间通信，建立基于 HTTP 的通信机制是比较常见和简单的做法。
 如果一个节点启动了 HTTP 服务，那么这个节点就可以被其他节点访问。
@@ -57,20 +539,207 @@ http.go这节就是为单机节点搭建 HTTP Server。
 */
 //使用服务器名称记录信息
 func (p *HTTPPool) Log(format string, v ...interface{}) {
-	log.Printf("[Server %s] %s", p.self, fmt.Sprintf(format, v))
+	p.logf(LevelInfo, format, v...)
+}
+
+func (p *HTTPPool) logf(level LogLevel, format string, v ...interface{}) {
+	p.logger.logf(level, "[Server %s] %s", p.self, fmt.Sprintf(format, v...))
+}
+
+//healthResponse 是健康检查接口返回的 JSON
+type healthResponse struct {
+	Status        string  `json:"status"`
+	Groups        int     `json:"groups"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+//serveHealth 处理 GET <basePath>health，返回 200 和本节点的基本信息
+func (p *HTTPPool) serveHealth(w http.ResponseWriter) {
+	writeJSON(w, healthResponse{
+		Status:        "ok",
+		Groups:        groupCount(),
+		UptimeSeconds: time.Since(p.started).Seconds(),
+	})
+}
+
+//authorized 检查请求携带的令牌，使用 subtle.ConstantTimeCompare 避免通过响应时间猜测令牌
+func (p *HTTPPool) authorized(r *http.Request) bool {
+	if p.authToken == "" {
+		return true
+	}
+	got := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+p.authToken)) == 1
+}
+
+//splitPath 把 /<basepath>/<groupname>/<key> 拆分为 groupname 和 key。
+//在转义后的路径上按第一个 "/" 拆分，再分别反转义，因此 group 和 key 中可以包含 "/"、"%"、空格和多字节字符；
+//key 中未转义的 "/" 仍然属于 key
+func (p *HTTPPool) splitPath(r *http.Request) ([]string, error) {
+	escaped := r.URL.EscapedPath()
+	if !strings.HasPrefix(escaped, p.basePath) {
+		return nil, fmt.Errorf("unexpected path %s", escaped)
+	}
+	parts := strings.SplitN(escaped[len(p.basePath):], "/", 2)
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = unescaped
+	}
+	return parts, nil
+}
+
+//readRequest 按请求的 Content-Type 解码请求体，失败时返回 400
+func (p *HTTPPool) readRequest(w http.ResponseWriter, r *http.Request, m proto.Message) bool {
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = codecFor(r.Header.Get("Content-Type"), p.codec).Unmarshal(data, m)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+//writeBody 按请求的 Accept 编码响应，开启压缩且请求支持 gzip 时压缩较大的响应。
+//响应的格式随 Accept（开启压缩时还有 Accept-Encoding）变化，通过 Vary 告诉中间的 HTTP 缓存不要混用
+func (p *HTTPPool) writeBody(w http.ResponseWriter, r *http.Request, m proto.Message) {
+	codec := negotiateCodec(r.Header.Get("Accept"), p.codec)
+	body, err := codec.Marshal(m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.Header().Add("Vary", "Accept")
+	if p.compress {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if !p.compress || len(body) < p.compressMinSize || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Write(body)
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	zw := gzip.NewWriter(w)
+	zw.Write(body)
+	zw.Close()
+}
+
+//Shutdown 让本节点平滑下线：不再接受新的节点间请求（返回 503，其他节点会回退或尝试下一个节点），
+//等待正在处理的请求完成，停止健康检查，并关闭访问远程节点的空闲连接。
+//group 不属于 HTTPPool，可能还在本地使用或由其他服务提供，后台清理协程由 DestroyGroup 或调用方的 StopCleanup 停止。
+//ctx 结束时不再等待并返回 ctx.Err()。只能调用一次，再次调用返回 ErrPoolClosed。
+//通过 ListenAndServe 启动的服务也会被关闭，挂载在其他 http.Server 上时需要自行关闭该服务
+func (p *HTTPPool) Shutdown(ctx context.Context) error {
+	p.serveMu.Lock()
+	if p.closed {
+		p.serveMu.Unlock()
+		return ErrPoolClosed
+	}
+	p.closed = true
+	server := p.server
+	p.serveMu.Unlock()
+
+	if p.stopHealth != nil {
+		close(p.stopHealth)
+	}
+
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+	done := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if p.ownClient {
+		p.client.CloseIdleConnections()
+	}
+	return err
 }
 
 //ServeHTTP处理所有http请求
 func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.serveMu.Lock()
+	if p.closed {
+		p.serveMu.Unlock()
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	p.inflight.Add(1)
+	p.serveMu.Unlock()
+	defer p.inflight.Done()
+	p.serve(w, r)
+}
+
+func (p *HTTPPool) serve(w http.ResponseWriter, r *http.Request) {
 	if !strings.HasPrefix(r.URL.Path, p.basePath) {
-		str := "HTTPPool serving unexpected path: " + r.URL.Path
-		fmt.Println("error:" + str)
+		p.logf(LevelError, "HTTPPool serving unexpected path: %s", r.URL.Path)
+		return
+	}
+	if p.tlsConfig != nil && r.TLS == nil {
+		http.Error(w, "TLS required", http.StatusForbidden)
+		return
+	}
+	//健康检查不需要令牌，方便负载均衡器等外部系统探测，也不记录日志
+	if r.Method == http.MethodGet && r.URL.Path == p.basePath+healthPath {
+		p.serveHealth(w)
+		return
+	}
+	//先检查令牌，未授权的请求不消耗限流的令牌，避免被用来耗尽合法节点的配额
+	if !p.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if !p.allowRequest(r) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+	if p.tracer != nil {
+		r = r.WithContext(p.tracer.Extract(r.Context(), r.Header))
+	}
 	p.Log("%s %s", r.Method, r.URL.Path)
+	if p.admin && strings.HasPrefix(r.URL.Path, p.basePath+adminPath) {
+		p.serveAdmin(w, r, r.URL.Path[len(p.basePath+adminPath):])
+		return
+	}
+
+	if r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, p.basePath+tagPath) {
+		p.serveInvalidateTag(w, r)
+		return
+	}
 
 	// /<basepath>/<groupname>/<key> 必填
-	parts := strings.SplitN(string(r.URL.Path[len(p.basePath)]), "/", 2)
+	parts, err := p.splitPath(r)
+	if err != nil {
+		http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// POST /<basepath>/<groupname> 为批量获取，请求体为 pb.MultiRequest
+	if len(parts) == 1 && r.Method == http.MethodPost {
+		p.serveMulti(w, r, parts[0])
+		return
+	}
+	// DELETE /<basepath>/<groupname> 清空本节点上该 group 的缓存，不再转发给其他节点
+	if len(parts) == 1 && r.Method == http.MethodDelete {
+		group := GetGroup(parts[0])
+		if group == nil {
+			http.Error(w, "no such group:"+parts[0], http.StatusNotFound)
+			return
+		}
+		group.Clear()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	if len(parts) != 2 {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
@@ -82,35 +751,122 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "no such group:"+groupName, http.StatusNotFound)
 		return
 	}
-	view, err := group.Get(key)
+	if key == "" {
+		http.Error(w, ErrKeyRequired.Error(), http.StatusBadRequest)
+		return
+	}
+	//PUT/POST 请求写入本节点的缓存，请求体为 pb.SetRequest，不再转发给其他节点
+	if r.Method == http.MethodPut || r.Method == http.MethodPost {
+		req := &pb.SetRequest{}
+		if !p.readRequest(w, r, req) {
+			return
+		}
+		if err = group.SetLocalWithMeta(key, req.GetValue(), req.GetMeta(), req.GetTags()...); err != nil {
+			http.Error(w, err.Error(), statusFor(err))
+			return
+		}
+		p.writeBody(w, r, &pb.SetResponse{})
+		return
+	}
+	//DELETE 请求只删除本节点的缓存，不再转发给其他节点，key 不存在时同样返回 204，重复删除是安全的
+	if r.Method == http.MethodDelete {
+		group.DeleteLocal(key)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	view, err := group.GetContext(r.Context(), key)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), statusFor(err))
+		return
 	}
 	//w.Header().Set("Content-Type", "application/octet-stream")
 	//w.Write(view.ByteSlice())
-	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	p.writeBody(w, r, &pb.Response{Value: view.ByteSlice(), Meta: view.meta})
+}
+
+//serveMulti 处理批量获取请求，单个 key 失败时记录在 pb.MultiResponse.Errors 中，不影响其他 key
+func (p *HTTPPool) serveMulti(w http.ResponseWriter, r *http.Request, groupName string) {
+	group := GetGroup(groupName)
+	if group == nil {
+		http.Error(w, "no such group:"+groupName, http.StatusNotFound)
+		return
+	}
+	req := &pb.MultiRequest{}
+	if !p.readRequest(w, r, req) {
 		return
 	}
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Write(body)
+	res := &pb.MultiResponse{
+		Values: make(map[string][]byte, len(req.GetKeys())),
+		Errors: make(map[string]string),
+		Meta:   make(map[string]*pb.Meta),
+	}
+	for _, key := range req.GetKeys() {
+		view, err := group.GetContext(r.Context(), key)
+		if err != nil {
+			res.Errors[key] = err.Error()
+			continue
+		}
+		res.Values[key] = view.ByteSlice()
+		if view.meta != nil {
+			res.Meta[key] = &pb.Meta{Values: view.meta}
+		}
+	}
+	p.writeBody(w, r, res)
+}
+
+//statusFor 返回请求失败时的状态码：数据源中不存在的 key 返回 404，空 key 或不合法的 key 返回 400，值太大返回 413，其他错误返回 500
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrKeyRequired), errors.Is(err, ErrInvalidKey):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrValueTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+//serveInvalidateTag 删除本节点上带有标签的记录，响应为 pb.TagResponse，group 和 tag 分别按路径片段转义
+func (p *HTTPPool) serveInvalidateTag(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(r.URL.EscapedPath()[len(p.basePath+tagPath):], "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		parts[i] = unescaped
+	}
+	group := GetGroup(parts[0])
+	if group == nil {
+		http.Error(w, "no such group:"+parts[0], http.StatusNotFound)
+		return
+	}
+	p.writeBody(w, r, &pb.TagResponse{Keys: group.InvalidateTagLocal(parts[1])})
 }
 
 //节点选择与 HTTP 客户端
 
 //使用 http.Get() 方式获取返回值，并转换为 []bytes 类型。
 //func (h *httpGetter) Get(group string, key string) ([]byte, error)
-func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
+func (h *httpGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
 	//u := fmt.Sprintf("%v%v/%v", h.baseURL, url.QueryEscape(group), url.QueryEscape(key))
 	//res, err := http.Get(u)
-	u := fmt.Sprintf(
-		"%v%v/%v",
-		h.baseURL,
-		url.QueryEscape(in.GetGroup()),
-		url.QueryEscape(in.GetKey()),
-	)
-	res, err := http.Get(u)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.keyURL(in.GetGroup(), in.GetKey()), nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(req)
 	if err != nil {
 		return err
 	}
@@ -118,46 +874,319 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 	if res.StatusCode != http.StatusOK {
 		return fmt.Errorf("server returned:%v", res.Status)
 	}
-	bytes, err := ioutil.ReadAll(res.Body)
+	//return bytes, nil
+	return h.decode(res, out)
+}
+
+//使用 POST 方法一次从远程节点获取多个 key，请求体为 pb.MultiRequest
+func (h *httpGetter) GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error {
+	body, err := h.codec.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+	u := h.baseURL + url.PathEscape(in.GetGroup())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", h.codec.ContentType())
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned:%v", res.Status)
+	}
+	return h.decode(res, out)
+}
+
+//使用 PUT 方法把缓存值写入远程节点，请求体为 pb.SetRequest
+func (h *httpGetter) Set(in *pb.SetRequest, out *pb.SetResponse) error {
+	body, err := h.codec.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPut, h.keyURL(in.GetGroup(), in.GetKey()), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", h.codec.ContentType())
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned:%v", res.Status)
+	}
+	return h.decode(res, out)
+}
+
+//使用 DELETE 方法通知远程节点删除缓存值
+func (h *httpGetter) Delete(in *pb.Request) error {
+	req, err := http.NewRequest(http.MethodDelete, h.keyURL(in.GetGroup(), in.GetKey()), nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned:%v", res.Status)
+	}
+	return nil
+}
+
+//使用 DELETE 方法通知远程节点清空整个 group 的缓存
+func (h *httpGetter) Clear(in *pb.Request) error {
+	req, err := http.NewRequest(http.MethodDelete, h.baseURL+url.PathEscape(in.GetGroup()), nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned:%v", res.Status)
+	}
+	return nil
+}
+
+//decode 按响应的 Content-Type 解码响应体，早期版本的节点总是返回 protobuf
+func (h *httpGetter) decode(res *http.Response, out proto.Message) error {
+	b, err := readBody(res)
 	if err != nil {
 		return fmt.Errorf("reading response body:%v", err)
 	}
-	//return bytes, nil
-	if err = proto.Unmarshal(bytes, out); err != nil {
+	if err = codecFor(res.Header.Get("Content-Type"), h.codec).Unmarshal(b, out); err != nil {
 		return fmt.Errorf("decoding response body: %v", err)
 	}
-
 	return nil
 }
 
+//使用 DELETE 方法通知远程节点删除带有标签的记录，响应为 pb.TagResponse
+func (h *httpGetter) InvalidateTag(in *pb.TagRequest, out *pb.TagResponse) error {
+	u := h.baseURL + tagPath + url.PathEscape(in.GetGroup()) + "/" + url.PathEscape(in.GetTag())
+	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned:%v", res.Status)
+	}
+	return h.decode(res, out)
+}
+
+//keyURL 返回远程节点上 group/key 对应的地址，group 和 key 分别按路径片段转义，"/" 被转义为 %2F
+func (h *httpGetter) keyURL(group string, key string) string {
+	return fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		url.PathEscape(group),
+		url.PathEscape(key),
+	)
+}
+
 var _ PeerGetter = (*httpGetter)(nil)
 
 //实现 PeerPicker 接口
 
-//Set() 方法实例化了一致性哈希算法，并且添加了传入的节点
+//Set() 把节点列表更新为 peers：不在 peers 中的节点通过 RemovePeer 移除，新的节点通过 AddPeer 加入，
+//仍然存在的节点保留原有的 httpGetter（包括熔断器和健康检查状态），哈希环上只有变化的节点负责的 key 会迁移。
+//节点地址可以是 http://host:port、https://host:port，或者同一台机器上的 unix:///path/to.sock，
+//后者通过 Unix domain socket 访问，省去 TCP 回环的开销；unix 地址只能用于 HTTPPool 自己创建的客户端，见 WithHTTPClient
 func (p *HTTPPool) Set(peers ...string) {
+	keep := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		keep[peer] = true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for peer := range p.httpGetters {
+		if !keep[peer] {
+			p.removePeer(peer)
+		}
+	}
+	for _, peer := range peers {
+		p.addPeer(peer)
+	}
+}
+
+//AddPeer 把节点 addr 加入哈希环并为它创建 httpGetter，已经存在时什么也不做
+func (p *HTTPPool) AddPeer(addr string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	p.peers.Add(peers...)
+	p.addPeer(addr)
+}
+
+//RemovePeer 把节点 addr 从哈希环中移除，之后选择节点时不会再返回它，不存在时什么也不做
+func (p *HTTPPool) RemovePeer(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removePeer(addr)
+}
+
+//addPeer 需要持有 p.mu 的写锁
+func (p *HTTPPool) addPeer(peer string) {
+	if _, ok := p.httpGetters[peer]; ok {
+		return
+	}
+	if p.peers == nil {
+		if p.newRing != nil {
+			p.peers = p.newRing()
+		} else {
+			p.peers = consistenthash.New(defaultReplicas, nil)
+		}
+		p.httpGetters = make(map[string]*httpGetter)
+	}
+	p.peers.Add(peer)
 
 	//并为每一个节点创建了一个 HTTP 客户端 httpGetter
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
-	for _, peer := range peers {
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
+	getter := &httpGetter{
+		baseURL:  peerURL(peer) + p.basePath,
+		client:   p.client,
+		token:    p.authToken,
+		compress: p.compress,
+		retry:    p.retry,
+		peer:     peer,
+		observer: p.observer,
+		codec:    p.codec,
+		tracer:   p.tracer,
+	}
+	if getter.codec == nil {
+		getter.codec = ProtobufCodec{}
+	}
+	if p.breakerThreshold > 0 {
+		getter.breaker = newCircuitBreaker(p.breakerThreshold, p.breakerCooldown)
 	}
+	p.httpGetters[peer] = getter
+}
+
+//removePeer 需要持有 p.mu 的写锁
+func (p *HTTPPool) removePeer(peer string) {
+	if _, ok := p.httpGetters[peer]; !ok {
+		return
+	}
+	p.peers.Remove(peer)
+	delete(p.httpGetters, peer)
 }
 
 //PickerPeer() 包装了一致性哈希算法的 Get() 方法，根据具体的 key，选择节点，返回节点对应的 HTTP 客户端。
 //HTTPPool 既具备了提供 HTTP 服务的能力，也具备了根据具体的 key，创建 HTTP 客户端从远程节点获取缓存值的能力。
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
-		p.Log("Pick peer %s", peer)
-		return p.httpGetters[peer], true
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.peers == nil {
+		return nil, false
+	}
+	if p.latencyAware {
+		if peer, local := p.nearestPeer(key); local {
+			return nil, false
+		} else if peer != "" {
+			p.logf(LevelDebug, "Pick nearest peer %s", peer)
+			return p.httpGetters[peer], true
+		}
+	}
+	peer := p.peers.Get(key)
+	if peer == "" || peer == p.self {
+		return nil, false
+	}
+	getter := p.httpGetters[peer]
+	if getter.isDown() {
+		//负责 key 的节点已下线，交给哈希环上的下一个在线节点，下一个是本节点时在本地加载
+		getter = nil
+		for _, next := range p.peers.GetN(key, len(p.httpGetters)) {
+			if next == p.self {
+				return nil, false
+			}
+			if g := p.httpGetters[next]; !g.isDown() {
+				peer, getter = next, g
+				break
+			}
+		}
+		if getter == nil {
+			return nil, false
+		}
+	}
+	p.logf(LevelDebug, "Pick peer %s", peer)
+	return getter, true
+}
+
+//PickPeers 包装了一致性哈希算法的 GetN() 方法，按哈希环的顺序返回最多 n 个远程节点，跳过本节点
+func (p *HTTPPool) PickPeers(key string, n int) []PeerGetter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.peers == nil {
+		return nil
+	}
+	var getters []PeerGetter
+	//多取一个节点，本节点被跳过时仍能凑够 n 个；有节点下线时需要取出所有节点
+	limit := n + 1
+	if p.healthInterval > 0 {
+		limit = len(p.httpGetters)
+	}
+	for _, peer := range p.peers.GetN(key, limit) {
+		if peer != p.self && len(getters) < n && !p.httpGetters[peer].isDown() {
+			getters = append(getters, p.httpGetters[peer])
+		}
+	}
+	if p.latencyAware {
+		sortByLatency(getters)
+	}
+	return getters
+}
+
+//DownPeers 返回健康检查标记为下线的节点，按地址排序，没有开启 WithHealthCheck 时返回 nil
+func (p *HTTPPool) DownPeers() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var down []string
+	for peer, getter := range p.httpGetters {
+		if getter.isDown() {
+			down = append(down, peer)
+		}
+	}
+	sort.Strings(down)
+	return down
+}
+
+//CircuitStates 返回访问每个远程节点的熔断器状态，没有开启 WithCircuitBreaker 时返回 nil
+func (p *HTTPPool) CircuitStates() map[string]CircuitState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.breakerThreshold <= 0 {
+		return nil
+	}
+	states := make(map[string]CircuitState, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		if peer != p.self {
+			states[peer] = getter.breaker.State()
+		}
+	}
+	return states
+}
+
+//Peers 返回除本节点以外所有节点的 HTTP 客户端
+func (p *HTTPPool) Peers() []PeerGetter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var getters []PeerGetter
+	for peer, getter := range p.httpGetters {
+		if peer != p.self {
+			getters = append(getters, getter)
+		}
 	}
-	return nil, false
+	return getters
 }
 
 var _ PeerPicker = (*HTTPPool)(nil)
@@ -0,0 +1,1220 @@
+package GoCache
+
+import (
+	pb "GoCache/gocachepb"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//newTestTLSConfig 生成自签名 CA 和一张同时用于服务端与客户端的 127.0.0.1 证书，开启双向 TLS
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gocache test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "gocache node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leafDER}, PrivateKey: key}},
+		RootCAs:      roots,
+		ClientCAs:    roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+func TestTLS(t *testing.T) {
+	NewGroup("tls", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte("v:" + key), nil }))
+	defer DestroyGroup("tls")
+	cfg := newTestTLSConfig(t)
+
+	server := httptest.NewUnstartedServer(NewHTTPPool("server", WithTLS(cfg), WithPoolLogLevel(LevelSilent)))
+	server.TLS = cfg
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewHTTPPool("client", WithTLS(cfg))
+	client.Set(server.URL)
+	out := &pb.Response{}
+	err := client.httpGetters[server.URL].Get(context.Background(), &pb.Request{Group: "tls", Key: "Tom"}, out)
+	if err != nil || string(out.GetValue()) != "v:Tom" {
+		t.Fatalf("mutual TLS request failed: %v", err)
+	}
+
+	//没有客户端证书时握手失败
+	noCert := &tls.Config{RootCAs: cfg.RootCAs}
+	anonymous := NewHTTPPool("anonymous", WithTLS(noCert))
+	anonymous.Set(server.URL)
+	if err := anonymous.httpGetters[server.URL].Get(context.Background(), &pb.Request{Group: "tls", Key: "Tom"}, out); err == nil {
+		t.Fatal("request without client certificate should fail")
+	}
+
+	//开启 TLS 后拒绝明文请求
+	w := httptest.NewRecorder()
+	NewHTTPPool("plain", WithTLS(cfg)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_gocache/tls/Tom", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("plaintext request should be rejected, got %d", w.Code)
+	}
+}
+
+func TestPeerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := NewHTTPPool("client", WithTimeout(50*time.Millisecond))
+	p.Set(server.URL)
+	start := time.Now()
+	err := p.httpGetters[server.URL].Get(context.Background(), &pb.Request{Group: "g", Key: "k"}, &pb.Response{})
+	if err == nil || time.Since(start) > time.Second {
+		t.Fatalf("hung peer should time out, got %v after %v", err, time.Since(start))
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	NewGroup("auth", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte("v:" + key), nil }))
+	defer DestroyGroup("auth")
+	server := httptest.NewServer(NewHTTPPool("server", WithAuthToken("secret"), WithPoolLogLevel(LevelSilent)))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		token string
+		ok    bool
+	}{
+		{"secret", true},
+		{"wrong", false},
+		{"", false},
+	} {
+		p := NewHTTPPool("client", WithAuthToken(tt.token))
+		p.Set(server.URL)
+		out := &pb.Response{}
+		err := p.httpGetters[server.URL].Get(context.Background(), &pb.Request{Group: "auth", Key: "Tom"}, out)
+		if (err == nil) != tt.ok {
+			t.Fatalf("token %q: expect ok=%v, got %v", tt.token, tt.ok, err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	NewHTTPPool("server", WithAuthToken("secret")).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_gocache/auth/Tom", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("request without token should get 401, got %d", w.Code)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("gocache ", 1000)
+	NewGroup("gzip", 2<<20, GetterFunc(func(key string) ([]byte, error) {
+		if key == "large" {
+			return []byte(large), nil
+		}
+		return []byte("small"), nil
+	}))
+	defer DestroyGroup("gzip")
+	var encodings []string
+	pool := NewHTTPPool("server", WithCompression(true), WithPoolLogLevel(LevelSilent))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		pool.ServeHTTP(rec, r)
+		encodings = append(encodings, rec.Header().Get("Content-Encoding"))
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	defer server.Close()
+
+	p := NewHTTPPool("client", WithCompression(true))
+	p.Set(server.URL)
+	for _, key := range []string{"large", "small"} {
+		out := &pb.Response{}
+		if err := p.httpGetters[server.URL].Get(context.Background(), &pb.Request{Group: "gzip", Key: key}, out); err != nil {
+			t.Fatal(err)
+		}
+		if expect, _ := GetGroup("gzip").Get(key); string(out.GetValue()) != expect.String() {
+			t.Fatalf("%s: value changed after gzip round trip", key)
+		}
+	}
+	//小于 1KB 的响应不压缩
+	if !reflect.DeepEqual(encodings, []string{"gzip", ""}) {
+		t.Fatalf("expect only the large response to be compressed, got %q", encodings)
+	}
+}
+
+func TestEscapedKeys(t *testing.T) {
+	NewGroup("escape group", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte("v:" + key), nil }))
+	defer DestroyGroup("escape group")
+	server := httptest.NewServer(NewHTTPPool("server", WithPoolLogLevel(LevelSilent)))
+	defer server.Close()
+	p := NewHTTPPool("client")
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+
+	for _, key := range []string{"path/to/thing", "100%", "a b+c", "键/值", "/leading", "?query#frag"} {
+		out := &pb.Response{}
+		if err := getter.Get(context.Background(), &pb.Request{Group: "escape group", Key: key}, out); err != nil {
+			t.Fatalf("%q: %v", key, err)
+		}
+		if string(out.GetValue()) != "v:"+key {
+			t.Fatalf("%q: server saw key %q", key, out.GetValue())
+		}
+	}
+}
+
+//countingTransport 记录经过的请求数
+type countingTransport struct {
+	n int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.n++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHTTPClient(t *testing.T) {
+	NewGroup("client", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("client")
+	server := httptest.NewServer(NewHTTPPool("server", WithPoolLogLevel(LevelSilent)))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	p := NewHTTPPool("client", WithHTTPClient(&http.Client{Transport: transport}))
+	p.Set(server.URL, "http://127.0.0.1:1")
+	for _, getter := range p.httpGetters {
+		if getter.client != p.client {
+			t.Fatal("every httpGetter should share the injected client")
+		}
+	}
+	if err := p.httpGetters[server.URL].Get(context.Background(), &pb.Request{Group: "client", Key: "Tom"}, &pb.Response{}); err != nil {
+		t.Fatal(err)
+	}
+	if transport.n != 1 {
+		t.Fatalf("expect the request to go through the injected client, got %d", transport.n)
+	}
+}
+
+func TestPeerRetry(t *testing.T) {
+	var calls int32
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//前两次请求失败
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		w.Write(nil)
+	}))
+	defer server.Close()
+	p := NewHTTPPool("client", WithPeerRetry(3, time.Millisecond))
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+
+	if err := getter.Set(&pb.SetRequest{Group: "g", Key: "k", Value: []byte("v")}, &pb.SetResponse{}); err != nil || calls != 3 {
+		t.Fatalf("5xx should be retried until success, got %v after %d calls", err, calls)
+	}
+
+	//4xx 不重试
+	calls, status = 0, http.StatusNotFound
+	if err := getter.Get(context.Background(), &pb.Request{Group: "g", Key: "k"}, &pb.Response{}); err == nil || calls != 1 {
+		t.Fatalf("4xx should fail fast, got %v after %d calls", err, calls)
+	}
+
+	//剩余时间不够等待下一次重试时直接返回
+	calls, status = 0, http.StatusServiceUnavailable
+	p = NewHTTPPool("client", WithPeerRetry(3, time.Second))
+	p.Set(server.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := p.httpGetters[server.URL].Get(ctx, &pb.Request{Group: "g", Key: "k"}, &pb.Response{}); err == nil || calls != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("retry should respect the deadline, got %v after %d calls in %v", err, calls, time.Since(start))
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls int32
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(nil)
+	}))
+	defer server.Close()
+	p := NewHTTPPool("client", WithCircuitBreaker(2, 50*time.Millisecond))
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+	get := func() error {
+		return getter.Get(context.Background(), &pb.Request{Group: "g", Key: "k"}, &pb.Response{})
+	}
+
+	//连续两次失败后打开，之后的请求不再发出
+	get()
+	get()
+	if err := get(); !errors.Is(err, ErrCircuitOpen) || calls != 2 {
+		t.Fatalf("circuit should be open after 2 failures, got %v after %d calls", err, calls)
+	}
+	if state := p.CircuitStates()[server.URL]; state != CircuitOpen {
+		t.Fatalf("state = %v, want open", state)
+	}
+
+	//冷却期结束后放行一个探测请求，失败后重新打开
+	time.Sleep(60 * time.Millisecond)
+	if err := get(); err == nil || errors.Is(err, ErrCircuitOpen) || calls != 3 {
+		t.Fatalf("half-open circuit should let one probe through, got %v after %d calls", err, calls)
+	}
+	if err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("failed probe should reopen the circuit, got %v", err)
+	}
+
+	//探测成功后恢复
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(60 * time.Millisecond)
+	if err := get(); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if state := p.CircuitStates()[server.URL]; state != CircuitClosed {
+		t.Fatalf("state = %v, want closed", state)
+	}
+}
+
+func TestCircuitBreakerCallerDeadline(t *testing.T) {
+	var slow int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	p := NewHTTPPool("client", WithCircuitBreaker(3, 50*time.Millisecond))
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+	get := func(timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return getter.Get(ctx, &pb.Request{Group: "g", Key: "k"}, &pb.Response{})
+	}
+	state := func() CircuitState { return p.CircuitStates()[server.URL] }
+
+	//调用方的超时比节点的响应时间短，这些请求不算成功，不会清零之前的失败次数
+	get(time.Second)
+	get(time.Second)
+	atomic.StoreInt32(&slow, 1)
+	for i := 0; i < 10; i++ {
+		if err := get(20 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expect caller deadline, got %v", err)
+		}
+	}
+	atomic.StoreInt32(&slow, 0)
+	get(time.Second)
+	if s := state(); s != CircuitOpen {
+		t.Fatalf("timed out requests should not reset failures, state = %v", s)
+	}
+
+	//超时的探测请求不会关闭熔断器，下一个请求重新探测
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&slow, 1)
+	if err := get(20 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("probe should reach the peer, got %v", err)
+	}
+	if s := state(); s == CircuitClosed {
+		t.Fatal("timed out probe should not close the circuit")
+	}
+	atomic.StoreInt32(&slow, 0)
+	if err := get(time.Second); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("next request should be let through as a new probe, got %v", err)
+	}
+	if s := state(); s != CircuitOpen {
+		t.Fatalf("failed probe should reopen the circuit, state = %v", s)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	up := httptest.NewServer(NewHTTPPool("up"))
+	defer up.Close()
+	res, err := http.Get(up.URL + defultBasePath + healthPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health healthResponse
+	err = json.NewDecoder(res.Body).Decode(&health)
+	res.Body.Close()
+	if err != nil || res.StatusCode != http.StatusOK || health.Status != "ok" || health.Groups != groupCount() {
+		t.Fatalf("health = %+v, %v (status %d)", health, err, res.StatusCode)
+	}
+
+	var healthy int32 = 1
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(nil)
+	}))
+	defer flaky.Close()
+	p := NewHTTPPool("self", WithHealthCheck(10*time.Millisecond))
+	p.Set("self", up.URL, flaky.URL)
+
+	//找一个属于 flaky 的 key
+	var key string
+	for i := 0; ; i++ {
+		key = strconv.Itoa(i)
+		if getter, ok := p.PickPeer(key); ok && getter == p.httpGetters[flaky.URL] {
+			break
+		}
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	waitFor(t, func() bool { return reflect.DeepEqual(p.DownPeers(), []string{flaky.URL}) })
+	if getter, ok := p.PickPeer(key); ok && getter == p.httpGetters[flaky.URL] {
+		t.Fatal("PickPeer should skip a peer marked down")
+	}
+	for _, getter := range p.PickPeers(key, 2) {
+		if getter == p.httpGetters[flaky.URL] {
+			t.Fatal("PickPeers should skip a peer marked down")
+		}
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	waitFor(t, func() bool { return len(p.DownPeers()) == 0 })
+	if getter, ok := p.PickPeer(key); !ok || getter != p.httpGetters[flaky.URL] {
+		t.Fatal("peer should be picked again once it is back up")
+	}
+}
+
+//waitFor 每隔 5ms 检查一次 cond，1s 内没有满足时测试失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	NewGroup("drain", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		close(entered)
+		<-release
+		return []byte("v:" + key), nil
+	}))
+	defer DestroyGroup("drain")
+	cleaning := NewGroupWithCleanup("drain-cleanup", 2<<10, GetterFunc(func(key string) ([]byte, error) { return nil, ErrNotFound }), time.Hour)
+	defer DestroyGroup("drain-cleanup")
+	p := NewHTTPPool("self", WithHealthCheck(time.Hour))
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	inflight := make(chan int)
+	go func() {
+		res, err := http.Get(server.URL + defultBasePath + "drain/k")
+		if err != nil {
+			inflight <- 0
+			return
+		}
+		res.Body.Close()
+		inflight <- res.StatusCode
+	}()
+	<-entered
+
+	shutdown := make(chan error)
+	go func() { shutdown <- p.Shutdown(context.Background()) }()
+	waitFor(t, func() bool {
+		res, err := http.Get(server.URL + defultBasePath + "drain/other")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusServiceUnavailable
+	})
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the in-flight request finished", err)
+	default:
+	}
+
+	close(release)
+	if code := <-inflight; code != http.StatusOK {
+		t.Fatalf("in-flight request should complete, got status %d", code)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Shutdown(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("second Shutdown = %v, want ErrPoolClosed", err)
+	}
+	//Shutdown 只停止 HTTPPool 自己的协程，group 的后台清理继续运行
+	cleaning.mainCache.mu.Lock()
+	running := cleaning.mainCache.stop != nil
+	cleaning.mainCache.mu.Unlock()
+	if !running {
+		t.Fatal("Shutdown should not stop the cleanup of groups")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	NewGroup("drain-deadline", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		close(entered)
+		<-release
+		return nil, nil
+	}))
+	defer DestroyGroup("drain-deadline")
+	p := NewHTTPPool("self")
+	server := httptest.NewServer(p)
+	defer server.Close()
+	//先让 getter 返回，server.Close 才能结束
+	defer close(release)
+	go func() {
+		if res, err := http.Get(server.URL + defultBasePath + "drain-deadline/k"); err == nil {
+			res.Body.Close()
+		}
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestPeerObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			http.Error(w, "nope", http.StatusNotFound)
+			return
+		}
+		w.Write(nil)
+	}))
+	defer server.Close()
+	var mu sync.Mutex
+	var errs []error
+	p := NewHTTPPool("client", WithPeerObserver(func(peer string, latency time.Duration, err error) {
+		if peer != server.URL || latency <= 0 {
+			t.Errorf("unexpected observation %s %v", peer, latency)
+		}
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+	getter.Set(&pb.SetRequest{Group: "g", Key: "k", Value: []byte("v")}, &pb.SetResponse{})
+	getter.Delete(&pb.Request{Group: "g", Key: "k"})
+	if len(errs) != 2 || errs[0] != nil || errs[1] == nil {
+		t.Fatalf("observed errors = %v, want [nil, non-2xx]", errs)
+	}
+}
+
+func TestLatencyAwareRouting(t *testing.T) {
+	server := func(delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.Write(nil)
+		}))
+	}
+	fast, slow, slower := server(0), server(20*time.Millisecond), server(40*time.Millisecond)
+	defer fast.Close()
+	defer slow.Close()
+	defer slower.Close()
+	p := NewHTTPPool("self", WithLatencyAwareRouting(true))
+	p.Set("self", fast.URL, slow.URL, slower.URL)
+	for _, peer := range []string{slower.URL, slow.URL, fast.URL} {
+		p.httpGetters[peer].Set(&pb.SetRequest{Group: "g", Key: "k"}, &pb.SetResponse{})
+	}
+	latencies := p.Latencies()
+	if len(latencies) != 3 || latencies[fast.URL] >= latencies[slow.URL] || latencies[slow.URL] >= latencies[slower.URL] {
+		t.Fatalf("unexpected latencies %v", latencies)
+	}
+
+	var remote, local int
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		getter, ok := p.PickPeer(key)
+		if !ok {
+			local++
+			continue
+		}
+		remote++
+		if getter != p.httpGetters[fast.URL] {
+			t.Fatalf("%s: expect the nearest peer %s, got %s", key, fast.URL, getter.(*httpGetter).peer)
+		}
+		if peers := p.PickPeers(key, 3); peers[0] != getter {
+			t.Fatalf("%s: PickPeers should be sorted by latency", key)
+		}
+	}
+	if remote == 0 || local == 0 {
+		t.Fatalf("expect both local and remote picks, got %d local, %d remote", local, remote)
+	}
+
+	if NewHTTPPool("self").Latencies() != nil {
+		t.Fatal("Latencies should be nil without WithLatencyAwareRouting")
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	var active, peak int32
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		arrived <- struct{}{}
+		<-release
+		w.Write(nil)
+	}))
+	defer server.Close()
+	p := NewHTTPPool("self", WithMaxConnsPerHost(2), WithIdleConnTimeout(time.Minute))
+	p.Set("self", server.URL)
+	getter := p.httpGetters[server.URL]
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- getter.Set(&pb.SetRequest{Group: "g", Key: strconv.Itoa(i)}, &pb.SetResponse{})
+		}(i)
+	}
+	<-arrived
+	<-arrived
+	//超出上限的请求排队等待，而不是建立新连接或返回错误
+	time.Sleep(50 * time.Millisecond)
+	if got := p.PeerConns()[server.URL]; got != 2 {
+		t.Fatalf("PeerConns = %d, want 2", got)
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if peak != 2 {
+		t.Fatalf("peak concurrent requests = %d, want 2", peak)
+	}
+	if got := p.PeerConns()[server.URL]; got != 2 {
+		t.Fatalf("idle connections should be kept, PeerConns = %d", got)
+	}
+	p.client.CloseIdleConnections()
+	if got := p.PeerConns()[server.URL]; got != 0 {
+		t.Fatalf("PeerConns after closing idle connections = %d", got)
+	}
+
+	if NewHTTPPool("self", WithHTTPClient(http.DefaultClient)).PeerConns() != nil {
+		t.Fatal("PeerConns should be nil with WithHTTPClient")
+	}
+}
+
+func TestUnixSocketPeer(t *testing.T) {
+	NewGroup("unix-test", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte("v:" + key), nil }))
+	defer DestroyGroup("unix-test")
+	sock := filepath.Join(t.TempDir(), "peer.sock")
+	addr := "unix://" + sock
+	server := NewHTTPPool(addr, WithPoolLogLevel(LevelSilent))
+	served := make(chan error, 1)
+	go func() { served <- server.ListenAndServe(addr) }()
+	waitFor(t, func() bool {
+		_, err := os.Stat(sock)
+		return err == nil
+	})
+
+	p := NewHTTPPool("http://self", WithPoolLogLevel(LevelSilent))
+	p.Set("http://self", addr)
+	//socket 路径每次运行都不同，找一个由 unix 节点负责的 key
+	key := "Tom"
+	for i := 0; p.OwnerOf(key) != addr; i++ {
+		key = "Tom" + strconv.Itoa(i)
+	}
+	getter, ok := p.PickPeer(key)
+	if !ok {
+		t.Fatal("expect the unix peer to be picked")
+	}
+	out := &pb.Response{}
+	if err := getter.Get(context.Background(), &pb.Request{Group: "unix-test", Key: key}, out); err != nil || string(out.GetValue()) != "v:"+key {
+		t.Fatalf("Get over unix socket = %q, %v", out.GetValue(), err)
+	}
+	if conns := p.PeerConns(); conns[addr] != 1 {
+		t.Fatalf("PeerConns = %v, want 1 connection to %s", conns, addr)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Fatalf("ListenAndServe returned %v", err)
+	}
+	if _, err := os.Stat(sock); !os.IsNotExist(err) {
+		t.Fatalf("socket file should be removed after Shutdown, got %v", err)
+	}
+}
+
+func TestPeerInfos(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.Write(nil)
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	if owner := NewHTTPPool("self").OwnerOf("Tom"); owner != "" {
+		t.Fatalf("OwnerOf without peers = %q", owner)
+	}
+	p := NewHTTPPool("self", WithPoolLogLevel(LevelSilent))
+	p.Set("self", ok.URL, bad.URL)
+	p.httpGetters[ok.URL].Set(&pb.SetRequest{Group: "g", Key: "k"}, &pb.SetResponse{})
+	if err := p.httpGetters[bad.URL].Set(&pb.SetRequest{Group: "g", Key: "k"}, &pb.SetResponse{}); err == nil {
+		t.Fatal("expect an error from the failing peer")
+	}
+
+	infos := p.PeerInfos()
+	if len(infos) != 3 || !sort.SliceIsSorted(infos, func(i, j int) bool { return infos[i].Addr < infos[j].Addr }) {
+		t.Fatalf("unexpected peers %+v", infos)
+	}
+	byAddr := make(map[string]PeerInfo)
+	for _, info := range infos {
+		byAddr[info.Addr] = info
+	}
+	if info := byAddr[ok.URL]; !info.Up || info.LastError != nil || info.Latency <= 0 || info.Conns != 1 {
+		t.Fatalf("unexpected healthy peer %+v", info)
+	}
+	if info := byAddr[bad.URL]; info.LastError == nil || info.LastErrorAt.IsZero() || info.Latency != 0 {
+		t.Fatalf("unexpected failing peer %+v", info)
+	}
+	if info := byAddr["self"]; !info.Self {
+		t.Fatalf("expect self in PeerInfos, got %+v", info)
+	}
+
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i)
+		owner := p.OwnerOf(key)
+		getter, remote := p.PickPeer(key)
+		if remote != (owner != "self") || remote && getter.(*httpGetter).peer != owner {
+			t.Fatalf("%s: OwnerOf = %s disagrees with PickPeer", key, owner)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var loads int32
+	NewGroup("ratelimit", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		return []byte(key), nil
+	}))
+	defer DestroyGroup("ratelimit")
+	serve := func(p *HTTPPool, path, remote string) int {
+		req := httptest.NewRequest(http.MethodGet, defultBasePath+path, nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	p := NewHTTPPool("self", WithRateLimit(1, 2), WithPoolLogLevel(LevelSilent))
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		if code := serve(p, "ratelimit/k"+strconv.Itoa(i), "10.0.0.1:1234"); code != want {
+			t.Fatalf("request %d returned %d, want %d", i, code, want)
+		}
+	}
+	//被拒绝的请求不会访问缓存或调用 getter
+	if loads != 2 {
+		t.Fatalf("expect 2 loads, got %d", loads)
+	}
+	if code := serve(p, healthPath, "10.0.0.1:1234"); code != http.StatusOK {
+		t.Fatalf("health check should not be rate limited, got %d", code)
+	}
+
+	perPeer := NewHTTPPool("self", WithPeerRateLimit(1, 1), WithPoolLogLevel(LevelSilent))
+	for _, tt := range []struct {
+		remote string
+		want   int
+	}{
+		{"10.0.0.1:1234", http.StatusOK},
+		{"10.0.0.1:5678", http.StatusTooManyRequests}, //同一个来源的不同端口共用令牌桶
+		{"10.0.0.2:1234", http.StatusOK},
+	} {
+		if code := serve(perPeer, "ratelimit/k0", tt.remote); code != tt.want {
+			t.Fatalf("%s returned %d, want %d", tt.remote, code, tt.want)
+		}
+	}
+
+	//Unix domain socket 的请求没有远端地址，共用一个令牌桶；有客户端证书时按证书区分
+	unix := NewHTTPPool("self", WithPeerRateLimit(1, 1), WithPoolLogLevel(LevelSilent))
+	if serve(unix, "ratelimit/k0", "@") != http.StatusOK || serve(unix, "ratelimit/k0", "") != http.StatusTooManyRequests {
+		t.Fatal("unix socket requests without an identity should share one bucket")
+	}
+	withCert := func(cn string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, defultBasePath+"ratelimit/k0", nil)
+		req.RemoteAddr = "@"
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+		return req
+	}
+	for _, tt := range []struct {
+		cn   string
+		want int
+	}{{"a", http.StatusOK}, {"b", http.StatusOK}, {"a", http.StatusTooManyRequests}} {
+		w := httptest.NewRecorder()
+		unix.ServeHTTP(w, withCert(tt.cn))
+		if w.Code != tt.want {
+			t.Fatalf("peer %s returned %d, want %d", tt.cn, w.Code, tt.want)
+		}
+	}
+
+	//未授权的请求先被拒绝，不消耗令牌
+	authed := NewHTTPPool("self", WithRateLimit(1, 1), WithAuthToken("secret"), WithPoolLogLevel(LevelSilent))
+	if code := serve(authed, "ratelimit/k0", "10.0.0.1:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("request without token returned %d, want 401", code)
+	}
+	req := httptest.NewRequest(http.MethodGet, defultBasePath+"ratelimit/k0", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	authed.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unauthorized requests should not use up tokens, got %d", w.Code)
+	}
+
+	b := newTokenBucket(10, 1)
+	now := time.Now()
+	if !b.allow(now) || b.allow(now) {
+		t.Fatal("expect a burst of 1")
+	}
+	if !b.allow(now.Add(100*time.Millisecond)) || !b.full(now.Add(time.Second)) {
+		t.Fatal("expect the bucket to refill at 10 tokens per second")
+	}
+	if newTokenBucket(0, 10) != nil {
+		t.Fatal("perSecond <= 0 should disable the limit")
+	}
+}
+
+func TestAddRemovePeer(t *testing.T) {
+	p := NewHTTPPool("http://a")
+	p.Set("http://a", "http://b", "http://c")
+	owners := func() map[string]string {
+		m := make(map[string]string)
+		for i := 0; i < 1000; i++ {
+			key := strconv.Itoa(i)
+			m[key] = p.peers.Get(key)
+		}
+		return m
+	}
+	before := owners()
+	getterB := p.httpGetters["http://b"]
+
+	p.RemovePeer("http://c")
+	if _, ok := p.httpGetters["http://c"]; ok {
+		t.Fatal("removed peer should not keep a getter")
+	}
+	for key, owner := range owners() {
+		if before[key] != "http://c" && owner != before[key] {
+			t.Fatalf("key %s moved from %s to %s although its owner was not removed", key, before[key], owner)
+		}
+		if owner == "http://c" {
+			t.Fatalf("key %s still owned by the removed peer", key)
+		}
+	}
+	if p.httpGetters["http://b"] != getterB {
+		t.Fatal("getters of remaining peers should be kept")
+	}
+
+	p.AddPeer("http://c")
+	p.AddPeer("http://c")
+	if !reflect.DeepEqual(owners(), before) {
+		t.Fatal("adding the peer back should restore the original placement")
+	}
+
+	//Set 只处理变化的节点
+	p.Set("http://a", "http://b", "http://d")
+	if p.httpGetters["http://b"] != getterB || p.httpGetters["http://c"] != nil || p.httpGetters["http://d"] == nil {
+		t.Fatalf("Set should diff against the current peers, got %v", p.httpGetters)
+	}
+
+	//与 PickPeer 并发调用
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			p.RemovePeer("http://d")
+			p.AddPeer("http://d")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			p.PickPeer(strconv.Itoa(i))
+		}
+	}()
+	wg.Wait()
+}
+
+func TestCodec(t *testing.T) {
+	NewGroup("codec", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key + "-value"), nil }))
+	defer DestroyGroup("codec")
+	pool := NewHTTPPool("server", WithPoolLogLevel(LevelSilent))
+	server := httptest.NewServer(pool)
+	defer server.Close()
+
+	//curl 风格的请求按 Accept 返回 JSON
+	req, _ := http.NewRequest(http.MethodGet, server.URL+defultBasePath+"codec/Tom", nil)
+	req.Header.Set("Accept", "text/html, application/json;q=0.9")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var body struct{ Value []byte }
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil || string(body.Value) != "Tom-value" {
+		t.Fatalf("JSON response = %q, %v", body.Value, err)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	if vary := res.Header.Get("Vary"); vary != "Accept" {
+		t.Fatalf("Vary = %q, want Accept", vary)
+	}
+
+	for _, codec := range []Codec{ProtobufCodec{}, ProtoJSONCodec{}} {
+		p := NewHTTPPool("client", WithCodec(codec))
+		p.Set(server.URL)
+		getter := p.httpGetters[server.URL]
+		out := &pb.Response{}
+		if err := getter.Get(context.Background(), &pb.Request{Group: "codec", Key: "Jack"}, out); err != nil || string(out.GetValue()) != "Jack-value" {
+			t.Fatalf("%T: Get = %q, %v", codec, out.GetValue(), err)
+		}
+		multi := &pb.MultiResponse{}
+		if err := getter.GetMulti(context.Background(), &pb.MultiRequest{Group: "codec", Keys: []string{"a", "b"}}, multi); err != nil || len(multi.GetValues()) != 2 {
+			t.Fatalf("%T: GetMulti = %v, %v", codec, multi.GetValues(), err)
+		}
+		if err := getter.Set(&pb.SetRequest{Group: "codec", Key: "Sam", Value: []byte("567")}, &pb.SetResponse{}); err != nil {
+			t.Fatalf("%T: Set = %v", codec, err)
+		}
+	}
+	if v, _ := GetGroup("codec").Peek("Sam"); v.String() != "567" {
+		t.Fatalf("Set through the JSON codec stored %q", v.String())
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	g := NewGroup("admin-test", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("admin-test")
+	g.Get("Tom")
+	g.Get("Tom")
+	g.SetWithTTL("Jack", []byte("589"), time.Minute)
+	//异步写入的统计由后台协程更新，这里直接计数
+	g.stats.add(&g.stats.writeBehindDropped)
+	g.stats.add(&g.stats.writeBehindDropped)
+	g.stats.add(&g.stats.writeBehindErrors)
+	pool := NewHTTPPool("http://self", WithAdmin(true), WithAuthToken("secret"), WithPoolLogLevel(LevelSilent))
+	pool.Set("http://self", "http://other")
+	server := httptest.NewServer(pool)
+	defer server.Close()
+
+	get := func(path string, v interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, server.URL+defultBasePath+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return res.StatusCode
+	}
+
+	var groups adminGroupsResponse
+	if code := get("admin/groups", &groups); code != http.StatusOK {
+		t.Fatalf("admin/groups returned %d", code)
+	}
+	var found *adminGroup
+	for i := range groups.Groups {
+		if groups.Groups[i].Name == "admin-test" {
+			found = &groups.Groups[i]
+		}
+	}
+	if found == nil || found.Entries != 2 || found.UsedBytes != g.UsedBytes() || found.Stats.Hits != 1 || found.Stats.Misses != 1 ||
+		found.Stats.WriteBehindDropped != 2 || found.Stats.WriteBehindErrors != 1 {
+		t.Fatalf("unexpected group %+v", found)
+	}
+	if want := []adminPeer{{Addr: "http://other", Up: true}, {Addr: "http://self", Self: true, Up: true}}; !reflect.DeepEqual(groups.Peers, want) {
+		t.Fatalf("peers = %+v, want %+v", groups.Peers, want)
+	}
+
+	var keys adminKeysResponse
+	if code := get("admin/keys?group=admin-test&limit=1", &keys); code != http.StatusOK || len(keys.Keys) != 1 {
+		t.Fatalf("admin/keys returned %d, %+v", code, keys)
+	}
+	if code := get("admin/keys?group=missing", &keys); code != http.StatusNotFound {
+		t.Fatalf("missing group returned %d", code)
+	}
+
+	//管理接口与节点间请求使用相同的令牌
+	res, err := http.Get(server.URL + defultBasePath + "admin/groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request without token returned %d", res.StatusCode)
+	}
+}
+
+func TestInvalidateTagOverHTTP(t *testing.T) {
+	g := NewGroup("http-tags", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("http-tags")
+	server := httptest.NewServer(NewHTTPPool("server", WithPoolLogLevel(LevelSilent)))
+	defer server.Close()
+	p := NewHTTPPool("client")
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+
+	//标签随 Set 请求写入远程节点，标签中的 "/" 被转义
+	if err := getter.Set(&pb.SetRequest{Group: "http-tags", Key: "k", Value: []byte("v"), Tags: []string{"a/b"}}, &pb.SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	out := &pb.TagResponse{}
+	if err := getter.InvalidateTag(&pb.TagRequest{Group: "http-tags", Tag: "a/b"}, out); err != nil || !reflect.DeepEqual(out.GetKeys(), []string{"k"}) {
+		t.Fatalf("InvalidateTag = %v, %v", out.GetKeys(), err)
+	}
+	if _, ok := g.Peek("k"); ok {
+		t.Fatal("k should be deleted by its tag")
+	}
+}
+
+func TestServeHTTPStatus(t *testing.T) {
+	NewGroup("status", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte(key), nil
+	}))
+	defer DestroyGroup("status")
+	server := httptest.NewServer(NewHTTPPool("server", WithPoolLogLevel(LevelSilent)))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "status/Tom", http.StatusOK},
+		{http.MethodGet, "status/missing", http.StatusNotFound},
+		{http.MethodGet, "nogroup/Tom", http.StatusNotFound},
+		{http.MethodGet, "status/", http.StatusBadRequest},
+		{http.MethodGet, "status/a%00b", http.StatusBadRequest}, //key 含有 NUL，返回 ErrInvalidKey
+		{http.MethodPut, "status/", http.StatusBadRequest},
+		{http.MethodPut, "status/Tom", http.StatusBadRequest}, //请求体不是合法的 pb.SetRequest
+		{http.MethodDelete, "status/Tom", http.StatusNoContent},
+		{http.MethodDelete, "status/Tom", http.StatusNoContent},
+		{http.MethodPatch, "status/Tom", http.StatusMethodNotAllowed},
+	} {
+		req, _ := http.NewRequest(tc.method, server.URL+defultBasePath+tc.path, strings.NewReader("\xff"))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tc.code {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.path, res.StatusCode, tc.code)
+		}
+	}
+
+	//其他节点写入的 key 同样需要通过检查，只有值太大时返回 413
+	g := NewGroup("status-set", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }),
+		WithMaxKeyLength(8), WithMaxValueSize(4))
+	defer DestroyGroup("status-set")
+	p := NewHTTPPool("client")
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+	for _, tc := range []struct {
+		key, value, status string
+	}{
+		{"toolongkey", "v", "400"},
+		{"a\nb", "v", "400"},
+		{"k", "12345", "413"},
+	} {
+		err := getter.Set(&pb.SetRequest{Group: "status-set", Key: tc.key, Value: []byte(tc.value)}, &pb.SetResponse{})
+		if err == nil || !strings.Contains(err.Error(), tc.status) {
+			t.Errorf("Set(%q) = %v, want status %s", tc.key, err, tc.status)
+		}
+		if _, ok := g.Peek(tc.key); ok {
+			t.Errorf("rejected key %q should not be stored", tc.key)
+		}
+	}
+}
+
+func TestServeKey(t *testing.T) {
+	g := NewGroup("serveKey", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if key == "/missing" {
+			return nil, ErrNotFound
+		}
+		return []byte("<p>" + key + "</p>"), nil
+	}))
+	defer DestroyGroup("serveKey")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		g.ServeKey(w, r, r.URL.Path)
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "<p>/index</p>" || rec.Header().Get("Content-Type") != "text/html" {
+		t.Fatalf("GET = %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+	if rec.Header().Get("Content-Length") != "13" {
+		t.Fatalf("Content-Length = %q", rec.Header().Get("Content-Length"))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/index", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("HEAD = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing key = %d, want 404", rec.Code)
+	}
+}
+
+func TestMetaOverHTTP(t *testing.T) {
+	g := NewGroup("http-meta", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("http-meta")
+	server := httptest.NewServer(NewHTTPPool("server", WithPoolLogLevel(LevelSilent)))
+	defer server.Close()
+	p := NewHTTPPool("client")
+	p.Set(server.URL)
+	getter := p.httpGetters[server.URL]
+
+	meta := map[string]string{MetaETag: `"1"`}
+	if err := getter.Set(&pb.SetRequest{Group: "http-meta", Key: "k", Value: []byte("v"), Meta: meta}, &pb.SetResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := g.Peek("k"); v.Meta(MetaETag) != `"1"` {
+		t.Fatalf("server meta = %v", v.Metadata())
+	}
+	out := &pb.Response{}
+	if err := getter.Get(context.Background(), &pb.Request{Group: "http-meta", Key: "k"}, out); err != nil || !reflect.DeepEqual(out.GetMeta(), meta) {
+		t.Fatalf("Get meta = %v, %v", out.GetMeta(), err)
+	}
+	multi := &pb.MultiResponse{}
+	if err := getter.GetMulti(context.Background(), &pb.MultiRequest{Group: "http-meta", Keys: []string{"k", "other"}}, multi); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(multi.GetMeta()["k"].GetValues(), meta) || multi.GetMeta()["other"] != nil {
+		t.Fatalf("GetMulti meta = %v", multi.GetMeta())
+	}
+}
+
+func TestServeHTTPETag(t *testing.T) {
+	g := NewGroup("etag", 2<<10, GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil }))
+	defer DestroyGroup("etag")
+	g.SetWithMeta("page", []byte("<p>hi</p>"), map[string]string{MetaContentType: "text/html", MetaETag: `"abc"`})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.ServeKey(w, r, "page")
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html" || rec.Header().Get("ETag") != `"abc"` {
+		t.Fatalf("GET = %d %v", rec.Code, rec.Header())
+	}
+	for _, inm := range []string{`"abc"`, `"x", W/"abc"`, "*"} {
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", inm)
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Fatalf("If-None-Match %s = %d %q", inm, rec.Code, rec.Body.String())
+		}
+	}
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"old"`)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "<p>hi</p>" {
+		t.Fatalf("stale If-None-Match = %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,62 @@
+package GoCache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//ServeHTTP 把缓存值作为响应体写入 w，通过 WriteTo 直接写入，不会像 ByteSlice 那样复制。
+//调用前已经设置的 Content-Type 保持不变，否则使用元数据 MetaContentType，都没有时使用 application/octet-stream；
+//值有元数据 MetaETag 时设置 ETag 响应头，请求的 If-None-Match 匹配时返回 304。HEAD 请求只写入响应头
+func (v ByteView) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	if etag := v.Meta(MetaETag); etag != "" {
+		h.Set("ETag", etag)
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if h.Get("Content-Type") == "" {
+		if ct := v.Meta(MetaContentType); ct != "" {
+			h.Set("Content-Type", ct)
+		} else {
+			h.Set("Content-Type", "application/octet-stream")
+		}
+	}
+	h.Set("Content-Length", strconv.Itoa(v.Len()))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		v.WriteTo(w)
+	}
+}
+
+//etagMatch 判断 If-None-Match 是否包含 etag，使用弱比较，忽略 W/ 前缀
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" || strings.TrimPrefix(part, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+//ServeKey 获取 key 并通过 ByteView.ServeHTTP 写入 w，用于把 Group 作为 HTTP 响应的缓存层，例如：
+//	http.HandleFunc("/pages/", func(w http.ResponseWriter, r *http.Request) {
+//		pages.ServeKey(w, r, r.URL.Path)
+//	})
+//获取使用 r.Context()，失败时按错误返回 404（ErrNotFound）、400（ErrKeyRequired）或 500
+func (g *Group) ServeKey(w http.ResponseWriter, r *http.Request, key string) {
+	view, err := g.GetContext(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	view.ServeHTTP(w, r)
+}
@@ -0,0 +1,51 @@
+package GoCache
+
+import (
+	"fmt"
+	"strings"
+)
+
+//DefaultMaxKeyLength 是 key 的默认最大长度（字节），见 WithMaxKeyLength
+const DefaultMaxKeyLength = 1024
+
+//WithMaxKeyLength 设置 key 的最大长度（字节），n <= 0 表示不限制，默认为 DefaultMaxKeyLength。
+//过长的 key 占用内存，并且节点间请求把 key 放在 URL 中，过长时可能被代理或服务端拒绝
+func WithMaxKeyLength(n int) Option {
+	return func(g *Group) {
+		g.maxKeyLength = n
+	}
+}
+
+//WithKeyValidator 设置额外的 key 校验函数，在内置的校验（非空、长度、不含换行和 NUL）通过之后调用，
+//返回错误时操作直接失败，错误用 ErrInvalidKey 包装后返回。例如只允许小写字母和数字：
+//	WithKeyValidator(func(key string) error {
+//		if strings.Trim(key, "abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+//			return errors.New("only [a-z0-9] allowed")
+//		}
+//		return nil
+//	})
+func WithKeyValidator(fn func(key string) error) Option {
+	return func(g *Group) {
+		g.keyValidator = fn
+	}
+}
+
+//validateKey 校验 key，Get、Set、Delete 等在访问缓存和远程节点之前调用。
+//空 key 返回 ErrKeyRequired，其他不合法的 key 返回包装了 ErrInvalidKey 的错误
+func (g *Group) validateKey(key string) error {
+	if key == "" {
+		return ErrKeyRequired
+	}
+	if g.maxKeyLength > 0 && len(key) > g.maxKeyLength {
+		return fmt.Errorf("%w: length %d exceeds %d", ErrInvalidKey, len(key), g.maxKeyLength)
+	}
+	if i := strings.IndexAny(key, "\n\r\x00"); i >= 0 {
+		return fmt.Errorf("%w: %q contains control character at %d", ErrInvalidKey, key, i)
+	}
+	if g.keyValidator != nil {
+		if err := g.keyValidator(key); err != nil {
+			return fmt.Errorf("%w: %q: %v", ErrInvalidKey, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package GoCache
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	//latencyReplicas 是开启 WithLatencyAwareRouting 后每个 key 的候选节点数，即哈希环上从负责节点开始的前几个节点
+	latencyReplicas = 3
+	//latencyWeight 是 EWMA 中新样本的权重
+	latencyWeight = 0.2
+)
+
+//WithLatencyAwareRouting 开启按延迟选择节点：根据访问每个远程节点耗时的指数加权移动平均（只统计成功的请求），
+//PickPeer 在哈希环上从负责 key 的节点开始的前 latencyReplicas 个节点中选择平均延迟最低的，
+//候选节点包括本节点时在本地加载；PickPeers 返回的节点同样按平均延迟排序，用于重试和对冲。
+//还没有样本的节点视为延迟为 0，会被优先访问一次。适合节点分布在不同地域、延迟差异明显的集群，
+//代价是每个 key 会被缓存在多个节点上。默认关闭，始终选择负责 key 的节点
+func WithLatencyAwareRouting(enabled bool) PoolOption {
+	return func(p *HTTPPool) {
+		p.latencyAware = enabled
+	}
+}
+
+//observeLatency 把一次请求的耗时 d 计入平均延迟
+func (h *httpGetter) observeLatency(d time.Duration) {
+	for {
+		old := atomic.LoadInt64(&h.rtt)
+		next := int64(d)
+		if old != 0 {
+			next = old + int64(latencyWeight*float64(int64(d)-old))
+		}
+		if atomic.CompareAndSwapInt64(&h.rtt, old, next) {
+			return
+		}
+	}
+}
+
+//latency 返回平均延迟，没有样本时返回 0
+func (h *httpGetter) latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.rtt))
+}
+
+//Latencies 返回访问每个远程节点的平均延迟，没有开启 WithLatencyAwareRouting 时返回 nil
+func (p *HTTPPool) Latencies() map[string]time.Duration {
+	if !p.latencyAware {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	latencies := make(map[string]time.Duration, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		if peer != p.self {
+			latencies[peer] = getter.latency()
+		}
+	}
+	return latencies
+}
+
+//nearestPeer 在 key 的候选节点中选择平均延迟最低的在线节点，需要持有 p.mu 的读锁。
+//local 为 true 表示候选节点包括本节点，应当在本地加载；候选节点都已下线时 peer 为空
+func (p *HTTPPool) nearestPeer(key string) (peer string, local bool) {
+	var best time.Duration
+	for _, candidate := range p.peers.GetN(key, latencyReplicas) {
+		if candidate == p.self {
+			return "", true
+		}
+		getter := p.httpGetters[candidate]
+		if getter.isDown() {
+			continue
+		}
+		if l := getter.latency(); peer == "" || l < best {
+			peer, best = candidate, l
+		}
+	}
+	return peer, false
+}
+
+//sortByLatency 按平均延迟从低到高排序，延迟相同时保持哈希环上的顺序
+func sortByLatency(getters []PeerGetter) {
+	sort.SliceStable(getters, func(i, j int) bool {
+		return getters[i].(*httpGetter).latency() < getters[j].(*httpGetter).latency()
+	})
+}
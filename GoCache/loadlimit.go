@@ -0,0 +1,63 @@
+package GoCache
+
+import (
+	"context"
+	"time"
+)
+
+//WithMaxConcurrentLoads 限制整个 Group 同时调用 getter 的数量最多为 n，n <= 0 表示不限制（默认）。
+//singleflight 只合并相同 key 的加载，流量突增时大量不同的 key 同时未命中仍可能耗尽数据库连接，
+//超出 n 的加载排队等待，ctx 结束时返回 ctx.Err()，等待时间的上限见 WithLoadQueueTimeout。
+//只限制本节点的 getter，从远程节点获取不受影响
+func WithMaxConcurrentLoads(n int) Option {
+	return func(g *Group) {
+		if n > 0 {
+			g.loadSem = make(chan struct{}, n)
+		} else {
+			g.loadSem = nil
+		}
+	}
+}
+
+//WithLoadQueueTimeout 设置达到 WithMaxConcurrentLoads 的上限后加载最多等待的时间，超时返回 ErrLoadOverloaded。
+//d 为 0（默认）时一直等到 ctx 结束，d < 0 时不等待，立即返回 ErrLoadOverloaded
+func WithLoadQueueTimeout(d time.Duration) Option {
+	return func(g *Group) {
+		g.loadWait = d
+	}
+}
+
+//acquireLoad 获取一个调用 getter 的名额，成功后需要调用 releaseLoad
+func (g *Group) acquireLoad(ctx context.Context) error {
+	if g.loadSem == nil {
+		return nil
+	}
+	select {
+	case g.loadSem <- struct{}{}:
+		return nil
+	default:
+	}
+	if g.loadWait < 0 {
+		return ErrLoadOverloaded
+	}
+	var timeout <-chan time.Time
+	if g.loadWait > 0 {
+		timer := time.NewTimer(g.loadWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case g.loadSem <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrLoadOverloaded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *Group) releaseLoad() {
+	if g.loadSem != nil {
+		<-g.loadSem
+	}
+}
@@ -0,0 +1,36 @@
+package GoCache
+
+import "log"
+
+//Logger 是 GoCache 内部使用的日志接口，*log.Logger 已经实现了该接口，
+//也可以包装为其他结构化日志库，通过 WithLogger / WithPoolLogger 设置
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+//LogLevel 日志级别，低于设定级别的日志不会输出
+type LogLevel int
+
+const (
+	LevelDebug  LogLevel = iota //命中、选择节点等高频日志
+	LevelInfo                   //收到的请求等一般日志
+	LevelError                  //远程节点失败等错误日志
+	LevelSilent                 //不输出任何日志
+)
+
+//leveledLogger 为 Logger 增加日志级别过滤，零值使用标准库的默认 logger 并输出全部日志
+type leveledLogger struct {
+	logger Logger
+	level  LogLevel
+}
+
+func (l *leveledLogger) logf(level LogLevel, format string, v ...interface{}) {
+	if level < l.level || l.level == LevelSilent {
+		return
+	}
+	if l.logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	l.logger.Printf(format, v...)
+}
@@ -0,0 +1,132 @@
+//Package metrics 把 GoCache 的统计信息导出为 Prometheus 指标，依赖 Prometheus 的代码只放在这个包中。
+//
+//	c := metrics.NewCollector()
+//	pool := GoCache.NewHTTPPool(self, GoCache.WithPeerObserver(c.ObservePeer))
+//	prometheus.MustRegister(c)
+//	http.Handle("/metrics", promhttp.Handler())
+//
+//也可以用 metrics.Handler(c) 得到只包含 GoCache 指标的 http.Handler
+package metrics
+
+import (
+	"GoCache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const namespace = "gocache"
+
+//Collector 实现 prometheus.Collector。
+//每个 group 的指标在 Collect 时从 Group.Stats() 读取，带有 group 标签；
+//访问远程节点的延迟和错误通过 ObservePeer 记录，带有 peer 标签
+type Collector struct {
+	hits          *prometheus.Desc
+	hotHits       *prometheus.Desc
+	misses        *prometheus.Desc
+	localLoads    *prometheus.Desc
+	peerLoads     *prometheus.Desc
+	peerErrors    *prometheus.Desc
+	dedups        *prometheus.Desc
+	circuitOpen   *prometheus.Desc
+	writeDropped  *prometheus.Desc
+	writeFailures *prometheus.Desc
+	entries       *prometheus.Desc
+	bytes         *prometheus.Desc
+	maxBytes      *prometheus.Desc
+
+	peerLatency  *prometheus.HistogramVec
+	peerFailures *prometheus.CounterVec
+}
+
+//NewCollector 创建 Collector，需要注册到 prometheus.Registerer 后才会被采集
+func NewCollector() *Collector {
+	group := []string{"group"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, group, nil)
+	}
+	return &Collector{
+		hits:          desc("hits_total", "Number of lookups served from the main cache."),
+		hotHits:       desc("hot_hits_total", "Number of lookups served from the hot cache."),
+		misses:        desc("misses_total", "Number of lookups that missed both caches."),
+		localLoads:    desc("local_loads_total", "Number of values loaded from the local getter."),
+		peerLoads:     desc("peer_loads_total", "Number of values loaded from a peer."),
+		peerErrors:    desc("peer_errors_total", "Number of failed loads from a peer."),
+		dedups:        desc("loader_dedups_total", "Number of loads deduplicated by singleflight."),
+		circuitOpen:   desc("peer_circuit_open_total", "Number of peer loads rejected by an open circuit breaker."),
+		writeDropped:  desc("write_behind_dropped_total", "Number of write-behind replications dropped because the queue was full."),
+		writeFailures: desc("write_behind_errors_total", "Number of write-behind replications that failed."),
+		entries:       desc("entries", "Number of entries in the main cache."),
+		bytes:         desc("bytes", "Bytes used by the main cache."),
+		maxBytes:      desc("max_bytes", "Capacity of the main cache in bytes, 0 means unlimited."),
+
+		peerLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "peer_request_duration_seconds",
+			Help:      "Latency of requests to peers, including retries.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"peer"}),
+		peerFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "peer_request_errors_total",
+			Help:      "Number of requests to peers that failed or returned a non-2xx status.",
+		}, []string{"peer"}),
+	}
+}
+
+//ObservePeer 记录一次访问远程节点的结果，传给 GoCache.WithPeerObserver
+func (c *Collector) ObservePeer(peer string, latency time.Duration, err error) {
+	c.peerLatency.WithLabelValues(peer).Observe(latency.Seconds())
+	if err != nil {
+		c.peerFailures.WithLabelValues(peer).Inc()
+	}
+}
+
+//Describe 实现 prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.hits, c.hotHits, c.misses, c.localLoads, c.peerLoads, c.peerErrors,
+		c.dedups, c.circuitOpen, c.writeDropped, c.writeFailures, c.entries, c.bytes, c.maxBytes,
+	} {
+		ch <- d
+	}
+	c.peerLatency.Describe(ch)
+	c.peerFailures.Describe(ch)
+}
+
+//Collect 实现 prometheus.Collector，读取当前所有 group 的统计信息
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, g := range GoCache.Groups() {
+		name := g.Name()
+		s := g.Stats()
+		counter := func(d *prometheus.Desc, v int64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v), name)
+		}
+		gauge := func(d *prometheus.Desc, v int64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(v), name)
+		}
+		counter(c.hits, s.Hits)
+		counter(c.hotHits, s.HotHits)
+		counter(c.misses, s.Misses)
+		counter(c.localLoads, s.LocalLoads)
+		counter(c.peerLoads, s.PeerLoads)
+		counter(c.peerErrors, s.PeerErrors)
+		counter(c.dedups, s.LoaderDedups)
+		counter(c.circuitOpen, s.PeerCircuitOpen)
+		counter(c.writeDropped, s.WriteBehindDropped)
+		counter(c.writeFailures, s.WriteBehindErrors)
+		gauge(c.entries, int64(g.Len()))
+		gauge(c.bytes, g.UsedBytes())
+		gauge(c.maxBytes, g.MaxBytes())
+	}
+	c.peerLatency.Collect(ch)
+	c.peerFailures.Collect(ch)
+}
+
+//Handler 返回只导出 c 中指标的 http.Handler
+func Handler(c *Collector) http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"GoCache"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	g := GoCache.NewGroup("metrics", 2<<10, GoCache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v:" + key), nil
+	}))
+	defer GoCache.DestroyGroup("metrics")
+	g.Get("a")
+	g.Get("a")
+	g.Get("b")
+
+	c := NewCollector()
+	c.ObservePeer("http://peer1", 3*time.Millisecond, nil)
+	c.ObservePeer("http://peer1", 5*time.Millisecond, errors.New("boom"))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			key := f.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case m.Counter != nil:
+				got[key] = m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				got[key] = m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				got[key] = float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	for key, want := range map[string]float64{
+		"gocache_hits_total{group=metrics}":                        1,
+		"gocache_misses_total{group=metrics}":                      2,
+		"gocache_local_loads_total{group=metrics}":                 2,
+		"gocache_entries{group=metrics}":                           2,
+		"gocache_max_bytes{group=metrics}":                         2 << 10,
+		"gocache_peer_request_duration_seconds{peer=http://peer1}": 2,
+		"gocache_peer_request_errors_total{peer=http://peer1}":     1,
+	} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %v", key, got[key], want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := NewCollector()
+	c.ObservePeer("http://peer1", time.Millisecond, nil)
+	w := httptest.NewRecorder()
+	Handler(c).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(w.Body)
+	if !strings.Contains(string(body), `gocache_peer_request_duration_seconds_count{peer="http://peer1"} 1`) {
+		t.Fatalf("unexpected metrics output:\n%s", body)
+	}
+}
@@ -0,0 +1,115 @@
+package GoCache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+//Namespace 是 Group 上的一个 key 前缀，所有操作在调用 Group 之前给 key 加上前缀，
+//多个逻辑上独立的数据集可以共用一个 Group 的缓存、getter 和远程节点，而不会互相覆盖。
+//前缀在一致性哈希选择节点之前加上，加了前缀的 key 按完整的 key 分布到各节点；getter 收到的也是完整的 key，
+//需要时用 strings.TrimPrefix 去掉前缀。Namespace 本身不保存状态，可以随时创建
+type Namespace struct {
+	group  *Group
+	prefix string
+}
+
+//WithPrefix 返回 key 前缀为 prefix 的 Namespace，例如：
+//	users := g.WithPrefix("user:")
+//	users.Get("42") //等价于 g.Get("user:42")
+func (g *Group) WithPrefix(prefix string) *Namespace {
+	return &Namespace{group: g, prefix: prefix}
+}
+
+//WithPrefix 返回在当前前缀之后再加上 prefix 的 Namespace
+func (n *Namespace) WithPrefix(prefix string) *Namespace {
+	return &Namespace{group: n.group, prefix: n.prefix + prefix}
+}
+
+//Group 返回 Namespace 所在的 Group
+func (n *Namespace) Group() *Group {
+	return n.group
+}
+
+//Prefix 返回完整的 key 前缀
+func (n *Namespace) Prefix() string {
+	return n.prefix
+}
+
+//key 返回加上前缀的 key，key 为空时仍返回空，交给 Group 返回 ErrKeyRequired
+func (n *Namespace) key(key string) string {
+	if key == "" {
+		return ""
+	}
+	return n.prefix + key
+}
+
+func (n *Namespace) Get(key string) (ByteView, error) {
+	return n.group.Get(n.key(key))
+}
+
+func (n *Namespace) GetContext(ctx context.Context, key string) (ByteView, error) {
+	return n.group.GetContext(ctx, n.key(key))
+}
+
+//GetMulti 与 Group.GetMulti 相同，返回结果和 KeyErrors 中的 key 不带前缀
+func (n *Namespace) GetMulti(keys []string) (map[string]ByteView, error) {
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = n.key(key)
+	}
+	values, err := n.group.GetMulti(full)
+	res := make(map[string]ByteView, len(values))
+	for key, value := range values {
+		res[strings.TrimPrefix(key, n.prefix)] = value
+	}
+	var keyErrs KeyErrors
+	if errors.As(err, &keyErrs) {
+		trimmed := make(KeyErrors, len(keyErrs))
+		for key, e := range keyErrs {
+			trimmed[strings.TrimPrefix(key, n.prefix)] = e
+		}
+		err = trimmed
+	}
+	return res, err
+}
+
+func (n *Namespace) Peek(key string) (ByteView, bool) {
+	return n.group.Peek(n.key(key))
+}
+
+func (n *Namespace) Set(key string, value []byte) error {
+	return n.group.Set(n.key(key), value)
+}
+
+func (n *Namespace) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return n.group.SetWithTTL(n.key(key), value, ttl)
+}
+
+func (n *Namespace) GetOrStore(key string, value []byte) (ByteView, bool) {
+	return n.group.GetOrStore(n.key(key), value)
+}
+
+func (n *Namespace) Touch(key string, ttl time.Duration) bool {
+	return n.group.Touch(n.key(key), ttl)
+}
+
+func (n *Namespace) Delete(key string) error {
+	return n.group.Delete(n.key(key))
+}
+
+func (n *Namespace) Invalidate(key string) error {
+	return n.group.Invalidate(n.key(key))
+}
+
+//Range 与 Group.Range 相同，只遍历带有前缀的记录，传给 fn 的 key 不带前缀
+func (n *Namespace) Range(fn func(key string, value ByteView) bool) {
+	n.group.Range(func(key string, value ByteView) bool {
+		if !strings.HasPrefix(key, n.prefix) {
+			return true
+		}
+		return fn(key[len(n.prefix):], value)
+	})
+}
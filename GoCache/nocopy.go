@@ -0,0 +1,20 @@
+package GoCache
+
+//WithNoCopy 关闭值的防御性复制：getter 返回的值和 Set、SetWithTTL、GetOrStore 等传入的值直接写入缓存，
+//Get 返回的 ByteView 的 ByteSlice 直接返回缓存中的切片，热路径上每次加载和读取各少一次分配和复制。
+//
+//警告：开启后缓存与调用者共享底层数组，调用者传入缓存之后，或者从 ByteSlice 得到切片之后，都不能再修改它，
+//否则会直接改坏缓存中的值（并且可能产生数据竞争）。只适用于值写入后不再修改的场景，例如只读或只追加的数据。默认关闭
+func WithNoCopy(enabled bool) Option {
+	return func(g *Group) {
+		g.noCopy = enabled
+	}
+}
+
+//ownBytes 返回写入缓存使用的切片，开启 WithNoCopy 时直接使用 b
+func (g *Group) ownBytes(b []byte) []byte {
+	if g.noCopy {
+		return b
+	}
+	return cloneBytes(b)
+}
@@ -0,0 +1,191 @@
+package GoCache
+
+import "time"
+
+//Option 用于在创建 Group 时修改可选配置
+type Option func(g *Group)
+
+//WithCacheBytes 设置 mainCache 的容量，0 表示不限制
+func WithCacheBytes(n int64) Option {
+	return func(g *Group) {
+		g.mainCache.cacheBytes = n
+	}
+}
+
+//WithTTL 设置默认有效期，用于 getter 没有给出 ttl 的值和 Set 写入的值，为 0 表示永不过期
+func WithTTL(ttl time.Duration) Option {
+	return func(g *Group) {
+		g.ttl = ttl
+	}
+}
+
+//WithCleanupInterval 启动后台协程，每隔 interval 清理一次已过期的记录，不再使用时需要调用 StopCleanup
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(g *Group) {
+		g.cleanupInterval = interval
+	}
+}
+
+//WithDiskTier 为 mainCache 开启磁盘层：内存写满时被淘汰的记录写入 dir 下的文件，最多占用 maxBytes（0 表示不限制），
+//Get 在内存中未命中时先查找磁盘，再调用 getter，适合值较大但访问不频繁的场景。磁盘层写满时按 LRU 淘汰。
+//dir 需要是本 Group 独占的目录，第一次写入时其中的各分片目录会被清空；磁盘读写在分片的锁内进行
+func WithDiskTier(dir string, maxBytes int64) Option {
+	return func(g *Group) {
+		g.mainCache.diskDir = dir
+		g.mainCache.diskBytes = maxBytes
+	}
+}
+
+//WithShards 设置 mainCache 与 hotCache 的分片数，默认为 DefaultShards。
+//容量较小时实际分片数会减少，保证每个分片至少分到 64KB
+func WithShards(n int) Option {
+	return func(g *Group) {
+		g.mainCache.nshards = n
+		g.hotCache.nshards = n
+	}
+}
+
+//WithMaxValueSize 设置单个值的大小上限，n <= 0 表示不限制。超过上限的值不会进入缓存：
+//Set 返回 ErrValueTooLarge，getter 返回的值仍然交给调用者，只是不缓存，下次 Get 会再次访问数据源
+func WithMaxValueSize(n int64) Option {
+	return func(g *Group) {
+		g.maxValueSize = n
+	}
+}
+
+//WithNegativeTTL 开启负缓存：getter 返回 ErrNotFound 时，在缓存中保存一个有效期为 ttl 的墓碑记录，
+//ttl 内重复查询不存在的 key 直接返回 ErrNotFound，不再访问数据源。临时错误不会被缓存。
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(g *Group) {
+		g.negativeTTL = ttl
+	}
+}
+
+//WithLogger 设置 Group 使用的 Logger，默认使用标准库的 log
+func WithLogger(l Logger) Option {
+	return func(g *Group) {
+		g.logger.logger = l
+	}
+}
+
+//WithLogLevel 设置 Group 的日志级别，例如生产环境中使用 LevelError 屏蔽命中日志，默认为 LevelDebug
+func WithLogLevel(level LogLevel) Option {
+	return func(g *Group) {
+		g.logger.level = level
+	}
+}
+
+//WithHotCacheBytes 设置 hotCache 的容量，默认为 cacheBytes 的 1/8，n <= 0 表示关闭 hotCache
+func WithHotCacheBytes(n int64) Option {
+	return func(g *Group) {
+		if n < 0 {
+			n = 0
+		}
+		g.hotCache.cacheBytes = n
+		g.hotCacheEnabled = n > 0
+	}
+}
+
+//WithRefreshWindow 开启提前刷新：设置了过期时间的记录距离过期不足 window 时，Get 立即返回仍然有效的旧值，
+//同时在后台刷新，避免过期后的第一次读取等待 getter。后台刷新失败时继续返回旧值，直到真正过期。
+func WithRefreshWindow(window time.Duration) Option {
+	return func(g *Group) {
+		g.refreshWindow = window
+	}
+}
+
+//WithStaleOnError 开启过期值兜底：mainCache 中的记录过期后继续保留 maxStale，这段时间内 Get 重新加载失败
+//（getter 或远程节点返回错误）时返回过期的旧值而不是错误，返回值的 Stale() 为 true。
+//getter 返回 ErrNotFound 或 ctx 结束时仍然返回错误。过期的记录在 maxStale 之后才会被后台清理删除，仍然占用容量
+func WithStaleOnError(maxStale time.Duration) Option {
+	return func(g *Group) {
+		g.mainCache.staleFor = maxStale
+	}
+}
+
+//WithTTLJitter 在写入时把每条记录的剩余有效期随机调整 ±fraction，例如 0.1 表示有效期为 1 小时的记录在 54 到 66 分钟后过期，
+//避免同时预热的大量 key 同时过期、一起回源。fraction 超出 [0, 1] 时按边界处理，默认为 0，不调整
+func WithTTLJitter(fraction float64) Option {
+	return func(g *Group) {
+		if fraction < 0 {
+			fraction = 0
+		} else if fraction > 1 {
+			fraction = 1
+		}
+		g.mainCache.jitter = fraction
+		g.hotCache.jitter = fraction
+	}
+}
+
+//PeerFailurePolicy 决定从远程节点获取失败后 load 的行为
+type PeerFailurePolicy int
+
+const (
+	//PeerFallbackLocal 回退到本地调用 getter，这是默认行为
+	PeerFallbackLocal PeerFailurePolicy = iota
+	//PeerFailFast 直接返回包装了 ErrPeerUnavailable 的错误，不调用 getter，适用于 getter 代价很高的场景
+	PeerFailFast
+	//PeerTryNext 尝试哈希环上的下一个远程节点（数量由 WithPeerRetries 决定，至少 1 个），仍然失败时返回 ErrPeerUnavailable，不调用 getter
+	PeerTryNext
+)
+
+//WithPeerFailurePolicy 设置从远程节点获取失败后的行为，默认为 PeerFallbackLocal
+func WithPeerFailurePolicy(policy PeerFailurePolicy) Option {
+	return func(g *Group) {
+		g.peerPolicy = policy
+	}
+}
+
+//WithPeerRetries 设置负责 key 的远程节点失败后，最多再尝试哈希环上的几个远程节点，已经尝试过的节点会被跳过。
+//所有节点都失败后再按 WithPeerFailurePolicy 处理，默认为 0
+func WithPeerRetries(n int) Option {
+	return func(g *Group) {
+		g.peerRetries = n
+	}
+}
+
+//WithHedging 开启对冲请求：从负责 key 的远程节点获取超过 delay 还没有返回时，再向哈希环上的下一个远程节点发出同样的请求，
+//使用先成功返回的结果并取消另一个请求，用于降低个别节点变慢时的尾延迟。delay 通常设为远程请求的 p95 延迟，
+//<= 0 表示关闭，默认关闭。对冲请求不会重复计入 Stats，两个请求都失败后按 WithPeerFailurePolicy 处理
+func WithHedging(delay time.Duration) Option {
+	return func(g *Group) {
+		g.hedgeDelay = delay
+	}
+}
+
+//WithOnEvicted 设置记录离开 mainCache 或 hotCache 时的回调，reason 为超出容量、过期或被删除。
+//回调在释放缓存的锁之后调用，可以在回调中访问缓存
+func WithOnEvicted(fn func(key string, value ByteView, reason EvictReason)) Option {
+	return func(g *Group) {
+		g.mainCache.onEvict = fn
+		g.hotCache.onEvict = fn
+	}
+}
+
+//WithEvictionPolicy 设置 mainCache 与 hotCache 写满时的淘汰策略，默认为 PolicyLRU
+func WithEvictionPolicy(p EvictionPolicy) Option {
+	return func(g *Group) {
+		g.mainCache.policy = p
+		g.hotCache.policy = p
+	}
+}
+
+//WithTinyLFU 使用 W-TinyLFU 淘汰策略，并设置窗口占缓存容量的比例和 count-min sketch 的宽度。
+//windowRatio 不在 (0, 1) 之间或 sketchWidth <= 0 时使用默认值，sketchWidth 应不小于预计的记录数
+func WithTinyLFU(windowRatio float64, sketchWidth int) Option {
+	return func(g *Group) {
+		cfg := tinyLFUConfig{windowRatio: windowRatio, sketchWidth: sketchWidth}
+		g.mainCache.policy, g.mainCache.tinyLFU = PolicyTinyLFU, cfg
+		g.hotCache.policy, g.hotCache.tinyLFU = PolicyTinyLFU, cfg
+	}
+}
+
+//WithTwoQueue 使用 2Q 淘汰策略，并设置 in 队列占缓存容量的比例和幽灵队列 out 的大小（按被挤出记录原来的大小占容量的比例计算）。
+//inRatio 不在 (0, 1) 之间或 ghostRatio <= 0 时使用默认值 LRU_Cache.DefaultInRatio 和 LRU_Cache.DefaultGhostRatio
+func WithTwoQueue(inRatio, ghostRatio float64) Option {
+	return func(g *Group) {
+		cfg := twoQueueConfig{inRatio: inRatio, ghostRatio: ghostRatio}
+		g.mainCache.policy, g.mainCache.twoQueue = Policy2Q, cfg
+		g.hotCache.policy, g.hotCache.twoQueue = Policy2Q, cfg
+	}
+}
@@ -0,0 +1,72 @@
+package GoCache
+
+import (
+	"sort"
+	"time"
+)
+
+//PeerInfo 是 HTTPPool 中一个节点状态的快照，见 PeerInfos
+type PeerInfo struct {
+	Addr string
+	Self bool
+	//Up 为健康检查是否通过，没有开启 WithHealthCheck 时总是 true
+	Up bool
+	//LastError 和 LastErrorAt 是最近一次请求或健康检查失败的错误和时间，没有失败过时为零值
+	LastError   error
+	LastErrorAt time.Time
+	//Latency 是成功请求耗时的指数加权移动平均，还没有成功的请求时为 0
+	Latency time.Duration
+	//Circuit 是熔断器状态，没有开启 WithCircuitBreaker 时总是 CircuitClosed
+	Circuit CircuitState
+	//Conns 是当前打开的连接数，见 PeerConns
+	Conns int
+}
+
+//PeerInfos 返回按地址排序的所有节点（包括本节点）及其状态，用于管理接口和排查问题。
+//HTTPPool 的 Peers 方法属于 PeerPicker 接口，只返回远程节点的 PeerGetter，因此这里使用不同的名字
+func (p *HTTPPool) PeerInfos() []PeerInfo {
+	conns := p.PeerConns()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	infos := make([]PeerInfo, 0, len(p.httpGetters))
+	for addr, getter := range p.httpGetters {
+		info := PeerInfo{
+			Addr:    addr,
+			Self:    addr == p.self,
+			Up:      !getter.isDown(),
+			Latency: getter.latency(),
+			Conns:   conns[addr],
+		}
+		info.LastError, info.LastErrorAt = getter.lastError()
+		if getter.breaker != nil && !info.Self {
+			info.Circuit = getter.breaker.State()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Addr < infos[j].Addr })
+	return infos
+}
+
+//OwnerOf 返回当前哈希环上负责 key 的节点地址，可能是本节点，没有节点时返回空字符串。
+//开启 WithLatencyAwareRouting 或节点被健康检查标记为下线时，PickPeer 实际访问的节点可能不同
+func (p *HTTPPool) OwnerOf(key string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.peers == nil {
+		return ""
+	}
+	return p.peers.Get(key)
+}
+
+func (h *httpGetter) setLastError(err error) {
+	h.errMu.Lock()
+	defer h.errMu.Unlock()
+	h.lastErr = err
+	h.lastErrAt = time.Now()
+}
+
+func (h *httpGetter) lastError() (error, time.Time) {
+	h.errMu.Lock()
+	defer h.errMu.Unlock()
+	return h.lastErr, h.lastErrAt
+}
@@ -1,6 +1,10 @@
 package GoCache
 
-import pb "GoCache/gocachepb"
+import (
+	pb "GoCache/gocachepb"
+	"context"
+	"io"
+)
 
 /*
 使用一致性哈希选择节点        是                                    是
@@ -12,11 +16,33 @@ import pb "GoCache/gocachepb"
 type PeerPicker interface {
 	//用于根据传入的 key 选择相应节点 PeerGetter
 	PickPeer(key string) (peer PeerGetter, ok bool)
+	//按哈希环的顺序返回负责 key 的最多 n 个远程节点（不包括本节点），用于远程节点失败时尝试下一个节点
+	PickPeers(key string, n int) []PeerGetter
+	//返回所有远程节点（不包括本节点），用于需要通知整个集群的操作，例如 ClearCluster
+	Peers() []PeerGetter
 }
 
 //PeerGetter 就对应于上述流程中的 HTTP 客户端。
 type PeerGetter interface {
 	//用于从对应 group 查找缓存值
 	//Get(group string, key string) ([]byte, error)
-	Get(in *pb.Request, out *pb.Response) error
+	//ctx 被取消或超时后应尽快返回错误
+	Get(ctx context.Context, in *pb.Request, out *pb.Response) error
+	//用于从对应 group 一次查找多个缓存值，单个 key 的错误记录在 out.Errors 中
+	GetMulti(ctx context.Context, in *pb.MultiRequest, out *pb.MultiResponse) error
+	//用于把缓存值写入对应 group，已存在时覆盖
+	Set(in *pb.SetRequest, out *pb.SetResponse) error
+	//用于从对应 group 删除缓存值，key 不存在时不返回错误
+	Delete(in *pb.Request) error
+	//用于清空对应 group 的缓存，in.Key 被忽略
+	Clear(in *pb.Request) error
+	//用于删除对应 group 中带有 in.Tag 的所有记录，out.Keys 为被删除的 key，见 Group.InvalidateByTag
+	InvalidateTag(in *pb.TagRequest, out *pb.TagResponse) error
+}
+
+//StreamPeerGetter 是 PeerGetter 可以选择实现的接口，实现后 Group.GetStream 以流的形式从远程节点获取值，
+//不需要在内存中保存完整的值，例如 grpcpool 的 GetStream
+type StreamPeerGetter interface {
+	//把 in 对应的缓存值分块写入 w，出错时 w 中可能已经写入了部分数据
+	GetStream(ctx context.Context, in *pb.Request, w io.Writer) error
 }
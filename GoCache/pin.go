@@ -0,0 +1,122 @@
+package GoCache
+
+import (
+	"GoCache/LRU_Cache"
+	"fmt"
+)
+
+//maxPinnedRatio 是每个分片中固定的记录最多占用的容量比例，保证 store 仍然能放下新的记录
+const maxPinnedRatio = 0.9
+
+//Pin 固定 mainCache 中的 key，固定的记录不会因为容量被淘汰，也不会被后台清理删除，
+//适合少量必须常驻内存的配置类 key。key 还没有缓存时，之后写入（Set 或 getter 加载）的值被固定。
+//固定的记录过期后 Get 视为未命中并重新加载，Delete 等操作仍然可以删除，key 保持固定状态直到 Unpin。
+//固定的记录占用所在分片的容量，超过分片容量的 90% 时返回 ErrPinLimit，key 没有被固定；
+//之后写入的值超过上限时按普通记录缓存。Pin 只影响本节点，不转发给其他节点
+func (g *Group) Pin(key string) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	if err := g.validateKey(key); err != nil {
+		return err
+	}
+	return g.mainCache.shardFor(key).pin(key)
+}
+
+//Unpin 取消固定 key，已经缓存的记录重新按淘汰策略管理，key 没有固定时什么也不做
+func (g *Group) Unpin(key string) {
+	if g != nil {
+		g.mainCache.shardFor(key).unpin(key)
+	}
+}
+
+func (s *shard) pin(key string) error {
+	s.mu.Lock()
+	defer s.unlock()
+	if s.pins[key] {
+		return nil
+	}
+	if s.store != nil {
+		if v, ok := s.store.Peek(key); ok && !s.addPinned(key, v.(ByteView)) {
+			return fmt.Errorf("%s: %w", key, ErrPinLimit)
+		}
+	}
+	if s.pins == nil {
+		s.pins = make(map[string]bool)
+	}
+	s.pins[key] = true
+	return nil
+}
+
+func (s *shard) unpin(key string) {
+	s.mu.Lock()
+	defer s.unlock()
+	delete(s.pins, key)
+	v, ok := s.pinned[key]
+	if !ok {
+		return
+	}
+	delete(s.pinned, key)
+	s.pinnedBytes -= LRU_Cache.EntryBytes(key, v)
+	s.resizeStore()
+	if !v.e.IsZero() {
+		s.ttls++
+		s.trackExpiry(v.e)
+	}
+	s.store.Add(key, v)
+}
+
+//addPinned 把固定的 key 写入 pinned，并从 store 中移除旧的记录，不触发回调。
+//超过固定记录的上限时返回 false，由调用方写入 store。调用方需持有 s.mu，且 s.store 不为 nil
+func (s *shard) addPinned(key string, value ByteView) bool {
+	if old, ok := s.pinned[key]; ok {
+		delete(s.pinned, key)
+		s.pinnedBytes -= LRU_Cache.EntryBytes(key, old)
+		s.resizeStore()
+	}
+	size := LRU_Cache.EntryBytes(key, value)
+	if s.cacheBytes != 0 && s.pinnedBytes+size > int64(float64(s.cacheBytes)*maxPinnedRatio) {
+		return false
+	}
+	if _, ok := s.store.Peek(key); ok {
+		s.moving = true
+		s.store.Remove(key)
+		s.moving = false
+	}
+	if s.pinned == nil {
+		s.pinned = make(map[string]ByteView)
+	}
+	s.pinned[key] = value
+	s.pinnedBytes += size
+	s.resizeStore()
+	return true
+}
+
+//removePinned 删除固定的记录并以 s.reason 触发回调，调用方需持有 s.mu
+func (s *shard) removePinned(key string, v ByteView) {
+	delete(s.pinned, key)
+	s.pinnedBytes -= LRU_Cache.EntryBytes(key, v)
+	s.resizeStore()
+	if s.notify() && !v.tombstone {
+		s.pending = append(s.pending, evictedEntry{key: key, value: v, reason: s.reason})
+	}
+}
+
+//storeBytes 返回 store 的容量，即分片容量减去固定记录占用的容量，0 表示不限制
+func (s *shard) storeBytes() int64 {
+	if s.cacheBytes == 0 {
+		return 0
+	}
+	if b := s.cacheBytes - s.pinnedBytes; b > 0 {
+		return b
+	}
+	//0 表示不限制，固定的记录占满分片（例如 Resize 缩小了容量）时 store 只保留最小的容量
+	return 1
+}
+
+//resizeStore 按 storeBytes 修改 store 的容量，缩小时超出的记录以 EvictCapacity 淘汰
+func (s *shard) resizeStore() {
+	if s.store != nil {
+		s.store.Resize(s.storeBytes())
+	}
+}
@@ -0,0 +1,140 @@
+package GoCache
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//maxRateLimitPeers 是 WithPeerRateLimit 记录的来源数超过该值时，清理已经回满、相当于空闲的令牌桶
+const maxRateLimitPeers = 1024
+
+//WithRateLimit 用令牌桶限制本节点每秒处理的节点间请求数：令牌以每秒 perSecond 个的速度补充，最多积攒 burst 个，
+//没有令牌的请求直接返回 429，在查找 group、访问缓存和调用 getter 之前被拒绝，不会占用缓存的锁或加载的名额。
+//burst <= 0 时等于 perSecond；perSecond <= 0 表示不限制，默认不限制。健康检查不受限制。
+//发出请求的节点收到 429 后按 PeerFailurePolicy 处理，不会重试
+func WithRateLimit(perSecond, burst int) PoolOption {
+	return func(p *HTTPPool) {
+		p.rateLimit = newTokenBucket(perSecond, burst)
+	}
+}
+
+//WithPeerRateLimit 与 WithRateLimit 相同，但每个来源有独立的令牌桶，避免一个异常的节点占满整个节点的配额。
+//来源按 rateLimitSource 区分：开启双向 TLS 时按客户端证书的 CommonName，否则按请求的远端 IP。
+//通过 Unix domain socket 连接的请求没有远端地址，又没有客户端证书时共用同一个令牌桶，等同于一个全局限制。
+//可以与 WithRateLimit 同时使用，请求需要同时通过两者
+func WithPeerRateLimit(perSecond, burst int) PoolOption {
+	return func(p *HTTPPool) {
+		if perSecond > 0 {
+			p.peerRateLimit = &peerLimiter{perSecond: perSecond, burst: burst, buckets: make(map[string]*tokenBucket)}
+		} else {
+			p.peerRateLimit = nil
+		}
+	}
+}
+
+//tokenBucket 是并发安全的令牌桶
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 //每秒补充的令牌数
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+//newTokenBucket 创建装满令牌的令牌桶，perSecond <= 0 时返回 nil，表示不限制
+func newTokenBucket(perSecond, burst int) *tokenBucket {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = perSecond
+	}
+	return &tokenBucket{rate: float64(perSecond), burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+//allow 在 now 时刻取出一个令牌，没有令牌时返回 false
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+//full 判断令牌桶在 now 时刻是否已经回满
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+//peerLimiter 为每个来源维护一个令牌桶
+type peerLimiter struct {
+	mu        sync.Mutex
+	perSecond int
+	burst     int
+	buckets   map[string]*tokenBucket
+}
+
+func (l *peerLimiter) allow(source string, now time.Time) bool {
+	l.mu.Lock()
+	b, ok := l.buckets[source]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitPeers {
+			for s, old := range l.buckets {
+				if old.full(now) {
+					delete(l.buckets, s)
+				}
+			}
+		}
+		b = newTokenBucket(l.perSecond, l.burst)
+		l.buckets[source] = b
+	}
+	l.mu.Unlock()
+	return b.allow(now)
+}
+
+//allowRequest 判断请求是否在 WithRateLimit 和 WithPeerRateLimit 的限制之内，先检查来源的令牌桶，
+//被来源限制拒绝的请求不消耗整个节点的令牌
+func (p *HTTPPool) allowRequest(r *http.Request) bool {
+	now := time.Now()
+	if p.peerRateLimit != nil {
+		if !p.peerRateLimit.allow(rateLimitSource(r), now) {
+			return false
+		}
+	}
+	return p.rateLimit == nil || p.rateLimit.allow(now)
+}
+
+//rateLimitSource 返回 WithPeerRateLimit 区分来源使用的 key。
+//经过验证的客户端证书是认证过的身份，优先使用；没有时使用远端 IP，端口不同的连接属于同一个来源。
+//Unix domain socket 的远端地址为空或 "@"，无法区分不同的进程，所有这类请求返回同一个 key
+func rateLimitSource(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return "cert:" + r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if host == "" || host == "@" {
+		return "unix"
+	}
+	return "ip:" + host
+}
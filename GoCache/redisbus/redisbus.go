@@ -0,0 +1,101 @@
+//Package redisbus 使用 Redis 的发布订阅实现 GoCache.InvalidationBus，失效的 key 作为消息发布到一个 channel：
+//
+//	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+//	bus := redisbus.New(client, "gocache:invalidate:scores")
+//	defer bus.Close()
+//	g := GoCache.NewGroup("scores", 2<<10, getter, GoCache.WithInvalidationBus(bus))
+//
+//每个 Group 需要使用单独的 channel。Redis 的发布订阅不保存消息，订阅断开期间发布的 key 会丢失，
+//go-redis 会自动重连并重新订阅，需要更强的保证时配合 WithTTL 设置有效期
+package redisbus
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+//DefaultTimeout 是发布和建立订阅的超时时间
+const DefaultTimeout = 5 * time.Second
+
+//Bus 通过一个 Redis channel 发布和接收失效的 key
+type Bus struct {
+	client  redis.UniversalClient
+	channel string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	subs   []*redis.PubSub
+	done   sync.WaitGroup
+	closed bool
+}
+
+//Option 用于在创建 Bus 时修改可选配置
+type Option func(b *Bus)
+
+//WithTimeout 设置发布和建立订阅的超时时间，默认为 DefaultTimeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(b *Bus) {
+		b.timeout = timeout
+	}
+}
+
+//New 创建使用 channel 的 Bus，client 可以是单机、哨兵或集群客户端，由调用者负责关闭
+func New(client redis.UniversalClient, channel string, opts ...Option) *Bus {
+	b := &Bus{client: client, channel: channel, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+//Publish 实现 GoCache.InvalidationBus，把 key 发布到 channel
+func (b *Bus) Publish(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	return b.client.Publish(ctx, b.channel, key).Err()
+}
+
+//Subscribe 实现 GoCache.InvalidationBus，订阅确认后才返回，之后发布的 key 都会交给 fn，直到调用 Close
+func (b *Bus) Subscribe(fn func(key string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	sub := b.client.Subscribe(ctx, b.channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.Close()
+		return redis.ErrClosed
+	}
+	b.subs = append(b.subs, sub)
+	b.done.Add(1)
+	go func() {
+		defer b.done.Done()
+		//Close 关闭 sub 后 Channel 随之关闭
+		for msg := range sub.Channel() {
+			fn(msg.Payload)
+		}
+	}()
+	return nil
+}
+
+//Close 取消所有订阅，等待正在执行的回调返回，不关闭 client
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs, b.closed = nil, true
+	b.mu.Unlock()
+	var first error
+	for _, sub := range subs {
+		if err := sub.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	b.done.Wait()
+	return first
+}
@@ -0,0 +1,70 @@
+package redisbus
+
+import (
+	"GoCache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+	"time"
+)
+
+var _ GoCache.InvalidationBus = (*Bus)(nil)
+
+func TestInvalidate(t *testing.T) {
+	server := miniredis.RunT(t)
+	getter := GoCache.GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	//两个进程内的 Group 模拟两个节点，各自使用独立的客户端连接同一个 channel
+	var groups []*GoCache.Group
+	for _, name := range []string{"redisbus-a", "redisbus-b"} {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		defer client.Close()
+		bus := New(client, "gocache:invalidate")
+		defer bus.Close()
+		g := GoCache.NewGroup(name, 2<<10, getter, GoCache.WithInvalidationBus(bus))
+		defer GoCache.DestroyGroup(name)
+		g.Get("Tom")
+		groups = append(groups, g)
+	}
+
+	if err := groups[0].Invalidate("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, okA := groups[0].Peek("Tom")
+		_, okB := groups[1].Peek("Tom")
+		if !okA && !okB {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Tom should be invalidated on every node, got %v, %v", okA, okB)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClose(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	bus := New(client, "gocache:invalidate")
+	received := make(chan string, 1)
+	if err := bus.Subscribe(func(key string) { received <- key }); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish("Tom")
+	select {
+	case key := <-received:
+		if key != "Tom" {
+			t.Fatalf("received %q, want Tom", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("published key should be received")
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Subscribe(func(string) {}); err == nil {
+		t.Fatal("Subscribe after Close should fail")
+	}
+}
@@ -1,42 +1,133 @@
 package singleflight
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+//ErrCallPanic 表示 fn 发生了 panic，Do 和 DoChan 返回的错误用 %w 包装它，错误信息中包含 panic 的值和调用栈
+var ErrCallPanic = errors.New("singleflight: fn panicked")
+
+//errGoexit 表示 fn 调用了 runtime.Goexit
+var errGoexit = errors.New("singleflight: fn called runtime.Goexit")
 
 //call 代表正在进行中，或已经结束的请求。使用 sync.WaitGroup 锁避免重入
 type call struct {
 	wg  sync.WaitGroup
 	val interface{}
 	err error
+	//dups 是与这次请求合并的调用次数，chans 是通过 DoChan 等待结果的调用者，都由 Group.mu 保护
+	dups  int
+	chans []chan<- Result
 }
 
 //Group 是 singleflight 的主数据结构，管理不同 key 的请求(call)。
 type Group struct {
 	mu sync.Mutex
 	m  map[string]*call
+	//coalesced 是与正在进行中的请求合并、没有调用 fn 的调用次数，通过 sync/atomic 读写
+	coalesced int64
+}
+
+//Result 是 DoChan 返回的结果，与 golang.org/x/sync/singleflight.Result 相同
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool //结果是否同时返回给了多个调用者
 }
 
 //针对相同的 key，无论 Do 被调用多少次，函数 fn 都只会被调用一次，等待 fn 调用结束了，返回返回值或错误。
 //接收 2 个参数，第一个参数是 key，第二个参数是一个函数 fn
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	v, err, _ := g.DoEx(key, fn)
+	return v, err
+}
+
+//DoEx 与 Do 相同，额外返回结果是否同时返回给了多个调用者，与 golang.org/x/sync/singleflight 的 Do 相同
+func (g *Group) DoEx(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
 	//g.mu 是保护 Group 的成员变量 m 不被并发读写而加上的锁。
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
+		c.dups++
+		atomic.AddInt64(&g.coalesced, 1)
 		g.mu.Unlock()
-		c.wg.Wait()         // 如果请求正在进行中，则等待
-		return c.val, c.err // 请求结束，返回结果
+		c.wg.Wait()               // 如果请求正在进行中，则等待
+		return c.val, c.err, true // 请求结束，返回结果
 	}
 	c := new(call)
 	c.wg.Add(1)  // 发起请求前加锁
 	g.m[key] = c // 添加到 g.m，表明 key 已经有对应的请求在处理
 	g.mu.Unlock()
-	c.val, c.err = fn() // 调用 fn，发起请求
-	c.wg.Done()         // wg.Done() 锁减1，请求结束
+	g.doCall(c, key, fn)
+	//doCall 在持有 g.mu 时已经把 c 从 g.m 中删除，之后不会再有调用者修改 dups
+	return c.val, c.err, c.dups > 0 // 返回结果
+
+}
+
+//DoChan 与 Do 相同，但不阻塞，结果在 fn 结束后通过返回的 channel 发送。
+//调用者可以同时 select 自己 context 的 Done，提前放弃等待，fn 仍然会继续执行，结果交给其他调用者。
+//返回的 channel 有缓冲，没有人接收也不会阻塞 fn
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
 	g.mu.Lock()
-	delete(g.m, key) // 更新 g.m
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		atomic.AddInt64(&g.coalesced, 1)
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+		return ch
+	}
+	c := &call{chans: []chan<- Result{ch}}
+	c.wg.Add(1)
+	g.m[key] = c
 	g.mu.Unlock()
-	return c.val, c.err // 返回结果
+	go g.doCall(c, key, fn)
+	return ch
+}
+
+//doCall 调用 fn，然后唤醒通过 Do 和 DoChan 等待的调用者。
+//fn 发生 panic 时不再向上传播，所有调用者收到包装了 ErrCallPanic 的错误，key 总是会从 g.m 中删除
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
+	returned := false
+	defer func() {
+		if r := recover(); r != nil {
+			c.val, c.err = nil, fmt.Errorf("%w: %v\n%s", ErrCallPanic, r, debug.Stack())
+		} else if !returned {
+			c.val, c.err = nil, errGoexit
+		}
+		c.wg.Done() // wg.Done() 锁减1，请求结束
+		g.mu.Lock()
+		//调用过 Forget 时 g.m[key] 可能已经是新的请求
+		if g.m[key] == c {
+			delete(g.m, key) // 更新 g.m
+		}
+		for _, ch := range c.chans {
+			ch <- Result{Val: c.val, Err: c.err, Shared: c.dups > 0}
+		}
+		g.mu.Unlock()
+	}()
+	c.val, c.err = fn() // 调用 fn，发起请求
+	returned = true
+}
+
+//Coalesced 返回累计有多少次调用与正在进行中的请求合并、没有调用 fn，可以用来判断合并请求的效果
+func (g *Group) Coalesced() int64 {
+	return atomic.LoadInt64(&g.coalesced)
+}
 
+//Forget 让 key 之后的调用不再等待正在进行中的请求，而是重新调用 fn，
+//用于正在进行中的请求卡住或结果已经失效的情况。已经在等待的调用者仍然会收到原来请求的结果
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
 }
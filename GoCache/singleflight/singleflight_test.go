@@ -0,0 +1,143 @@
+package singleflight
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForget(t *testing.T) {
+	var g Group
+	stuck := make(chan struct{})
+	defer close(stuck)
+	go g.Do("key", func() (interface{}, error) {
+		<-stuck
+		return "stuck", nil
+	})
+	//等待第一个请求进入 g.m
+	for {
+		g.mu.Lock()
+		_, ok := g.m["key"]
+		g.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	g.Forget("key")
+	done := make(chan interface{})
+	go func() {
+		v, _ := g.Do("key", func() (interface{}, error) { return "fresh", nil })
+		done <- v
+	}()
+	select {
+	case v := <-done:
+		if v != "fresh" {
+			t.Fatalf("Do after Forget = %v, want a fresh call", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do after Forget should not wait for the stuck call")
+	}
+}
+
+func TestForgetFailedCall(t *testing.T) {
+	var g Group
+	fail := errors.New("poisoned")
+	release := make(chan struct{})
+	first := g.DoChan("key", func() (interface{}, error) {
+		<-release
+		return nil, fail
+	})
+	g.Forget("key")
+	v, err := g.Do("key", func() (interface{}, error) { return "ok", nil })
+	if err != nil || v != "ok" {
+		t.Fatalf("Do after Forget = %v, %v", v, err)
+	}
+	close(release)
+	if res := <-first; res.Err != fail {
+		t.Fatalf("the forgotten call should still deliver its result, got %v", res.Err)
+	}
+	//被遗忘的请求结束时不能删除新的请求
+	g.mu.Lock()
+	n := len(g.m)
+	g.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d calls left in the map", n)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	const callers = 5
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			_, err := g.Do("key", func() (interface{}, error) {
+				<-release
+				panic("boom")
+			})
+			errs <- err
+		}()
+	}
+	//等待所有调用者都在等待同一个请求
+	for {
+		g.mu.Lock()
+		c, ok := g.m["key"]
+		ready := ok && c.dups == callers-1
+		g.mu.Unlock()
+		if ready {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for i := 0; i < callers; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrCallPanic) || !strings.Contains(err.Error(), "boom") {
+				t.Fatalf("caller got %v, want ErrCallPanic", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("callers should not hang after a panic")
+		}
+	}
+
+	if v, err := g.Do("key", func() (interface{}, error) { return "ok", nil }); err != nil || v != "ok" {
+		t.Fatalf("call after a panic = %v, %v", v, err)
+	}
+	if res := <-g.DoChan("chan", func() (interface{}, error) { panic("boom") }); !errors.Is(res.Err, ErrCallPanic) {
+		t.Fatalf("DoChan should report the panic, got %v", res.Err)
+	}
+}
+
+func TestDoExShared(t *testing.T) {
+	var g Group
+	if _, _, shared := g.DoEx("key", func() (interface{}, error) { return 1, nil }); shared {
+		t.Fatal("a single caller should not be shared")
+	}
+
+	release := make(chan struct{})
+	const callers = 4
+	sharedc := make(chan bool, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			_, _, shared := g.DoEx("key", func() (interface{}, error) {
+				<-release
+				return 1, nil
+			})
+			sharedc <- shared
+		}()
+	}
+	for g.Coalesced() != callers-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for i := 0; i < callers; i++ {
+		if !<-sharedc {
+			t.Fatal("every caller of a coalesced call should see shared = true")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package GoCache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+//快照的二进制格式：
+//
+//	magic(8 字节) | 写入时间(varint, UnixNano) | 记录... | 结束标记(uvarint 0)
+//	记录 = len(key)+1(uvarint) | key | len(value)(uvarint) | value | 剩余有效期(varint, 纳秒, 0 表示永不过期) | 元数据
+//	元数据 = 个数(uvarint) | (len(name)(uvarint) | name | len(value)(uvarint) | value)...
+//
+//key 的长度加 1 保存，使空 key 不会与结束标记混淆，结束标记用于区分正常结束和被截断的快照。
+//GCSNAP01 是没有元数据的旧格式，LoadSnapshot 仍然可以读取
+const (
+	snapshotMagic   = "GCSNAP02"
+	snapshotMagicV1 = "GCSNAP01"
+)
+
+//maxSnapshotField 是快照中单个 key 或 value 的长度上限，避免损坏的快照导致分配过大的内存
+const maxSnapshotField = 1 << 30
+
+//SaveSnapshot 把 mainCache 中未过期的记录（key、值、剩余有效期和元数据）写入 w，例如在关闭前保存到文件，
+//启动后用 LoadSnapshot 恢复，避免每次部署后缓存全部失效。与 Range 相同，不包括 hotCache 和墓碑记录
+func (g *Group) SaveSnapshot(w io.Writer) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	bw := bufio.NewWriter(w)
+	now := time.Now()
+	buf := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(x uint64) {
+		bw.Write(buf[:binary.PutUvarint(buf, x)])
+	}
+	putVarint := func(x int64) {
+		bw.Write(buf[:binary.PutVarint(buf, x)])
+	}
+	putString := func(s string) {
+		putUvarint(uint64(len(s)))
+		bw.WriteString(s)
+	}
+
+	bw.WriteString(snapshotMagic)
+	putVarint(now.UnixNano())
+	g.Range(func(key string, value ByteView) bool {
+		var ttl time.Duration
+		if !value.e.IsZero() {
+			if ttl = value.e.Sub(now); ttl <= 0 {
+				return true
+			}
+		}
+		putUvarint(uint64(len(key)) + 1)
+		bw.WriteString(key)
+		putUvarint(uint64(value.Len()))
+		bw.Write(value.b)
+		putVarint(int64(ttl))
+		putUvarint(uint64(len(value.meta)))
+		for name, v := range value.meta {
+			putString(name)
+			putString(v)
+		}
+		return true
+	})
+	putUvarint(0)
+	//bufio.Writer 出错后的写入都会被忽略，Flush 返回第一次出现的错误
+	return bw.Flush()
+}
+
+//LoadSnapshot 从 r 中读取 SaveSnapshot 写入的记录并写入 mainCache。
+//剩余有效期从快照写入时开始计算，到恢复时已经过期的记录会被跳过，超过 WithMaxValueSize 的值也会被跳过。
+//快照格式错误或被截断时返回错误，此前读到的记录仍然保留在缓存中
+func (g *Group) LoadSnapshot(r io.Reader) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || (string(magic) != snapshotMagic && string(magic) != snapshotMagicV1) {
+		return fmt.Errorf("gocache: invalid snapshot header")
+	}
+	hasMeta := string(magic) == snapshotMagic
+	savedAt, err := binary.ReadVarint(br)
+	if err != nil {
+		return snapshotError(err)
+	}
+	//快照写入后经过的时间从每条记录的剩余有效期中扣除
+	elapsed := time.Since(time.Unix(0, savedAt))
+	for {
+		keyLen, err := binary.ReadUvarint(br)
+		if err != nil {
+			return snapshotError(err)
+		}
+		if keyLen == 0 {
+			return nil
+		}
+		key, err := readSnapshotField(br, keyLen-1)
+		if err != nil {
+			return err
+		}
+		valueLen, err := binary.ReadUvarint(br)
+		if err != nil {
+			return snapshotError(err)
+		}
+		value, err := readSnapshotField(br, valueLen)
+		if err != nil {
+			return err
+		}
+		ttl, err := binary.ReadVarint(br)
+		if err != nil {
+			return snapshotError(err)
+		}
+		var meta map[string]string
+		if hasMeta {
+			if meta, err = readSnapshotMeta(br); err != nil {
+				return err
+			}
+		}
+		if ttl > 0 {
+			if ttl -= int64(elapsed); ttl <= 0 {
+				continue
+			}
+		}
+		g.populateCache(string(key), ByteView{b: value, e: expireAt(time.Duration(ttl)), meta: meta})
+	}
+}
+
+func readSnapshotField(r io.Reader, n uint64) ([]byte, error) {
+	if n > maxSnapshotField {
+		return nil, fmt.Errorf("gocache: invalid snapshot: field of %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, snapshotError(err)
+	}
+	return b, nil
+}
+
+//readSnapshotMeta 读取一条记录的元数据，没有元数据时返回 nil
+func readSnapshotMeta(r *bufio.Reader) (map[string]string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, snapshotError(err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n > maxSnapshotField {
+		return nil, fmt.Errorf("gocache: invalid snapshot: %d metadata entries", n)
+	}
+	meta := make(map[string]string)
+	for i := uint64(0); i < n; i++ {
+		var kv [2]string
+		for j := range kv {
+			size, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, snapshotError(err)
+			}
+			b, err := readSnapshotField(r, size)
+			if err != nil {
+				return nil, err
+			}
+			kv[j] = string(b)
+		}
+		meta[kv[0]] = kv[1]
+	}
+	return meta, nil
+}
+
+//snapshotError 把读取到一半时的 io.EOF 转换为 io.ErrUnexpectedEOF，表示快照被截断
+func snapshotError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("gocache: invalid snapshot: %w", err)
+}
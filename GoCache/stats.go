@@ -0,0 +1,54 @@
+package GoCache
+
+import "sync/atomic"
+
+//Stats 是 Group 统计信息的快照，可以用来计算命中率，或在远程获取失败增多时告警
+type Stats struct {
+	Hits         int64 //mainCache 命中次数
+	HotHits      int64 //hotCache 命中次数
+	Misses       int64 //mainCache 和 hotCache 都未命中的次数
+	LocalLoads   int64 //调用 getter 从本地数据源获取的次数
+	PeerLoads    int64 //从远程节点成功获取的次数
+	PeerErrors   int64 //从远程节点获取失败的次数
+	LoaderDedups int64 //并发请求被 singleflight 合并、没有实际执行 load 的次数，包括 GetMulti 中本地加载的 key
+	//PeerCircuitOpen 是因为熔断器打开而没有发出的远程请求次数，也计入 PeerErrors
+	PeerCircuitOpen int64
+	//WriteBehindDropped 是开启 WithWriteBehind 后因为队列已满被丢弃的远程写入次数，WriteBehindErrors 是异步写入远程节点失败的次数
+	WriteBehindDropped int64
+	WriteBehindErrors  int64
+}
+
+//stats 保存 Group 的计数器，全部通过 sync/atomic 读写，读取时不需要获取缓存的锁
+type stats struct {
+	hits        int64
+	hotHits     int64
+	misses      int64
+	localLoads  int64
+	peerLoads   int64
+	peerErrors  int64
+	circuitOpen int64
+	//writeBehindDropped 和 writeBehindErrors 见 Stats 中同名的字段
+	writeBehindDropped int64
+	writeBehindErrors  int64
+}
+
+func (s *stats) add(counter *int64) {
+	atomic.AddInt64(counter, 1)
+}
+
+//Stats 返回 Group 当前统计信息的快照
+func (g *Group) Stats() Stats {
+	return Stats{
+		Hits:         atomic.LoadInt64(&g.stats.hits),
+		HotHits:      atomic.LoadInt64(&g.stats.hotHits),
+		Misses:       atomic.LoadInt64(&g.stats.misses),
+		LocalLoads:   atomic.LoadInt64(&g.stats.localLoads),
+		PeerLoads:    atomic.LoadInt64(&g.stats.peerLoads),
+		PeerErrors:   atomic.LoadInt64(&g.stats.peerErrors),
+		LoaderDedups: g.loader.Coalesced(),
+
+		PeerCircuitOpen:    atomic.LoadInt64(&g.stats.circuitOpen),
+		WriteBehindDropped: atomic.LoadInt64(&g.stats.writeBehindDropped),
+		WriteBehindErrors:  atomic.LoadInt64(&g.stats.writeBehindErrors),
+	}
+}
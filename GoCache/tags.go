@@ -0,0 +1,115 @@
+package GoCache
+
+import (
+	pb "GoCache/gocachepb"
+	"sync"
+)
+
+//tagIndex 记录 mainCache 中每条记录的标签，由 SetWithTags 写入，
+//记录离开 mainCache（超出容量、过期或被删除）时通过 cache.onRemove 删除
+type tagIndex struct {
+	mu   sync.Mutex
+	tags map[string]map[string]struct{} //标签 -> key
+	keys map[string][]string            //key -> 标签
+}
+
+//set 把 key 的标签替换为 tags，tags 为空时删除 key 的所有标签
+func (t *tagIndex) set(key string, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(key)
+	if len(tags) == 0 {
+		return
+	}
+	if t.tags == nil {
+		t.tags = make(map[string]map[string]struct{})
+		t.keys = make(map[string][]string)
+	}
+	t.keys[key] = append([]string(nil), tags...)
+	for _, tag := range tags {
+		if t.tags[tag] == nil {
+			t.tags[tag] = make(map[string]struct{})
+		}
+		t.tags[tag][key] = struct{}{}
+	}
+}
+
+func (t *tagIndex) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(key)
+}
+
+func (t *tagIndex) removeLocked(key string) {
+	for _, tag := range t.keys[key] {
+		delete(t.tags[tag], key)
+		if len(t.tags[tag]) == 0 {
+			delete(t.tags, tag)
+		}
+	}
+	delete(t.keys, key)
+}
+
+//keysOf 返回带有 tag 的所有 key
+func (t *tagIndex) keysOf(tag string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]string, 0, len(t.tags[tag]))
+	for key := range t.tags[tag] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+//SetWithTags 与 Set 相同，并给 key 加上标签 tags，之后可以通过 InvalidateByTag 一次删除带有某个标签的所有记录，
+//例如给一个用户的所有派生 key 加上 "user:42"。标签与值一起写入负责该 key 的远程节点。
+//再次写入 key 时标签被替换，Set 写入的 key 没有标签；只有 Set 和 SetWithTags 写入的记录有标签，getter 加载的没有
+func (g *Group) SetWithTags(key string, value []byte, tags ...string) error {
+	return g.set(key, value, tags, nil)
+}
+
+//InvalidateByTag 删除集群中所有带有 tag 的记录：先删除本节点的记录，再通知所有远程节点删除各自带有 tag 的记录，
+//最后把各节点删除的 key 通过 InvalidateMany 广播出去，清除其他节点 hotCache 中的副本。
+//与 Invalidate 相同，通知是尽力而为的，返回的错误包含失败的节点数
+func (g *Group) InvalidateByTag(tag string) error {
+	if g == nil {
+		return ErrGroupNotFound
+	}
+	keys := g.InvalidateTagLocal(tag)
+	var mu sync.Mutex
+	err := g.broadcast("invalidate tag on", func(peer PeerGetter) error {
+		res := &pb.TagResponse{}
+		if err := peer.InvalidateTag(&pb.TagRequest{Group: g.name, Tag: tag}, res); err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, res.GetKeys()...)
+		mu.Unlock()
+		return nil
+	})
+	if len(keys) == 0 {
+		return err
+	}
+	seen := make(map[string]bool, len(keys))
+	unique := keys[:0]
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	if hotErr := g.InvalidateMany(unique); err == nil {
+		err = hotErr
+	}
+	return err
+}
+
+//InvalidateTagLocal 只删除本节点上带有 tag 的记录，不转发给其他节点，返回被删除的 key。
+//用于节点间通信的服务端处理其他节点转发来的 InvalidateTag 请求
+func (g *Group) InvalidateTagLocal(tag string) []string {
+	keys := g.tags.keysOf(tag)
+	for _, key := range keys {
+		g.DeleteLocal(key)
+	}
+	return keys
+}
@@ -0,0 +1,178 @@
+package GoCache
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+//KeyStat 是 TopKeys 返回的一个 key 及其命中次数的估计值
+type KeyStat struct {
+	Key  string
+	Hits int64
+}
+
+//defaultTopKeysWidth 是 WithTopKeys 中 sketchWidth <= 0 时 count-min sketch 每行的计数器个数
+const defaultTopKeysWidth = 1 << 16
+
+//WithTopKeys 开启热点 key 统计，mainCache 或 hotCache 每次命中时记录 key 的访问次数，通过 TopKeys 查询。
+//访问次数保存在 4 行、每行 sketchWidth 个计数器的 count-min sketch 中，占用的内存与 key 的数量无关，
+//另外只保存 capacity 个访问最多的候选 key。次数是估计值，可能偏高，sketchWidth 越大越准确，
+//sketchWidth 会向上取整为 2 的幂，<= 0 时使用 65536。capacity <= 0 时不统计
+func WithTopKeys(capacity int, sketchWidth int) Option {
+	return func(g *Group) {
+		if capacity <= 0 {
+			g.topKeys = nil
+			return
+		}
+		g.topKeys = newTopKeys(capacity, sketchWidth)
+	}
+}
+
+//TopKeys 返回自上次 ResetTopKeys 以来命中次数最多的最多 n 个 key，按次数从多到少排序。
+//没有开启 WithTopKeys 时返回 nil，n 大于 WithTopKeys 的 capacity 时最多返回 capacity 个
+func (g *Group) TopKeys(n int) []KeyStat {
+	if g == nil || g.topKeys == nil {
+		return nil
+	}
+	return g.topKeys.top(n)
+}
+
+//ResetTopKeys 清空热点 key 统计，重新开始计数
+func (g *Group) ResetTopKeys() {
+	if g != nil && g.topKeys != nil {
+		g.topKeys.reset()
+	}
+}
+
+func (g *Group) countHit(key string) {
+	if g.topKeys != nil {
+		g.topKeys.hit(key)
+	}
+}
+
+//topKeys 用 count-min sketch 估计每个 key 的访问次数，用最小堆保存次数最多的 capacity 个候选 key
+type topKeys struct {
+	mu       sync.Mutex
+	rows     [4][]uint32
+	mask     uint64
+	capacity int
+	heap     keyHeap
+	index    map[string]*keyItem
+}
+
+type keyItem struct {
+	key   string
+	hits  uint32
+	index int //在 heap 中的下标
+}
+
+func newTopKeys(capacity int, width int) *topKeys {
+	if width <= 0 {
+		width = defaultTopKeysWidth
+	}
+	w := 1
+	for w < width {
+		w <<= 1
+	}
+	t := &topKeys{mask: uint64(w - 1), capacity: capacity}
+	for i := range t.rows {
+		t.rows[i] = make([]uint32, w)
+	}
+	t.index = make(map[string]*keyItem, capacity)
+	return t
+}
+
+//hit 记录一次访问，更新 key 的估计次数和候选 key
+func (t *topKeys) hit(key string) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum, sum>>32|1
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	est := ^uint32(0)
+	for i := range t.rows {
+		j := (h1 + uint64(i)*h2) & t.mask
+		if t.rows[i][j] < ^uint32(0) {
+			t.rows[i][j]++
+		}
+		if t.rows[i][j] < est {
+			est = t.rows[i][j]
+		}
+	}
+	if item, ok := t.index[key]; ok {
+		item.hits = est
+		heap.Fix(&t.heap, item.index)
+		return
+	}
+	if len(t.heap) < t.capacity {
+		item := &keyItem{key: key, hits: est}
+		heap.Push(&t.heap, item)
+		t.index[key] = item
+		return
+	}
+	//替换候选 key 中次数最少的一个
+	if min := t.heap[0]; est > min.hits {
+		delete(t.index, min.key)
+		min.key, min.hits = key, est
+		t.index[key] = min
+		heap.Fix(&t.heap, 0)
+	}
+}
+
+func (t *topKeys) top(n int) []KeyStat {
+	t.mu.Lock()
+	stats := make([]KeyStat, 0, len(t.heap))
+	for _, item := range t.heap {
+		stats = append(stats, KeyStat{Key: item.key, Hits: int64(item.hits)})
+	}
+	t.mu.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Hits != stats[j].Hits {
+			return stats[i].Hits > stats[j].Hits
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	if n >= 0 && n < len(stats) {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+func (t *topKeys) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.rows {
+		for j := range t.rows[i] {
+			t.rows[i][j] = 0
+		}
+	}
+	t.heap = t.heap[:0]
+	t.index = make(map[string]*keyItem, t.capacity)
+}
+
+//keyHeap 是按 hits 排序的最小堆，实现 heap.Interface
+type keyHeap []*keyItem
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i].hits < h[j].hits }
+func (h keyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *keyHeap) Push(x interface{}) {
+	item := x.(*keyItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *keyHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
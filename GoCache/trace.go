@@ -0,0 +1,84 @@
+package GoCache
+
+import (
+	"context"
+	"net/http"
+)
+
+//span 的属性名，Tracer 的实现可以据此对属性做特殊处理，例如 tracing 包可以把 AttrKey 替换为哈希值
+const (
+	AttrGroup = "gocache.group"
+	AttrKey   = "gocache.key"
+	AttrHit   = "gocache.hit"  //mainCache 或 hotCache 是否命中，bool
+	AttrPeer  = "gocache.peer" //远程节点的地址，PeerGetter 实现了 Addr() string 时才有
+)
+
+//Tracer 为 GetContext、load、getLocally 和访问远程节点创建 span，见 WithTracer 和 WithPoolTracer。
+//GoCache 本身不依赖任何追踪库，基于 OpenTelemetry 的实现见 tracing 包；默认使用不做任何事的 Tracer
+type Tracer interface {
+	//Start 在 ctx 中的 span 之下创建名为 name 的 span，返回包含新 span 的 ctx
+	Start(ctx context.Context, name string) (context.Context, Span)
+	//Inject 把 ctx 中的追踪上下文写入发往远程节点的请求头
+	Inject(ctx context.Context, header http.Header)
+	//Extract 从远程节点发来的请求头中读取追踪上下文，返回包含该上下文的 ctx
+	Extract(ctx context.Context, header http.Header) context.Context
+}
+
+//Span 是 Tracer 创建的一个 span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	//End 结束 span，err 不为 nil 时表示操作失败
+	End(err error)
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (nopTracer) Inject(ctx context.Context, header http.Header) {}
+
+func (nopTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return ctx
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+
+func (nopSpan) End(err error) {}
+
+//WithTracer 设置 Group 使用的 Tracer，默认不记录任何 span
+func WithTracer(t Tracer) Option {
+	return func(g *Group) {
+		if t != nil {
+			g.tracer = t
+		}
+	}
+}
+
+//WithPoolTracer 设置 HTTPPool 使用的 Tracer，请求远程节点时把追踪上下文写入请求头，
+//处理请求时从请求头中读取，远程节点上的 span 与发起请求的 span 属于同一个 trace。
+//通常与传给 WithTracer 的是同一个 Tracer
+func WithPoolTracer(t Tracer) PoolOption {
+	return func(p *HTTPPool) {
+		p.tracer = t
+	}
+}
+
+//startSpan 创建带有 group 和 key 属性的 span
+func (g *Group) startSpan(ctx context.Context, name string, key string) (context.Context, Span) {
+	ctx, span := g.tracer.Start(ctx, name)
+	span.SetAttribute(AttrGroup, g.name)
+	span.SetAttribute(AttrKey, key)
+	return ctx, span
+}
+
+//peerAddr 返回 peer 的地址，用于 AttrPeer，httpGetter 和 grpcpool 的 PeerGetter 都实现了 Addr() string
+func peerAddr(peer PeerGetter) (string, bool) {
+	if a, ok := peer.(interface{ Addr() string }); ok {
+		return a.Addr(), true
+	}
+	return "", false
+}
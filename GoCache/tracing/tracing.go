@@ -0,0 +1,126 @@
+//Package tracing 基于 OpenTelemetry 实现 GoCache.Tracer，依赖 OpenTelemetry 的代码只放在这个包中。
+//
+//	t := tracing.New(tracing.WithHashedKeys())
+//	group := GoCache.NewGroup("scores", 2<<10, getter, GoCache.WithTracer(t))
+//	pool := GoCache.NewHTTPPool(self, GoCache.WithPoolTracer(t))
+//
+//默认使用 otel.GetTracerProvider() 和 otel.GetTextMapPropagator()，没有通过 otel.SetTracerProvider 设置时不记录 span
+package tracing
+
+import (
+	"GoCache"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+)
+
+//instrumentationName 是创建 trace.Tracer 时使用的名称
+const instrumentationName = "GoCache"
+
+//Tracer 实现 GoCache.Tracer
+type Tracer struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+	hashKeys   bool
+}
+
+//Option 是 New 的选项
+type Option func(t *Tracer)
+
+//WithTracerProvider 设置创建 span 使用的 TracerProvider，默认为 otel.GetTracerProvider()
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.provider = tp
+	}
+}
+
+//WithPropagator 设置在节点间请求头中传递追踪上下文的方式，默认为 otel.GetTextMapPropagator()
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		t.propagator = p
+	}
+}
+
+//WithHashedKeys 把 span 中的 key 替换为 FNV-1a 哈希值，避免 key 中的用户数据出现在追踪系统中，
+//相同的 key 哈希值相同，仍然可以用来关联请求
+func WithHashedKeys() Option {
+	return func(t *Tracer) {
+		t.hashKeys = true
+	}
+}
+
+//New 创建 Tracer
+func New(opts ...Option) *Tracer {
+	t := &Tracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = otel.GetTracerProvider()
+	}
+	if t.propagator == nil {
+		t.propagator = otel.GetTextMapPropagator()
+	}
+	t.tracer = t.provider.Tracer(instrumentationName)
+	return t
+}
+
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, GoCache.Span) {
+	ctx, span := t.tracer.Start(ctx, name)
+	return ctx, &otelSpan{span: span, hashKeys: t.hashKeys}
+}
+
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+func (t *Tracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct {
+	span     trace.Span
+	hashKeys bool
+}
+
+func (s *otelSpan) SetAttribute(key string, value interface{}) {
+	if key == GoCache.AttrKey && s.hashKeys {
+		value = hashKey(fmt.Sprint(value))
+	}
+	var kv attribute.KeyValue
+	switch v := value.(type) {
+	case string:
+		kv = attribute.String(key, v)
+	case bool:
+		kv = attribute.Bool(key, v)
+	case int:
+		kv = attribute.Int(key, v)
+	case int64:
+		kv = attribute.Int64(key, v)
+	default:
+		kv = attribute.String(key, fmt.Sprint(v))
+	}
+	s.span.SetAttributes(kv)
+}
+
+func (s *otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func hashKey(key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
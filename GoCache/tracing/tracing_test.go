@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"GoCache"
+	pb "GoCache/gocachepb"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTracer(opts ...Option) (*Tracer, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	return New(append([]Option{WithTracerProvider(tp), WithPropagator(propagation.TraceContext{})}, opts...)...), rec
+}
+
+func attrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestSpans(t *testing.T) {
+	tr, rec := newTracer(WithHashedKeys())
+	g := GoCache.NewGroup("tracing", 2<<10, GoCache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), GoCache.WithTracer(tr))
+	defer GoCache.DestroyGroup("tracing")
+	g.Get("Tom")
+	g.Get("Tom")
+
+	var names []string
+	for _, s := range rec.Ended() {
+		names = append(names, s.Name())
+	}
+	//第一次 Get 未命中，依次结束 getLocally、load、Get；第二次命中
+	want := []string{"gocache.getLocally", "gocache.load", "gocache.Get", "gocache.Get"}
+	if len(names) != len(want) {
+		t.Fatalf("spans = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("spans = %v, want %v", names, want)
+		}
+	}
+	spans := rec.Ended()
+	if spans[1].Parent().SpanID() != spans[2].SpanContext().SpanID() || spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Fatal("load and getLocally should be children of Get")
+	}
+	miss, hit := attrs(spans[2]), attrs(spans[3])
+	if miss[GoCache.AttrHit].AsBool() || !hit[GoCache.AttrHit].AsBool() || hit[GoCache.AttrGroup].AsString() != "tracing" {
+		t.Fatalf("attributes = %v, %v", miss, hit)
+	}
+	if key := hit[GoCache.AttrKey].AsString(); key == "Tom" || key != hashKey("Tom") {
+		t.Fatalf("key should be hashed, got %q", key)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	tr, rec := newTracer()
+	GoCache.NewGroup("tracing-peer", 2<<10, GoCache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}), GoCache.WithTracer(tr))
+	defer GoCache.DestroyGroup("tracing-peer")
+	server := httptest.NewServer(GoCache.NewHTTPPool("server", GoCache.WithPoolTracer(tr), GoCache.WithPoolLogLevel(GoCache.LevelSilent)))
+	defer server.Close()
+	client := GoCache.NewHTTPPool("client", GoCache.WithPoolTracer(tr), GoCache.WithPoolLogLevel(GoCache.LevelSilent))
+	client.Set(server.URL)
+	peer, ok := client.PickPeer("Tom")
+	if !ok {
+		t.Fatal("expect a peer")
+	}
+
+	ctx, parent := tr.tracer.Start(context.Background(), "request")
+	err := peer.Get(ctx, &pb.Request{Group: "tracing-peer", Key: "Tom"}, &pb.Response{})
+	parent.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range rec.Ended() {
+		if s.Name() == "gocache.Get" {
+			if s.SpanContext().TraceID() != parent.SpanContext().TraceID() || s.Parent().SpanID() != parent.SpanContext().SpanID() {
+				t.Fatal("server span should continue the client trace")
+			}
+			return
+		}
+	}
+	t.Fatal("no gocache.Get span on the server")
+}
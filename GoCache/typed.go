@@ -0,0 +1,61 @@
+package GoCache
+
+import "encoding/json"
+
+//ValueCodec 负责 TypedGroup 中缓存值与 []byte 之间的转换
+type ValueCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+//JSONCodec 使用 encoding/json 编解码，是 TypedGroup 的默认 ValueCodec
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+//TypedGroup 在 Group 之上封装了序列化，调用方直接读写 T 类型的值。
+//底层 Group 中保存的仍然是编码后的 []byte，因此分布式和 singleflight 的行为不变。
+//注意 getter 返回的 []byte 也需要是 codec 编码后的格式。
+type TypedGroup[T any] struct {
+	group *Group
+	codec ValueCodec
+}
+
+//NewTypedGroup 基于已有的 Group 创建 TypedGroup，codec 为 nil 时使用 JSONCodec
+func NewTypedGroup[T any](group *Group, codec ValueCodec) *TypedGroup[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &TypedGroup[T]{group: group, codec: codec}
+}
+
+//Group 返回底层的 Group
+func (t *TypedGroup[T]) Group() *Group {
+	return t.group
+}
+
+//Get 从底层 Group 获取 key 对应的值并解码为 T
+func (t *TypedGroup[T]) Get(key string) (T, error) {
+	var v T
+	view, err := t.group.Get(key)
+	if err != nil {
+		return v, err
+	}
+	err = t.codec.Unmarshal(view.b, &v)
+	return v, err
+}
+
+//Set 将 v 编码后写入底层 Group
+func (t *TypedGroup[T]) Set(key string, v T) error {
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.group.Set(key, data)
+}
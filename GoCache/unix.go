@@ -0,0 +1,60 @@
+package GoCache
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	//unixScheme 是 Unix domain socket 节点地址的前缀，例如 unix:///var/run/gocache.sock
+	unixScheme = "unix://"
+	//unixHostSuffix 是 unix 节点在 HTTP 请求中使用的主机名后缀，主机名的其余部分是 socket 路径的十六进制编码，
+	//拨号时据此还原 socket 路径，见 unixHost
+	unixHostSuffix = ".unix"
+)
+
+//socketPath 在 addr 为 unix:// 形式时返回 socket 的路径
+func socketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, unixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, unixScheme), true
+}
+
+//unixHost 把 socket 路径编码为可以放进 URL 的主机名，路径中的 / 不能出现在主机名中
+func unixHost(path string) string {
+	return hex.EncodeToString([]byte(path)) + unixHostSuffix
+}
+
+//peerURL 返回访问节点 peer 时使用的 URL 前缀：http:// 和 https:// 地址原样返回，
+//unix:// 地址转换为 http://<unixHost>，由 dialUnix 拨号到对应的 socket
+func peerURL(peer string) string {
+	if path, ok := socketPath(peer); ok {
+		return "http://" + unixHost(path)
+	}
+	return peer
+}
+
+//dialUnix 包装 dial，主机名由 unixHost 生成时拨号到对应的 Unix domain socket，其余地址交给 dial
+func dialUnix(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err == nil && strings.HasSuffix(host, unixHostSuffix) {
+			if path, err := hex.DecodeString(strings.TrimSuffix(host, unixHostSuffix)); err == nil {
+				return dial(ctx, "unix", string(path))
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+//listenUnix 在 path 上监听 Unix domain socket，先删除上一次运行遗留的 socket 文件
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,153 @@
+package GoCache
+
+import (
+	pb "GoCache/gocachepb"
+	"sync"
+)
+
+const (
+	//defaultWriteBehindQueue 和 defaultWriteBehindWorkers 是开启 WithWriteBehind 后队列的默认长度和后台协程数
+	defaultWriteBehindQueue   = 1024
+	defaultWriteBehindWorkers = 4
+)
+
+//WriteBehindPolicy 决定开启 WithWriteBehind 后队列已满时 Set 的行为
+type WriteBehindPolicy int
+
+const (
+	//WriteBehindDrop 丢弃这次写入远程节点的请求，只保留本地写入，计入 Stats.WriteBehindDropped，这是默认行为
+	WriteBehindDrop WriteBehindPolicy = iota
+	//WriteBehindBlock 阻塞 Set 直到队列有空位，写入速度超过远程节点的处理能力时会拖慢调用方
+	WriteBehindBlock
+)
+
+//WithWriteBehind 开启异步写入远程节点：Set、SetWithMeta 和 SetWithTags 写入本地 mainCache 后，
+//把写入负责 key 的远程节点的请求放入队列立即返回，由后台协程发送，不再等待远程节点的响应。
+//代价是短时间内负责 key 的节点上可能还是旧值，写入远程节点失败时只记录日志并计入 Stats.WriteBehindErrors，不会返回给调用方。
+//队列长度和已满时的行为见 WithWriteBehindQueue，后台协程数见 WithWriteBehindWorkers；
+//DestroyGroup 时停止后台协程，队列中还没有发送的请求被丢弃并计入 Stats.WriteBehindDropped，之后的 Set 同步写入远程节点。默认关闭
+func WithWriteBehind(enabled bool) Option {
+	return func(g *Group) {
+		g.writeBehind.enabled = enabled
+	}
+}
+
+//WithWriteBehindQueue 设置异步写入队列的长度和队列已满时的行为，size <= 0 时使用 defaultWriteBehindQueue
+func WithWriteBehindQueue(size int, policy WriteBehindPolicy) Option {
+	return func(g *Group) {
+		g.writeBehind.size = size
+		g.writeBehind.policy = policy
+	}
+}
+
+//WithWriteBehindWorkers 设置发送异步写入请求的后台协程数，n <= 0 时使用 defaultWriteBehindWorkers
+func WithWriteBehindWorkers(n int) Option {
+	return func(g *Group) {
+		g.writeBehind.workers = n
+	}
+}
+
+//writeBehind 保存异步写入的配置和队列，queue 在 start 时创建
+type writeBehind struct {
+	enabled bool
+	size    int
+	policy  WriteBehindPolicy
+	workers int
+	queue   chan writeJob
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.RWMutex //enqueueWrite 持有读锁检查 stopped 并放入队列，stopWriteBehind 持有写锁设置 stopped
+	stopped bool
+}
+
+type writeJob struct {
+	peer PeerGetter
+	req  *pb.SetRequest
+}
+
+//startWriteBehind 创建队列并启动后台协程，没有开启 WithWriteBehind 时什么也不做
+func (g *Group) startWriteBehind() {
+	w := &g.writeBehind
+	if !w.enabled {
+		return
+	}
+	if w.size <= 0 {
+		w.size = defaultWriteBehindQueue
+	}
+	if w.workers <= 0 {
+		w.workers = defaultWriteBehindWorkers
+	}
+	w.queue = make(chan writeJob, w.size)
+	w.stop = make(chan struct{})
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go g.writeBehindLoop()
+	}
+}
+
+//stopWriteBehind 停止后台协程并等待正在发送的请求返回，之后 enqueueWrite 不再放入队列。
+//先关闭 stop 唤醒阻塞在已满队列上的 enqueueWrite，再设置 stopped，避免与持有读锁的 enqueueWrite 互相等待
+func (g *Group) stopWriteBehind() {
+	w := &g.writeBehind
+	if w.queue == nil {
+		return
+	}
+	close(w.stop)
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+	w.wg.Wait()
+	//设置 stopped 之前放入队列的请求不会再被后台协程取出
+	for {
+		select {
+		case job := <-w.queue:
+			g.stats.add(&g.stats.writeBehindDropped)
+			g.logger.logf(LevelDebug, "[GoCache] write behind stopped, dropped %s", job.req.GetKey())
+		default:
+			return
+		}
+	}
+}
+
+func (g *Group) writeBehindLoop() {
+	w := &g.writeBehind
+	defer w.wg.Done()
+	for {
+		select {
+		case job := <-w.queue:
+			if err := job.peer.Set(job.req, &pb.SetResponse{}); err != nil {
+				g.stats.add(&g.stats.writeBehindErrors)
+				g.logger.logf(LevelError, "[GoCache] write behind %s to peer: %v", job.req.GetKey(), err)
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+//enqueueWrite 把写入 peer 的请求放入队列，按 WriteBehindPolicy 处理队列已满的情况。
+//后台协程已经停止时返回 false，由调用方同步写入
+func (g *Group) enqueueWrite(peer PeerGetter, req *pb.SetRequest) bool {
+	w := &g.writeBehind
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.stopped {
+		return false
+	}
+	job := writeJob{peer: peer, req: req}
+	if w.policy == WriteBehindBlock {
+		select {
+		case w.queue <- job:
+			return true
+		case <-w.stop:
+			return false
+		}
+	}
+	select {
+	case w.queue <- job:
+	default:
+		g.stats.add(&g.stats.writeBehindDropped)
+		g.logger.logf(LevelDebug, "[GoCache] write behind queue full, dropped %s", req.GetKey())
+	}
+	return true
+}